@@ -0,0 +1,337 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+)
+
+// ErrDatasetExists is returned by Allocate when the dataset is already cataloged.
+var ErrDatasetExists = errors.New("zftp: dataset already exists")
+
+// ErrDatasetNotFound is returned by GetPds when the PDS is not cataloged, and
+// by RetrieveDatasetRecords when the dataset is not.
+var ErrDatasetNotFound = errors.New("zftp: dataset not found")
+
+// SpaceUnit is the unit of the primary and secondary space of new datasets,
+// usable as a DataSpec via the Space… constants.
+type SpaceUnit string
+
+const (
+	SpaceTracks    SpaceUnit = "TRACKS"    // Space in tracks
+	SpaceCylinders SpaceUnit = "CYLINDERS" // Space in cylinders
+	SpaceBlocks    SpaceUnit = "BLOCKS"    // Space in blocks of BLKSIZE
+)
+
+// Apply renders the SITE subcommand for this space unit.
+func (u SpaceUnit) Apply() (string, error) {
+	if !u.isUnit() {
+		return "", fmt.Errorf("invalid space unit: %s", u)
+	}
+	return string(u), nil
+}
+
+func (u SpaceUnit) isUnit() bool {
+	return u == SpaceTracks || u == SpaceCylinders || u == SpaceBlocks
+}
+
+// DSNType is the type of new datasets (SITE DSNTYPE), usable as a DataSpec via
+// the DSNType… constants.
+type DSNType string
+
+const (
+	DSNTypeSystem  DSNType = "SYSTEM"  // Type chosen by the SMS data class
+	DSNTypeBasic   DSNType = "BASIC"   // Basic format sequential
+	DSNTypeLarge   DSNType = "LARGE"   // Large format sequential
+	DSNTypePDS     DSNType = "PDS"     // Partitioned data set
+	DSNTypeLibrary DSNType = "LIBRARY" // Partitioned data set extended (PDSE)
+	DSNTypeExtReq  DSNType = "EXTREQ"  // Extended format sequential, required
+	DSNTypeExtPref DSNType = "EXTPREF" // Extended format sequential, preferred
+)
+
+// Apply renders the SITE subcommand for this dataset type.
+func (t DSNType) Apply() (string, error) {
+	switch t {
+	case DSNTypeSystem, DSNTypeBasic, DSNTypeLarge, DSNTypePDS, DSNTypeLibrary, DSNTypeExtReq, DSNTypeExtPref:
+		return fmt.Sprintf("DSNTYPE=%s", t), nil
+	}
+	return "", fmt.Errorf("invalid DSNTYPE value: %s", t)
+}
+
+// maxSpace is the largest PRIMARY, SECONDARY or DIRECTORY quantity z/OS accepts.
+const maxSpace = 16777215
+
+// maxRetPD is the longest retention period, in days, z/OS accepts.
+const maxRetPD = 93000
+
+// The checks below are shared by the DataSpecs and the StatusSetter methods
+// that send the same SITE parameters.
+
+// checkRange reports a quantity outside lo to hi.
+func checkRange(what string, n, lo, hi int) error {
+	if n < lo || n > hi {
+		return fmt.Errorf("%s must be between %d and %d", what, lo, hi)
+	}
+	return nil
+}
+
+func checkPrimary(n int) error   { return checkRange("primary space", n, 1, maxSpace) }
+func checkSecondary(n int) error { return checkRange("secondary space", n, 0, maxSpace) }
+func checkDirectory(n int) error { return checkRange("directory blocks", n, 1, maxSpace) }
+func checkRetPD(n int) error     { return checkRange("retention period", n, 0, maxRetPD) }
+
+// checkVolumes reports a volume serial that is not 1 to 6 name characters.
+func checkVolumes(volumes []string) error {
+	for _, v := range volumes {
+		if len(v) > 6 || !hostName.MatchString(v) {
+			return fmt.Errorf("invalid VOLUME value: %s", v)
+		}
+	}
+	return nil
+}
+
+// checkEATTR reports an EATTR value other than SYSTEM, NO or OPT.
+func checkEATTR(option string) error {
+	switch option {
+	case "SYSTEM", "NO", "OPT":
+		return nil
+	}
+	return fmt.Errorf("invalid EATTR value: %s", option)
+}
+
+type primary uint32
+
+func (p primary) Apply() (string, error) {
+	if err := checkPrimary(int(p)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("PRIMARY=%d", p), nil
+}
+
+type secondary uint32
+
+func (p secondary) Apply() (string, error) {
+	if err := checkSecondary(int(p)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("SECONDARY=%d", p), nil
+}
+
+type directory uint32
+
+func (d directory) Apply() (string, error) {
+	if err := checkDirectory(int(d)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("DIRECTORY=%d", d), nil
+}
+
+type volume []string
+
+func (v volume) Apply() (string, error) {
+	if len(v) == 0 {
+		return "", fmt.Errorf("no volume specified")
+	}
+	if err := checkVolumes(v); err != nil {
+		return "", err
+	}
+	if len(v) == 1 {
+		return fmt.Sprintf("VOLUME=%s", v[0]), nil
+	}
+	return fmt.Sprintf("VOLUME=(%s)", strings.Join(v, ",")), nil
+}
+
+// hostNameSpec is a SITE parameter whose value is a z/OS name: a unit or an SMS
+// class.
+type hostNameSpec struct{ name, value string }
+
+func (h hostNameSpec) Apply() (string, error) {
+	if !hostName.MatchString(h.value) {
+		return "", fmt.Errorf("invalid %s value: %s", h.name, h.value)
+	}
+	return fmt.Sprintf("%s=%s", h.name, h.value), nil
+}
+
+type retpd uint32
+
+func (r retpd) Apply() (string, error) {
+	if err := checkRetPD(int(r)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("RETPD=%d", r), nil
+}
+
+type eattr string
+
+func (e eattr) Apply() (string, error) {
+	if err := checkEATTR(string(e)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("EATTR=%s", e), nil
+}
+
+// Compile-time checks that the DataSpec implementations satisfy the interface.
+var (
+	_ DataSpec = SpaceUnit("")
+	_ DataSpec = DSNType("")
+	_ DataSpec = primary(0)
+	_ DataSpec = secondary(0)
+	_ DataSpec = directory(0)
+	_ DataSpec = volume(nil)
+	_ DataSpec = hostNameSpec{}
+	_ DataSpec = retpd(0)
+	_ DataSpec = eattr("")
+)
+
+// WithPrimary specs for the primary space of a new dataset, in the unit of a
+// SpaceUnit spec (1 to 16777215).
+func WithPrimary(amount uint32) DataSpec {
+	return primary(amount)
+}
+
+// WithSecondary specs for the secondary space of a new dataset (0 to 16777215).
+func WithSecondary(amount uint32) DataSpec {
+	return secondary(amount)
+}
+
+// WithDirectory specs for the directory blocks of a new partitioned dataset.
+func WithDirectory(blocks uint32) DataSpec {
+	return directory(blocks)
+}
+
+// WithVolume specs for the volume serials a new dataset is allocated on.
+func WithVolume(volumes ...string) DataSpec {
+	return volume(volumes)
+}
+
+// WithUnit specs for the unit type a new dataset is allocated on, such as SYSDA.
+func WithUnit(unit string) DataSpec {
+	return hostNameSpec{"UNIT", unit}
+}
+
+// WithDataClass specs for the SMS data class of a new dataset.
+func WithDataClass(class string) DataSpec {
+	return hostNameSpec{"DATACLASS", class}
+}
+
+// WithMgmtClass specs for the SMS management class of a new dataset.
+func WithMgmtClass(class string) DataSpec {
+	return hostNameSpec{"MGMTCLASS", class}
+}
+
+// WithStorageClass specs for the SMS storage class of a new dataset.
+func WithStorageClass(class string) DataSpec {
+	return hostNameSpec{"STORCLASS", class}
+}
+
+// WithRetPD specs for the retention period of a new dataset, in days (0 to
+// 93000).
+func WithRetPD(days uint32) DataSpec {
+	return retpd(days)
+}
+
+// WithEATTR specs for whether a new dataset may have extended attributes:
+// SYSTEM, NO or OPT.
+func WithEATTR(option string) DataSpec {
+	return eattr(option)
+}
+
+// Allocate creates the empty dataset dsn with the given attributes. dsn is
+// quoted ('USER.DATA') or relative to the working directory, as for Put.
+//
+// The dataset is partitioned when a spec is DSNTypePDS, DSNTypeLibrary,
+// WithDirectory or Like a partitioned dataset; it is then created with MKD
+// (PDSTYPE=PDSE for a library). Otherwise it is sequential and created by
+// storing no data. An existing dataset is never overwritten: Allocate returns
+// ErrDatasetExists instead.
+//
+// Like Put, the attributes stay in effect for the rest of the session.
+func (s *FTPSession) Allocate(dsn string, a ...DataSpec) error {
+	partitioned, pdse := allocKind(a)
+	if err := s.checkNotExists(dsn); err != nil {
+		return err
+	}
+	if partitioned {
+		t := pdsType("PDS")
+		if pdse {
+			t = "PDSE"
+		}
+		a = append(a, t)
+	}
+	if len(a) > 0 {
+		if err := s.SetDataSpecs(a...); err != nil {
+			return err
+		}
+	}
+
+	if partitioned {
+		return s.Mkdir(dsn)
+	}
+	_, err := s.StoreIO(dsn, strings.NewReader(""), TypeAscii)
+	return err
+}
+
+// allocKind reports whether specs describe a partitioned dataset, and whether a
+// PDSE.
+func allocKind(specs []DataSpec) (partitioned, pdse bool) {
+	for _, spec := range specs {
+		switch spec := spec.(type) {
+		case DSNType:
+			partitioned = partitioned || spec == DSNTypePDS || spec == DSNTypeLibrary
+			pdse = spec == DSNTypeLibrary
+		case directory:
+			partitioned = true
+		case like:
+			p, l := spec.partitioned()
+			partitioned, pdse = partitioned || p, pdse || l
+		}
+	}
+	return partitioned, pdse
+}
+
+// pdsType is the PDSTYPE Allocate derives from the dataset type, which decides
+// what MKD creates.
+type pdsType string
+
+func (t pdsType) Apply() (string, error) {
+	return fmt.Sprintf("PDSTYPE=%s", t), nil
+}
+
+// checkNotExists returns ErrDatasetExists when a listing finds dsn.
+func (s *FTPSession) checkNotExists(dsn string) error {
+	_, found, err := s.findDataset(dsn)
+	switch {
+	case err != nil:
+		return err
+	case found:
+		return fmt.Errorf("%w: %s", ErrDatasetExists, dsn)
+	}
+	return nil
+}
+
+// findDataset returns the listing entry of dsn, or of its library for a PDS
+// member. An unquoted dsn is resolved first, as the listing shows fully
+// qualified names and only the entry with exactly that name counts. It lists
+// dsn followed by a wildcard, as listing a partitioned dataset by its name
+// returns its members.
+func (s *FTPSession) findDataset(dsn string) (hfs.InfoDataset, bool, error) {
+	d, err := s.ResolveDSN(dsn)
+	if err != nil {
+		return hfs.InfoDataset{}, false, err
+	}
+	d.Member = ""
+	datasets, err := s.ListDatasets("'" + d.Name() + "*'")
+	if err != nil && !errors.Is(err, CodeError(550)) {
+		return hfs.InfoDataset{}, false, err
+	}
+	for _, ds := range datasets {
+		if ds.Name() == d.Name() {
+			return ds, true, nil
+		}
+	}
+	return hfs.InfoDataset{}, false, nil
+}
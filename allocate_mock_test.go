@@ -0,0 +1,183 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// TestAllocate_Sequential checks a sequential dataset is created empty by STOR
+// after one SITE command carrying every attribute.
+func TestAllocate_Sequential(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+
+	err := s.Allocate("'ME.NEW.DATA'", zftp.RecfmFB, zftp.WithLrecl(80), zftp.SpaceCylinders,
+		zftp.WithPrimary(5), zftp.WithSecondary(1), zftp.DSNTypeLarge, zftp.WithVolume("VOL001"),
+		zftp.WithUnit("SYSDA"), zftp.WithStorageClass("SCSTD"), zftp.WithRetPD(30), zftp.WithEATTR("OPT"))
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	cmds := srv.Commands()
+	site := "SITE RECFM=FB LRECL=80 CYLINDERS PRIMARY=5 SECONDARY=1 DSNTYPE=LARGE VOLUME=VOL001 UNIT=SYSDA STORCLASS=SCSTD RETPD=30 EATTR=OPT"
+	if i, j := cmdIndex(cmds, site), cmdIndex(cmds, "STOR 'ME.NEW.DATA'"); i < 0 || j < i {
+		t.Errorf("commands = %v", cmds)
+	}
+	if recs, ok := srv.Records("ME.NEW.DATA"); !ok || len(recs) != 0 {
+		t.Errorf("dataset = %q, %v; want empty", recs, ok)
+	}
+}
+
+// TestAllocate_Partitioned checks libraries and PDSs are created with MKD and
+// the matching PDSTYPE.
+func TestAllocate_Partitioned(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+
+	if err := s.Allocate("'ME.LIB'", zftp.DSNTypeLibrary, zftp.WithDirectory(10)); err != nil {
+		t.Fatalf("Allocate library: %v", err)
+	}
+	if err := s.Allocate("'ME.PDS'", zftp.WithDirectory(5)); err != nil {
+		t.Fatalf("Allocate PDS: %v", err)
+	}
+	cmds := srv.Commands()
+	for _, want := range []string{
+		"SITE DSNTYPE=LIBRARY DIRECTORY=10 PDSTYPE=PDSE", "MKD 'ME.LIB'",
+		"SITE DIRECTORY=5 PDSTYPE=PDS", "MKD 'ME.PDS'",
+	} {
+		if !hasCmd(cmds, want) {
+			t.Errorf("%q not sent: %v", want, cmds)
+		}
+	}
+	if countPrefix(cmds, "STOR") != 0 {
+		t.Errorf("partitioned allocation stored data: %v", cmds)
+	}
+	if _, err := s.StoreIO("'ME.LIB(MEM)'", strings.NewReader("X\n"), zftp.TypeAscii); err != nil {
+		t.Errorf("store into allocated library: %v", err)
+	}
+}
+
+// TestAllocate_Errors checks an existing dataset is not overwritten and an
+// invalid attribute sends nothing.
+func TestAllocate_Errors(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	srv.AddDataset("ME.OLD", mockzos.Attrs{}, "KEEP")
+
+	if err := s.Allocate("'ME.OLD'", zftp.WithPrimary(1)); !errors.Is(err, zftp.ErrDatasetExists) {
+		t.Errorf("existing dataset: got %v, want ErrDatasetExists", err)
+	}
+	if recs, _ := srv.Records("ME.OLD"); len(recs) != 1 {
+		t.Errorf("existing dataset changed: %q", recs)
+	}
+	before := len(srv.Commands())
+	if err := s.Allocate("'ME.BAD'", zftp.WithVolume("TOOLONG1")); err == nil {
+		t.Error("invalid volume accepted")
+	}
+	if n := countPrefix(srv.Commands()[before:], "SITE"); n != 0 {
+		t.Errorf("sent %d SITE commands for an invalid spec", n)
+	}
+}
+
+// TestAllocate_RelativeNameExact checks a relative name is only taken as
+// existing when the listing has exactly the resolved name.
+func TestAllocate_RelativeNameExact(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.Script("PWD", `257 "'ME.'" is working directory.`)
+	srv.DataFor("LIST", "", "Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname\r\n"+
+		"FA00FF 3390   2023/06/02  1    1  FB      80 27920  PS  ME.XY.X\r\n")
+
+	if err := s.Allocate("X", zftp.WithPrimary(1)); err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+	if cmds := srv.Commands(); !hasCmd(cmds, "LIST 'ME.X*'") || !hasCmd(cmds, "STOR X") {
+		t.Errorf("commands = %v", cmds)
+	}
+}
+
+// TestAllocate_SpecsMatchSetter checks the DataSpecs and the StatusSetter
+// methods for the same SITE parameters reject the same values alike.
+func TestAllocate_SpecsMatchSetter(t *testing.T) {
+	s, _ := dialMock(t)
+	set := s.SetStatusOf()
+	for _, c := range []struct {
+		spec   zftp.DataSpec
+		setter error
+	}{
+		{zftp.WithPrimary(0), set.Primary(0)},
+		{zftp.WithSecondary(16777216), set.Secondary(16777216)},
+		{zftp.WithDirectory(0), set.Directory(0)},
+		{zftp.WithVolume("TOOLONG1"), set.Volume("TOOLONG1")},
+		{zftp.WithRetPD(93001), set.RetPD(93001)},
+		{zftp.WithEATTR("MAYBE"), set.EATTR("MAYBE")},
+	} {
+		_, err := c.spec.Apply()
+		if err == nil || c.setter == nil || err.Error() != c.setter.Error() {
+			t.Errorf("spec error %v, setter error %v", err, c.setter)
+		}
+	}
+}
+
+// TestAllocate_Like checks a new dataset takes the model's DCB and space, with
+// overrides replacing the derived attribute of the same kind.
+func TestAllocate_Like(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	srv.AddDataset("ME.MODEL", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 27920}, "A", "B")
+	srv.AddPDS("ME.SRC", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 3120})
+
+	models, err := s.ListDatasets("'ME.*'")
+	if err != nil || len(models) != 2 {
+		t.Fatalf("ListDatasets = %v, %v", models, err)
+	}
+	seq, pds := models[0], models[1]
+	if seq.IsPartitioned() {
+		seq, pds = pds, seq
+	}
+	if err := s.Allocate("'ME.COPY'", zftp.Like(seq, zftp.WithLrecl(133), zftp.SpaceCylinders)); err != nil {
+		t.Fatalf("Allocate like: %v", err)
+	}
+	if err := s.AllocateLike("'ME.SRC2'", "'ME.SRC'", zftp.DSNTypeLibrary); err != nil {
+		t.Fatalf("AllocateLike: %v", err)
+	}
+	cmds := srv.Commands()
+	for _, want := range []string{
+		"SITE RECFM=FB LRECL=133 BLKSIZE=27920 CYLINDERS PRIMARY=1 SECONDARY=1",
+		"STOR 'ME.COPY'",
+		"SITE RECFM=FB LRECL=80 BLKSIZE=3120 TRACKS PRIMARY=1 SECONDARY=1 DSNTYPE=LIBRARY PDSTYPE=PDSE",
+		"MKD 'ME.SRC2'",
+	} {
+		if !hasCmd(cmds, want) {
+			t.Errorf("%q not sent: %v", want, cmds)
+		}
+	}
+	if err := s.AllocateLike("'ME.X'", "'ME.NONE'"); !errors.Is(err, zftp.ErrNoModel) {
+		t.Errorf("missing model: got %v, want ErrNoModel", err)
+	}
+}
+
+// TestAllocate_LikeDCBDSN checks a model listed without attributes is copied
+// with SITE DCBDSN, which is cleared afterwards.
+func TestAllocate_LikeDCBDSN(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.DataFor("LIST", "'ME.ARCH*'", "Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname\r\n"+
+		"Migrated                                                'ME.ARCH'\r\n")
+
+	for range 2 {
+		if err := s.AllocateLike("'ME.NEW'", "'ME.ARCH'", zftp.WithPrimary(5)); err != nil {
+			t.Fatalf("AllocateLike: %v", err)
+		}
+	}
+	cmds := srv.Commands()
+	if n := countCmd(cmds, "SITE DCBDSN='ME.ARCH' PRIMARY=5"); n != 2 {
+		t.Errorf("DCBDSN sent %d times, want 2: %v", n, cmds)
+	}
+	if n := countCmd(cmds, "SITE DCBDSN"); n != 2 {
+		t.Errorf("DCBDSN cleared %d times, want 2: %v", n, cmds)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"crypto/x509/pkix"
 	"math/big"
 	"net"
+	"strings"
 	"testing"
 	"time"
 
@@ -87,3 +88,40 @@ func TestAuthTLS_LoginAndCommandOverTLS(t *testing.T) {
 		t.Errorf("AUTH TLS was not received by the server; commands=%v", srv.Commands())
 	}
 }
+
+// TestAuthTLS_ProtectedDataConnections checks that after PROT P the mock wraps
+// passive data connections in TLS too, so a download and an upload both run
+// end to end over the client's TLS data channel.
+func TestAuthTLS_ProtectedDataConnections(t *testing.T) {
+	srv := mockzos.New(t)
+	srv.EnableState()
+	srv.AddDataset("HLQ.IN", mockzos.Attrs{Recfm: "FB", Lrecl: 80}, "SECRET 1", "SECRET 2")
+	serverCfg, clientCfg := newSelfSignedTLS(t)
+	srv.EnableTLS(serverCfg)
+
+	s, err := zftp.Open(srv.Addr())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	if err := s.AuthTLS(clientCfg); err != nil {
+		t.Fatalf("AuthTLS: %v", err)
+	}
+	if err := s.Login("ME", "PW"); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("'HLQ.IN'", &out, zftp.TypeAscii); err != nil {
+		t.Fatalf("RetrieveIO over TLS: %v", err)
+	}
+	if out.String() != "SECRET 1\nSECRET 2\n" {
+		t.Errorf("retrieved %q", out.String())
+	}
+	if _, err := s.StoreIO("'HLQ.OUT'", strings.NewReader("UP\n"), zftp.TypeAscii); err != nil {
+		t.Fatalf("StoreIO over TLS: %v", err)
+	}
+	if recs, ok := srv.Records("HLQ.OUT"); !ok || len(recs) != 1 || recs[0] != "UP" {
+		t.Errorf("stored records = %q (ok=%v)", recs, ok)
+	}
+}
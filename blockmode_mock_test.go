@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/eol"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// textDataset adds an FB 80 dataset of n numbered lines and returns the text an
+// ASCII download of it produces.
+func textDataset(srv *mockzos.Server, dsn string, n int) string {
+	recs := make([]string, n)
+	var b strings.Builder
+	for i := range n {
+		recs[i] = fmt.Sprintf("LINE %05d OF THE BLOCK-MODE TEST DATASET", i+1)
+		b.WriteString(recs[i] + eol.System.NewLine())
+	}
+	srv.AddDataset(dsn, mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}, recs...)
+	return b.String()
+}
+
+// TestBlockMode_RetrieveMarkers downloads in MODE B, rebuilding the lines from
+// the record blocks and keeping the last restart marker, and returns the
+// session to MODE S.
+func TestBlockMode_RetrieveMarkers(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	want := textDataset(srv, "ME.TEXT", 10)
+
+	if err := s.SetStatusOf().CheckpointInterval(3); err != nil {
+		t.Fatalf("CheckpointInterval: %v", err)
+	}
+	if n, err := s.StatusOf().CheckpointInterval(); err != nil || n != 3 {
+		t.Fatalf("XSTA CHKPTINT = %d, %v", n, err)
+	}
+
+	var cp zftp.Checkpoint
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'ME.TEXT'", &b, zftp.TypeAscii, zftp.WithBlockMode(&cp)); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	if line := len(want) / 10; cp.Marker != "9" || cp.Offset != int64(9*line) {
+		t.Errorf("checkpoint = %+v, want marker 9 at %d", cp, 9*line)
+	}
+	cmds := srv.Commands()
+	if i, j := cmdIndex(cmds, "MODE B"), cmdIndex(cmds, "MODE S"); i < 0 || j < i || slices.ContainsFunc(cmds, func(c string) bool { return strings.HasPrefix(c, "REST") }) {
+		t.Errorf("commands = %v", cmds)
+	}
+}
+
+// TestBlockMode_GetRestart interrupts an ASCII download part-way and resumes it
+// on a new session from the last restart marker.
+func TestBlockMode_GetRestart(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	want := textDataset(srv, "ME.BIG.TEXT", 3000)
+	if err := s.SetStatusOf().CheckpointInterval(100); err != nil {
+		t.Fatal(err)
+	}
+	srv.InjectFaults(3, mockzos.ResetData("RETR", 1))
+
+	local := filepath.Join(t.TempDir(), "big.txt")
+	var cp zftp.Checkpoint
+	if err := s.GetRestart("'ME.BIG.TEXT'", local, zftp.TypeAscii, &cp); err == nil {
+		t.Fatal("GetRestart succeeded over a reset data connection")
+	}
+	if cp.Marker == "" || cp.Offset == 0 {
+		t.Fatalf("no restart marker before the reset: %+v", cp)
+	}
+	if partial, _ := os.ReadFile(local); int64(len(partial)) < cp.Offset {
+		t.Fatalf("partial file has %d bytes, checkpoint at %d", len(partial), cp.Offset)
+	}
+
+	srv.InjectFaults(0)
+	s2, err := zftp.Open(srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s2.Close() })
+	if err := s2.Login("ME", "PW"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s2.SetStatusOf().CheckpointInterval(100); err != nil {
+		t.Fatal(err)
+	}
+	marker := cp.Marker
+	if err := s2.GetRestart("'ME.BIG.TEXT'", local, zftp.TypeAscii, &cp); err != nil {
+		t.Fatalf("resumed GetRestart: %v", err)
+	}
+	got, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("resumed file has %d bytes, want %d", len(got), len(want))
+	}
+	if !hasCmd(srv.Commands(), "REST "+marker) {
+		t.Errorf("no REST %s in %v", marker, srv.Commands())
+	}
+	if cp.Marker != "2900" {
+		t.Errorf("final marker %q, want 2900", cp.Marker)
+	}
+}
+
+// TestBlockMode_Store uploads text and binary data in MODE B and reads both
+// back in stream mode.
+func TestBlockMode_Store(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddFile("/u/me/data.bin", nil)
+
+	text := "FIRST RECORD\nSECOND RECORD\n"
+	if _, err := s.StoreIO("'ME.UPLOAD'", strings.NewReader(text), zftp.TypeAscii, zftp.WithBlockMode(nil)); err != nil {
+		t.Fatalf("StoreIO ascii: %v", err)
+	}
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'ME.UPLOAD'", &b, zftp.TypeAscii); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != strings.ReplaceAll(text, "\n", eol.System.NewLine()) {
+		t.Errorf("dataset holds %q", b.String())
+	}
+
+	bin := strings.Repeat("\x00\x01\x02\xff", 20000) // more than one block
+	if _, err := s.StoreIO("/u/me/data.bin", strings.NewReader(bin), zftp.TypeBinary, zftp.WithBlockMode(nil)); err != nil {
+		t.Fatalf("StoreIO binary: %v", err)
+	}
+	b.Reset()
+	if _, err := s.RetrieveIO("/u/me/data.bin", &b, zftp.TypeBinary, zftp.WithBlockMode(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != bin {
+		t.Errorf("file round-tripped to %d bytes, want %d", b.Len(), len(bin))
+	}
+	if n := countCmd(srv.Commands(), "MODE S"); n != 3 {
+		t.Errorf("MODE S sent %d times, want 3", n)
+	}
+}
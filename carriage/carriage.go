@@ -0,0 +1,360 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package carriage interprets the column-1 carriage control of print datasets
+// (RECFM=FBA, VBA, FBM, VBM) and JES SYSOUT, turning it into plain text with
+// blank lines and form feeds, or into pages.
+//
+// ASA controls act before the line is printed: ' ' single space, '0' double
+// space (one blank line), '-' triple space (two blank lines), '+' no advance
+// (overprint), and '1' skip to the top of the next page. Machine controls are
+// the channel command codes: the "write" codes (0x01, 0x09, 0x11, 0x19, and
+// 0x89 to 0xF9 for the channels) print the line and then advance, and the
+// "immediate" codes (0x0B, 0x13, 0x1B, and 0x8B to 0xFB) advance without
+// printing the record's data.
+//
+// Overprinted lines are merged into the line they print over: a character of
+// the overprint replaces a blank in the line beneath, so underscores and bold
+// lines come out readable. Skips to channels other than 1 are treated as a
+// single space, and unknown controls as a single space after the line (machine)
+// or before it (ASA).
+//
+//	w := carriage.NewWriter(os.Stdout, carriage.ASA)
+//	_, err := s.RetrieveIO("'HLQ.REPORT'", w, zftp.TypeAscii)
+//	err = w.Close()
+package carriage
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+// Control is a kind of carriage control.
+type Control uint8
+
+const (
+	// ASA is American National Standard carriage control (RECFM=xA).
+	ASA Control = iota + 1
+	// Machine is machine-code carriage control (RECFM=xM).
+	Machine
+)
+
+// String returns "ASA" or "machine".
+func (c Control) String() string {
+	switch c {
+	case ASA:
+		return "ASA"
+	case Machine:
+		return "machine"
+	}
+	return "Control(" + strconv.Itoa(int(c)) + ")"
+}
+
+// action is the paper movement before a line: lines to advance (0 overprints
+// the previous line) or a skip to a new page.
+type action struct {
+	lines int
+	page  bool
+}
+
+// asa returns the action of an ASA control character.
+func asa(c byte) action {
+	switch c {
+	case '0':
+		return action{lines: 2}
+	case '-':
+		return action{lines: 3}
+	case '+':
+		return action{lines: 0}
+	case '1':
+		return action{page: true}
+	}
+	return action{lines: 1}
+}
+
+// machine returns the action of a machine code and whether it is a write (print
+// the record, then move) or an immediate (move only) command.
+func machine(c byte) (a action, write bool) {
+	write = c&0x03 == 0x01
+	switch {
+	case c == 0x03:
+		return action{}, false // no operation
+	case c&0x80 != 0:
+		if c>>3&0x0F == 1 {
+			return action{page: true}, write
+		}
+		return action{lines: 1}, write
+	}
+	switch c &^ 0x03 {
+	case 0x00:
+		return action{lines: 0}, write
+	case 0x08:
+		return action{lines: 1}, write
+	case 0x10:
+		return action{lines: 2}, write
+	case 0x18:
+		return action{lines: 3}, write
+	}
+	return action{lines: 1}, true
+}
+
+// printer holds the line being built, so an overprint can merge into it, and
+// the movement machine codes leave pending for the next line.
+type printer struct {
+	c       Control
+	line    func(text []byte) error // a finished line
+	page    func() error            // a skip to a new page
+	cur     []byte
+	started bool
+	pending action
+}
+
+func newPrinter(c Control, line func([]byte) error, page func() error) *printer {
+	return &printer{c: c, line: line, page: page, pending: action{lines: 1}}
+}
+
+// record handles one record: its control code and its text.
+func (p *printer) record(code byte, text []byte) error {
+	if p.c != Machine {
+		return p.print(asa(code), text)
+	}
+	a, write := machine(code)
+	if !write {
+		// Immediate commands move the paper before the next line prints.
+		switch {
+		case a.page:
+			p.pending = a
+		case !p.pending.page:
+			p.pending.lines += a.lines
+		}
+		return nil
+	}
+	before := p.pending
+	p.pending = a
+	return p.print(before, text)
+}
+
+func (p *printer) print(a action, text []byte) error {
+	text = bytes.TrimRight(text, " ")
+	if !p.started {
+		p.started = true
+		p.cur = append(p.cur[:0], text...)
+		for range a.lines - 1 {
+			if err := p.line(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !a.page && a.lines == 0 {
+		p.cur = overprint(p.cur, text)
+		return nil
+	}
+	if err := p.line(p.cur); err != nil {
+		return err
+	}
+	if a.page {
+		if err := p.page(); err != nil {
+			return err
+		}
+	}
+	for range a.lines - 1 {
+		if err := p.line(nil); err != nil {
+			return err
+		}
+	}
+	p.cur = append(p.cur[:0], text...)
+	return nil
+}
+
+// flush finishes the last line.
+func (p *printer) flush() error {
+	if !p.started {
+		return nil
+	}
+	p.started = false
+	return p.line(p.cur)
+}
+
+// overprint merges text into line: each non-blank character of text fills a
+// blank (or extends) line.
+func overprint(line, text []byte) []byte {
+	if bytes.IndexFunc(line, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0 ||
+		bytes.IndexFunc(text, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0 {
+		return overprintRunes(line, text)
+	}
+	for i, c := range text {
+		switch {
+		case i >= len(line):
+			line = append(line, c)
+		case line[i] == ' ':
+			line[i] = c
+		}
+	}
+	return line
+}
+
+func overprintRunes(line, text []byte) []byte {
+	l, t := []rune(string(line)), []rune(string(text))
+	for i, r := range t {
+		switch {
+		case i >= len(l):
+			l = append(l, r)
+		case l[i] == ' ':
+			l[i] = r
+		}
+	}
+	return []byte(string(l))
+}
+
+// Writer converts carriage-controlled lines or records written to it into plain
+// text: lines end in '\n', spacing becomes blank lines, and each skip to a new
+// page becomes a form feed ('\f') at the start of the page's first line.
+// Trailing blanks are removed from every line.
+type Writer struct {
+	// CodePage, when set, is the EBCDIC code page of the records passed to
+	// WriteRecord: their text is decoded with it (and so is an ASA control).
+	// Leave it nil for records that are already text.
+	CodePage *ebcdic.CodePage
+
+	w       *bufio.Writer
+	p       *printer
+	partial []byte
+}
+
+// NewWriter returns a Writer that interprets control c and writes plain text to
+// w. Output is buffered; call Close when done.
+func NewWriter(w io.Writer, c Control) *Writer {
+	cw := &Writer{w: bufio.NewWriter(w)}
+	cw.p = newPrinter(c, cw.writeLine, func() error { return cw.w.WriteByte('\f') })
+	return cw
+}
+
+func (w *Writer) writeLine(text []byte) error {
+	if _, err := w.w.Write(text); err != nil {
+		return err
+	}
+	return w.w.WriteByte('\n')
+}
+
+// Write takes text lines ending in '\n' (a "\r\n" ending is accepted too), as an
+// ASCII transfer delivers them, each with its control in column 1. A line split
+// across writes is held until its end arrives.
+//
+// A machine code arrives translated by the server's table; Write reads it back
+// through IBM-1047, the z/OS default, whether the line is ISO-8859-1 or UTF-8.
+func (w *Writer) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			break
+		}
+		line := p[:i]
+		if len(w.partial) > 0 {
+			line = append(w.partial, line...)
+			w.partial = w.partial[:0]
+		}
+		if err := w.textLine(bytes.TrimSuffix(line, []byte("\r"))); err != nil {
+			return 0, err
+		}
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+func (w *Writer) textLine(line []byte) error {
+	if len(line) == 0 {
+		return w.p.record(' ', nil)
+	}
+	if w.p.c != Machine {
+		return w.p.record(line[0], line[1:])
+	}
+	// Recover the EBCDIC code the server translated.
+	r, size := rune(line[0]), 1
+	if line[0] >= utf8.RuneSelf {
+		if u, n := utf8.DecodeRune(line); u != utf8.RuneError && u <= 0xFF {
+			r, size = u, n
+		}
+	}
+	return w.p.record(ebcdic.IBM1047.Encode(string(r), ebcdic.KeepNL)[0], line[size:])
+}
+
+// WriteRecord takes one record, such as a binary download split by its RDWs or
+// LRECL, with its control in the first byte. Machine codes are read as the raw
+// byte; the text is decoded with CodePage when it is set.
+func (w *Writer) WriteRecord(rec []byte) error {
+	if len(rec) == 0 {
+		return w.p.record(' ', nil)
+	}
+	code, text := rec[0], rec[1:]
+	if w.CodePage != nil {
+		if w.p.c != Machine {
+			code = w.CodePage.Decode(rec[:1], ebcdic.KeepNL)[0]
+		}
+		text = []byte(w.CodePage.Decode(text, ebcdic.KeepNL))
+	}
+	return w.p.record(code, text)
+}
+
+// Close finishes a pending line, including one without a final '\n', and
+// flushes. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if len(w.partial) > 0 {
+		line := w.partial
+		w.partial = nil
+		if err := w.textLine(bytes.TrimSuffix(line, []byte("\r"))); err != nil {
+			return err
+		}
+	}
+	if err := w.p.flush(); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// Pages reads carriage-controlled text lines from r, as Writer.Write takes
+// them, and splits the plain text into pages, one slice of lines per page.
+func Pages(r io.Reader, c Control) ([][]string, error) {
+	pages := [][]string{nil}
+	p := newPrinter(c, func(text []byte) error {
+		pages[len(pages)-1] = append(pages[len(pages)-1], string(text))
+		return nil
+	}, func() error {
+		pages = append(pages, nil)
+		return nil
+	})
+	w := &Writer{p: p}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		if err := w.textLine(bytes.TrimSuffix(sc.Bytes(), []byte("\r"))); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if err := p.flush(); err != nil {
+		return nil, err
+	}
+	if len(pages) == 1 && pages[0] == nil {
+		return nil, nil
+	}
+	return pages, nil
+}
+
+// String interprets carriage-controlled text held in memory; see Writer.
+func String(s string, c Control) string {
+	var b strings.Builder
+	w := NewWriter(&b, c)
+	_, _ = w.Write([]byte(s))
+	_ = w.Close()
+	return b.String()
+}
@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package carriage_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gopkg.in/ro-ag/zftp.v2/carriage"
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+const report = "1TITLE   \n" +
+	" LINE ONE\n" +
+	"0AFTER ONE BLANK\n" +
+	"-AFTER TWO BLANKS\n" +
+	"+     _   _\n" +
+	"1PAGE TWO\r\n" +
+	"\n" +
+	" END"
+
+// TestASA converts every ASA control, including a first-line skip to a new
+// page, an overprint, a CRLF line, an empty line and a last line without '\n'.
+func TestASA(t *testing.T) {
+	want := "TITLE\n" +
+		"LINE ONE\n" +
+		"\n" +
+		"AFTER ONE BLANK\n" +
+		"\n" +
+		"\n" +
+		"AFTER_TWO_BLANKS\n" +
+		"\fPAGE TWO\n" +
+		"\n" +
+		"END\n"
+	if got := carriage.String(report, carriage.ASA); got != want {
+		t.Errorf("got\n%q\nwant\n%q", got, want)
+	}
+
+	// The same text written one byte at a time.
+	var b strings.Builder
+	w := carriage.NewWriter(&b, carriage.ASA)
+	for i := range len(report) {
+		if _, err := w.Write([]byte{report[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != want {
+		t.Errorf("bytewise got %q", b.String())
+	}
+}
+
+// TestPages splits at every skip to a new page.
+func TestPages(t *testing.T) {
+	pages, err := carriage.Pages(iotest.HalfReader(strings.NewReader(report)), carriage.ASA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"TITLE", "LINE ONE", "", "AFTER ONE BLANK", "", "", "AFTER_TWO_BLANKS"},
+		{"PAGE TWO", "", "END"},
+	}
+	if !slices.EqualFunc(pages, want, slices.Equal) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+	if pages, err := carriage.Pages(strings.NewReader(""), carriage.ASA); err != nil || pages != nil {
+		t.Errorf("empty input: %q, %v", pages, err)
+	}
+}
+
+// TestMachineRecords interprets raw machine codes in EBCDIC records: write and
+// space, write without spacing (so the next line overprints), an immediate
+// space and an immediate skip to channel 1.
+func TestMachineRecords(t *testing.T) {
+	cp := ebcdic.IBM037
+	rec := func(code byte, text string) []byte {
+		return append([]byte{code}, cp.Encode(text, ebcdic.KeepNL)...)
+	}
+	var b strings.Builder
+	w := carriage.NewWriter(&b, carriage.Machine)
+	w.CodePage = cp
+	for _, r := range [][]byte{
+		rec(0x09, "HEADER"),     // write, space 1
+		rec(0x01, "TOTAL  "),    // write, no space
+		rec(0x11, "_____"),      // overprint, then space 2
+		rec(0x0B, "IGNORED"),    // space 1 immediately
+		rec(0x89, "LAST LINE"),  // write, skip to channel 1
+		rec(0x8B, ""),           // skip to channel 1 immediately
+		rec(0x09, "NEXT PAGE "), // write, space 1
+	} {
+		if err := w.WriteRecord(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := "HEADER\nTOTAL\n\n\nLAST LINE\n\fNEXT PAGE\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+// TestMachineText reads machine codes from an ASCII transfer, where the server
+// translated them (0x09 to U+008D, 0x89 to 'i', 0x11 to U+0011), both as
+// ISO-8859-1 bytes and as UTF-8.
+func TestMachineText(t *testing.T) {
+	want := "ONE\nTWO\n\nTHREE\n\fFOUR\n"
+	for name, text := range map[string]string{
+		"latin1": "\x8dONE\n\x11TWO\niTHREE\n\x8dFOUR\n",
+		"utf8":   "\u008dONE\n\x11TWO\niTHREE\n\u008dFOUR\n",
+	} {
+		if got := carriage.String(text, carriage.Machine); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/carriage"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// TestCarriage_RetrieveIO interprets the ASA controls of an FBA report fetched
+// in ASCII.
+func TestCarriage_RetrieveIO(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("HLQ.REPORT", mockzos.Attrs{Recfm: "FBA", Lrecl: 21, BlkSize: 210},
+		"1SALES REPORT", " NORTH      100", "0SOUTH       50", "1SUMMARY")
+
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'HLQ.REPORT'", &b, zftp.TypeAscii, zftp.WithCarriageControl(carriage.ASA)); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	want := "SALES REPORT\nNORTH      100\n\nSOUTH       50\n\fSUMMARY\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+// TestCarriage_RetrieveSpool fetches a job's JES2 job log with its ASA controls
+// interpreted, and all spool files raw, restoring FILETYPE afterwards.
+func TestCarriage_RetrieveSpool(t *testing.T) {
+	s, srv := dialMock(t)
+	id := srv.SubmitJob("ME", "//MEA JOB\n//S1 EXEC PGM=IEFBR14\n")
+
+	var log strings.Builder
+	if _, err := s.RetrieveSpool(id, 1, &log, zftp.WithCarriageControl(carriage.ASA)); err != nil {
+		t.Fatalf("RetrieveSpool: %v", err)
+	}
+	lines := strings.Split(log.String(), "\n")
+	// "1" title, then "0" with no text (a blank line, then an empty one).
+	if !strings.HasPrefix(strings.TrimSpace(lines[0]), "J E S 2  J O B  L O G") || lines[1] != "" || lines[2] != "" || !strings.Contains(lines[3], id) {
+		t.Errorf("job log = %q", log.String())
+	}
+	if !hasCmd(srv.Commands(), "RETR "+id+".1") {
+		t.Errorf("commands = %v", srv.Commands())
+	}
+
+	var all strings.Builder
+	if _, err := s.RetrieveSpool(id, 0, &all); err != nil {
+		t.Fatalf("RetrieveSpool all: %v", err)
+	}
+	if n := strings.Count(all.String(), "!! END OF JES SPOOL FILE !!"); n != 3 {
+		t.Errorf("%d spool files, want 3", n)
+	}
+	if ft, err := s.StatusOf().FileType(); err != nil || !strings.Contains(ft, "SEQ") {
+		t.Errorf("FILETYPE after RetrieveSpool = %q, %v", ft, err)
+	}
+	if _, err := s.RetrieveSpool("JOB*", 1, &all); err == nil {
+		t.Error("a job-id pattern was accepted")
+	}
+}
@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ro-ag/zftp.v2"
+)
+
+// allocFlags are the dataset attributes of the alloc sub-command; zero values
+// are left to the server's defaults.
+type allocFlags struct {
+	like                               string
+	recfm, space, dsntype, unit, eattr string
+	dataclass, mgmtclass, storclass    string
+	lrecl, blksize                     uint16
+	primary, secondary, dir, retpd     uint32
+	volumes                            []string
+}
+
+// specs converts the flags that were set into DataSpecs, in SITE order.
+func (f *allocFlags) specs(cmd *cobra.Command) []zftp.DataSpec {
+	var a []zftp.DataSpec
+	set := cmd.Flags().Changed
+	if f.recfm != "" {
+		a = append(a, zftp.Recfm(strings.ToUpper(f.recfm)))
+	}
+	if set("lrecl") {
+		a = append(a, zftp.WithLrecl(f.lrecl))
+	}
+	if set("blksize") {
+		a = append(a, zftp.WithBlkSize(f.blksize))
+	}
+	if f.space != "" {
+		a = append(a, zftp.SpaceUnit(strings.ToUpper(f.space)))
+	}
+	if set("primary") {
+		a = append(a, zftp.WithPrimary(f.primary))
+	}
+	if set("secondary") {
+		a = append(a, zftp.WithSecondary(f.secondary))
+	}
+	if set("dir") {
+		a = append(a, zftp.WithDirectory(f.dir))
+	}
+	if f.dsntype != "" {
+		a = append(a, zftp.DSNType(strings.ToUpper(f.dsntype)))
+	}
+	if len(f.volumes) > 0 {
+		a = append(a, zftp.WithVolume(f.volumes...))
+	}
+	for _, c := range []struct {
+		value string
+		spec  func(string) zftp.DataSpec
+	}{
+		{f.unit, zftp.WithUnit},
+		{f.dataclass, zftp.WithDataClass},
+		{f.mgmtclass, zftp.WithMgmtClass},
+		{f.storclass, zftp.WithStorageClass},
+	} {
+		if c.value != "" {
+			a = append(a, c.spec(strings.ToUpper(c.value)))
+		}
+	}
+	if set("retpd") {
+		a = append(a, zftp.WithRetPD(f.retpd))
+	}
+	if f.eattr != "" {
+		a = append(a, zftp.WithEATTR(strings.ToUpper(f.eattr)))
+	}
+	return a
+}
+
+// newAllocCmd returns the "alloc" sub-command. It creates an empty sequential
+// dataset, or a PDS/PDSE with --dir or --dsntype PDS|LIBRARY; with --like it
+// copies the attributes of a model dataset, overridden by the other flags.
+func newAllocCmd(d deps, g *globalFlags) *cobra.Command {
+	f := &allocFlags{}
+	c := &cobra.Command{
+		Use:   "alloc <dataset>",
+		Short: "Allocate an empty dataset (SITE + STOR/MKD)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, a []string) error {
+			specs := f.specs(cmd)
+			return withClient(d, g, func(c client) error {
+				if f.like != "" {
+					return c.AllocateLike(a[0], f.like, specs...)
+				}
+				return c.Allocate(a[0], specs...)
+			})
+		},
+	}
+	fl := c.Flags()
+	fl.StringVar(&f.like, "like", "", "model dataset to copy DCB and space from")
+	fl.StringVar(&f.recfm, "recfm", "", "record format (FB, VB, U, ...)")
+	fl.Uint16Var(&f.lrecl, "lrecl", 0, "logical record length")
+	fl.Uint16Var(&f.blksize, "blksize", 0, "block size")
+	fl.StringVar(&f.space, "space", "", "space unit: TRACKS, CYLINDERS or BLOCKS")
+	fl.Uint32Var(&f.primary, "primary", 0, "primary space quantity")
+	fl.Uint32Var(&f.secondary, "secondary", 0, "secondary space quantity")
+	fl.Uint32Var(&f.dir, "dir", 0, "directory blocks (makes a PDS)")
+	fl.StringVar(&f.dsntype, "dsntype", "", "BASIC, LARGE, PDS, LIBRARY, EXTREQ, EXTPREF or SYSTEM")
+	fl.StringSliceVar(&f.volumes, "volume", nil, "volume serial(s)")
+	fl.StringVar(&f.unit, "unit", "", "unit type (e.g. SYSDA)")
+	fl.StringVar(&f.dataclass, "dataclass", "", "SMS data class")
+	fl.StringVar(&f.mgmtclass, "mgmtclass", "", "SMS management class")
+	fl.StringVar(&f.storclass, "storclass", "", "SMS storage class")
+	fl.Uint32Var(&f.retpd, "retpd", 0, "retention period in days")
+	fl.StringVar(&f.eattr, "eattr", "", "extended attributes: SYSTEM, NO or OPT")
+	return c
+}
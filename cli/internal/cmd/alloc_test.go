@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import "testing"
+
+func TestAllocCmd(t *testing.T) {
+	env := map[string]string{"ZFTP_PASSWORD": "pw"}
+
+	t.Run("sequential", func(t *testing.T) {
+		fake := &fakeClient{}
+		_, err := runCLI(t, fake, env, "alloc", "'ME.DATA'", "-H", "h", "-u", "me",
+			"--recfm", "fb", "--lrecl", "80", "--space", "cylinders", "--primary", "5", "--secondary", "0",
+			"--volume", "VOL001,VOL002", "--storclass", "scstd", "--retpd", "30")
+		if err != nil {
+			t.Fatalf("alloc error: %v", err)
+		}
+		want := "Allocate:'ME.DATA' RECFM=FB LRECL=80 CYLINDERS PRIMARY=5 SECONDARY=0 VOLUME=(VOL001,VOL002) STORCLASS=SCSTD RETPD=30"
+		if !contains(fake.calls, want) {
+			t.Errorf("calls %v does not contain %q", fake.calls, want)
+		}
+	})
+
+	t.Run("library", func(t *testing.T) {
+		fake := &fakeClient{}
+		_, err := runCLI(t, fake, env, "alloc", "'ME.LIB'", "-H", "h", "-u", "me", "--dsntype", "library", "--dir", "10")
+		if err != nil {
+			t.Fatalf("alloc error: %v", err)
+		}
+		want := "Allocate:'ME.LIB' DIRECTORY=10 DSNTYPE=LIBRARY"
+		if !contains(fake.calls, want) {
+			t.Errorf("calls %v does not contain %q", fake.calls, want)
+		}
+	})
+
+	t.Run("like", func(t *testing.T) {
+		fake := &fakeClient{}
+		_, err := runCLI(t, fake, env, "alloc", "'ME.NEW'", "-H", "h", "-u", "me", "--like", "'ME.OLD'", "--lrecl", "133")
+		if err != nil {
+			t.Fatalf("alloc error: %v", err)
+		}
+		want := "Allocate:'ME.NEW' like 'ME.OLD' LRECL=133"
+		if !contains(fake.calls, want) {
+			t.Errorf("calls %v does not contain %q", fake.calls, want)
+		}
+	})
+
+	t.Run("invalid attribute", func(t *testing.T) {
+		fake := &fakeClient{}
+		if _, err := runCLI(t, fake, env, "alloc", "'ME.X'", "-H", "h", "-u", "me", "--space", "bytes"); err == nil {
+			t.Error("expected an error for --space bytes")
+		}
+	})
+}
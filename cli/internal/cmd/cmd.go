@@ -6,10 +6,12 @@
 package cmd
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
 	"net"
 	"os"
@@ -31,8 +33,14 @@ type client interface {
 	Get(remote, local string, mode zftp.TransferType) error
 	GetAt(remote, local string, mode zftp.TransferType, offset int64) error
 	GetAndGzip(remote, local string, mode zftp.TransferType) error
+	RetrieveDatasetRecords(remote string) iter.Seq2[[]byte, error]
 	Put(local, remote string, mode zftp.TransferType, a ...zftp.DataSpec) error
 	PutAt(local, remote string, mode zftp.TransferType, offset int64, a ...zftp.DataSpec) error
+	PutAutoDCB(local, remote string, opts zftp.DCBOptions, a ...zftp.DataSpec) (zftp.DCB, error)
+	GetPds(pds, localDir string, opts zftp.PdsOptions) ([]zftp.MemberTransfer, error)
+	PutPds(localDir, pds string, opts zftp.PdsOptions) ([]zftp.MemberTransfer, error)
+	Allocate(dsn string, a ...zftp.DataSpec) error
+	AllocateLike(dsn, model string, overrides ...zftp.DataSpec) error
 	Delete(name string) error
 	Mkdir(path string) error
 	Rename(from, to string) error
@@ -66,6 +74,7 @@ type deps struct {
 	prompt  func() (string, error) // no-echo password reader
 	out     io.Writer
 	errOut  io.Writer
+	signals func(context.Context) (context.Context, context.CancelFunc) // ends on interrupt
 }
 
 // BuildInfo is the version metadata injected by GoReleaser ldflags via main.
@@ -178,12 +187,14 @@ func newRootCmd(d deps, bi BuildInfo) *cobra.Command {
 		newPutCmd(d, g),
 		newRmCmd(d, g),
 		newMkdirCmd(d, g),
+		newAllocCmd(d, g),
 		newMvCmd(d, g),
 		newChmodCmd(d, g),
 		newStatCmd(d, g),
 		newSubmitCmd(d, g),
 		newJobsCmd(d, g),
 		newJobCmd(d, g),
+		newMockServerCmd(d, g),
 	)
 	root.SetOut(d.out)
 	root.SetErr(d.errOut)
@@ -206,6 +217,7 @@ func Execute(bi BuildInfo) error {
 		prompt:  termPrompt,
 		out:     os.Stdout,
 		errOut:  os.Stderr,
+		signals: notifySignals,
 	}
 	return newRootCmd(d, bi).Execute()
 }
@@ -3,6 +3,8 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
+	"iter"
 	"testing"
 
 	"gopkg.in/ro-ag/zftp.v2"
@@ -22,7 +24,10 @@ type fakeClient struct {
 	submitJob *zftp.JesJob
 	status    *zftp.ServerStatus
 	system    string
-	err       error // returned by the next mutating call when set
+	records   [][]byte              // yielded by RetrieveDatasetRecords
+	dcb       zftp.DCB              // returned by PutAutoDCB
+	members   []zftp.MemberTransfer // returned by GetPds and PutPds
+	err       error                 // returned by the next mutating call when set
 }
 
 func (f *fakeClient) ListDatasets(e string) ([]hfs.InfoDataset, error) {
@@ -53,6 +58,19 @@ func (f *fakeClient) GetAndGzip(r, l string, m zftp.TransferType) error {
 	f.calls = append(f.calls, "GetAndGzip:"+r)
 	return f.err
 }
+func (f *fakeClient) RetrieveDatasetRecords(r string) iter.Seq2[[]byte, error] {
+	f.calls = append(f.calls, "RetrieveDatasetRecords:"+r)
+	return func(yield func([]byte, error) bool) {
+		for _, rec := range f.records {
+			if !yield(rec, nil) {
+				return
+			}
+		}
+		if f.err != nil {
+			yield(nil, f.err)
+		}
+	}
+}
 func (f *fakeClient) Put(l, r string, m zftp.TransferType, a ...zftp.DataSpec) error {
 	f.calls = append(f.calls, "Put:"+l+"->"+r)
 	return f.err
@@ -61,6 +79,33 @@ func (f *fakeClient) PutAt(l, r string, m zftp.TransferType, o int64, a ...zftp.
 	f.calls = append(f.calls, "PutAt:"+l)
 	return f.err
 }
+func (f *fakeClient) Allocate(dsn string, a ...zftp.DataSpec) error {
+	call := "Allocate:" + dsn
+	for _, spec := range a {
+		p, err := spec.Apply()
+		if err != nil {
+			return err
+		}
+		call += " " + p
+	}
+	f.calls = append(f.calls, call)
+	return f.err
+}
+func (f *fakeClient) AllocateLike(dsn, model string, a ...zftp.DataSpec) error {
+	return f.Allocate(dsn+" like "+model, a...)
+}
+func (f *fakeClient) PutAutoDCB(l, r string, o zftp.DCBOptions, a ...zftp.DataSpec) (zftp.DCB, error) {
+	f.calls = append(f.calls, fmt.Sprintf("PutAutoDCB:%s->%s max=%d strict=%t", l, r, o.MaxLrecl, o.Strict))
+	return f.dcb, f.err
+}
+func (f *fakeClient) GetPds(p, dir string, o zftp.PdsOptions) ([]zftp.MemberTransfer, error) {
+	f.calls = append(f.calls, fmt.Sprintf("GetPds:%s->%s mode=%c include=%v exclude=%v ext=%s", p, dir, o.Mode, o.Include, o.Exclude, o.Ext))
+	return f.members, f.err
+}
+func (f *fakeClient) PutPds(dir, p string, o zftp.PdsOptions) ([]zftp.MemberTransfer, error) {
+	f.calls = append(f.calls, fmt.Sprintf("PutPds:%s->%s mode=%c include=%v exclude=%v", dir, p, o.Mode, o.Include, o.Exclude))
+	return f.members, f.err
+}
 func (f *fakeClient) Delete(n string) error {
 	f.calls = append(f.calls, "Delete:"+n)
 	return f.err
@@ -3,13 +3,32 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"os"
 	"path"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/copybook"
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
 )
 
+// localName is the default local file for remote: the member of a PDS member,
+// the name of any other dataset, or the base name of a z/OS UNIX path.
+func localName(remote string) string {
+	if !strings.HasPrefix(remote, "/") {
+		if d, err := zftp.ParseDSN(remote); err == nil {
+			if d.Member != "" {
+				return d.Member
+			}
+			return d.Name()
+		}
+	}
+	return path.Base(strings.Trim(remote, "'"))
+}
+
 // transferType maps the --ascii flag to the corresponding zftp transfer mode.
 // When ascii is false (the default) binary/image mode is used.
 func transferType(ascii bool) zftp.TransferType {
@@ -21,16 +40,52 @@ func transferType(ascii bool) zftp.TransferType {
 
 // newGetCmd returns the "get" sub-command (RETR). It downloads a remote dataset
 // or file to a local path, with optional gzip compression or byte-offset resume.
+// With --copybook it instead decodes the dataset's records with a COBOL copybook
+// and writes them as CSV or JSON lines to the local path, or stdout without one.
+// With --pds-dir it downloads the members of a PDS into a local directory.
 func newGetCmd(d deps, g *globalFlags) *cobra.Command {
 	var ascii, gzipOut bool
 	var offset int64
+	var copybookFile, format, codepage, ext string
+	var pds pdsFlags
 	c := &cobra.Command{
 		Use:   "get <remote> [local]",
 		Short: "Download a dataset or file (RETR)",
 		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			remote := args[0]
-			local := path.Base(strings.Trim(remote, "'"))
+			if pds.dir != "" {
+				if len(args) != 1 || gzipOut || offset > 0 || copybookFile != "" {
+					return errors.New("--pds-dir takes only the PDS and cannot be combined with --gzip, --offset or --copybook")
+				}
+				conn, err := dial(d, g)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				transfers, err := conn.GetPds(remote, pds.dir, pds.options(ascii, ext))
+				return reportPds(d, g.jsonOut, transfers, err)
+			}
+			if copybookFile != "" {
+				if ascii || gzipOut || offset > 0 {
+					return errors.New("--copybook cannot be combined with --ascii, --gzip or --offset")
+				}
+				enc, err := copybookEncoder(copybookFile, format, codepage)
+				if err != nil {
+					return err
+				}
+				conn, err := dial(d, g)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				local := ""
+				if len(args) == 2 {
+					local = args[1]
+				}
+				return getRecords(d, conn, remote, local, enc)
+			}
+			local := localName(remote)
 			if len(args) == 2 {
 				local = args[1]
 			}
@@ -56,5 +111,67 @@ func newGetCmd(d deps, g *globalFlags) *cobra.Command {
 	c.Flags().BoolVar(&ascii, "ascii", false, "ASCII (text) transfer; default is binary")
 	c.Flags().BoolVar(&gzipOut, "gzip", false, "gzip the downloaded stream")
 	c.Flags().Int64Var(&offset, "offset", 0, "resume at byte offset (binary only)")
+	c.Flags().StringVar(&copybookFile, "copybook", "", "decode records with this COBOL copybook")
+	c.Flags().StringVar(&format, "format", "json", "record output with --copybook: json or csv")
+	c.Flags().StringVar(&codepage, "codepage", "IBM-037", "EBCDIC code page of text fields with --copybook")
+	pds.register(c, "download every member of the PDS into this directory")
+	c.Flags().StringVar(&ext, "ext", "", "with --pds-dir, extension of the local files (e.g. .cbl)")
 	return c
 }
+
+// copybookEncoder parses the copybook file and resolves the output format and
+// code page, so a bad flag fails before dialing. The returned func binds the
+// encoder to the output once it is open.
+func copybookEncoder(file, format, codepage string) (func(io.Writer) *copybook.Encoder, error) {
+	f, err := copybook.ParseFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := ebcdic.Lookup(codepage)
+	if err != nil {
+		return nil, err
+	}
+	src, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	l, err := copybook.Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	l.CodePage = cp
+	return func(w io.Writer) *copybook.Encoder { return copybook.NewEncoder(w, l, f) }, nil
+}
+
+// getRecords streams the records of remote through the encoder to local, or to
+// stdout when local is empty.
+func getRecords(d deps, conn client, remote, local string, enc func(io.Writer) *copybook.Encoder) (err error) {
+	w := d.out
+	if local != "" {
+		f, err := os.Create(local)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+	e := enc(w)
+	n := 0
+	for rec, err := range conn.RetrieveDatasetRecords(remote) {
+		if err != nil {
+			_ = e.Flush()
+			return err
+		}
+		n++
+		if err := e.Encode(rec); err != nil {
+			_ = e.Flush()
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+	}
+	return e.Flush()
+}
@@ -2,6 +2,8 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -72,6 +74,86 @@ func TestGet(t *testing.T) {
 			t.Errorf("calls %v does not contain %q", fake.calls, want)
 		}
 	})
+
+	t.Run("default local member and path", func(t *testing.T) {
+		for remote, local := range map[string]string{
+			"'my.src(payroll)'": "PAYROLL",
+			"/u/me/run.sh":      "run.sh",
+		} {
+			fake := &fakeClient{}
+			if _, err := runCLI(t, fake, env, "get", remote); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := "Get:" + remote + "->" + local; !contains(fake.calls, want) {
+				t.Errorf("calls %v does not contain %q", fake.calls, want)
+			}
+		}
+	})
+}
+
+// TestGetCopybook decodes the fake's records with a copybook to CSV on stdout
+// and JSON in a local file, and rejects bad flags before dialing.
+func TestGetCopybook(t *testing.T) {
+	env := map[string]string{"ZFTP_HOST": "localhost", "ZFTP_USER": "user"}
+	dir := t.TempDir()
+	cpy := filepath.Join(dir, "item.cpy")
+	src := "       01  ITEM.\n" +
+		"           05  ITEM-NAME   PIC X(4).\n" +
+		"           05  ITEM-QTY    PIC S9(3) COMP-3.\n"
+	if err := os.WriteFile(cpy, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	recs := [][]byte{
+		{0xC2, 0xD6, 0xD3, 0xE3, 0x01, 0x2C}, // BOLT, 12
+		{0xD5, 0xE4, 0xE3, 0x40, 0x00, 0x5D}, // NUT, -5
+	}
+
+	t.Run("csv to stdout", func(t *testing.T) {
+		fake := &fakeClient{records: recs}
+		out, err := runCLI(t, fake, env, "get", "--copybook", cpy, "--format", "csv", "'HLQ.ITEMS'")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "ITEM-NAME,ITEM-QTY\nBOLT,12\nNUT,-5\n"; out != want {
+			t.Errorf("output %q, want %q", out, want)
+		}
+		if !contains(fake.calls, "RetrieveDatasetRecords:'HLQ.ITEMS'") {
+			t.Errorf("calls %v", fake.calls)
+		}
+	})
+
+	t.Run("json to file", func(t *testing.T) {
+		fake := &fakeClient{records: recs}
+		local := filepath.Join(dir, "items.jsonl")
+		if _, err := runCLI(t, fake, env, "get", "--copybook", cpy, "'HLQ.ITEMS'", local); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got, err := os.ReadFile(local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"ITEM-NAME":"BOLT","ITEM-QTY":12}` + "\n" + `{"ITEM-NAME":"NUT","ITEM-QTY":-5}` + "\n"; string(got) != want {
+			t.Errorf("file %q, want %q", got, want)
+		}
+	})
+
+	for name, argv := range map[string][]string{
+		"bad format":   {"--format", "xml"},
+		"bad codepage": {"--codepage", "IBM-930"},
+		"with gzip":    {"--gzip"},
+		"missing file": {"--copybook", filepath.Join(dir, "none.cpy")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeClient{records: recs}
+			args := append([]string{"get", "--copybook", cpy}, argv...)
+			if _, err := runCLI(t, fake, env, append(args, "'HLQ.ITEMS'")...); err == nil {
+				t.Fatal("expected an error")
+			}
+			if len(fake.calls) != 0 {
+				t.Errorf("dialed despite the error: %v", fake.calls)
+			}
+		})
+	}
 }
 
 func contains(calls []string, want string) bool {
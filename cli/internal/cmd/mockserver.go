@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/zftptest"
+)
+
+// newMockServerCmd returns the mock-server subcommand, which runs the z/OS
+// dialect test server from zftptest as a standalone fake mainframe until it is
+// interrupted.
+func newMockServerCmd(d deps, g *globalFlags) *cobra.Command {
+	var listen, seed string
+	c := &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run a fake z/OS FTP server for local testing",
+		Long: `Run a fake z/OS FTP server that speaks the z/OS dialect this client uses.
+
+With --seed DIR the catalog starts from a directory: each regular file becomes
+a sequential dataset named after the file, each subdirectory a PDS whose files
+are its members, and each file under jobs/ is JCL placed on the JES spool as
+owned by --user (default IBMUSER). With --tls the server accepts AUTH TLS using
+a freshly generated self-signed certificate.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			srv, err := zftptest.Listen(listen, func(format string, args ...any) {
+				fmt.Fprintf(d.errOut, "mock-server: "+format+"\n", args...)
+			})
+			if err != nil {
+				return err
+			}
+			defer srv.Close()
+
+			tlsNote := ""
+			if g.tlsOn {
+				cfg, fingerprint, err := selfSignedTLS(srv.Addr())
+				if err != nil {
+					return fmt.Errorf("tls: %w", err)
+				}
+				srv.EnableTLS(cfg)
+				tlsNote = fmt.Sprintf(", AUTH TLS with self-signed certificate SHA-256 %s", fingerprint)
+			}
+			if seed != "" {
+				owner := g.user
+				if owner == "" {
+					owner = zftptest.DefaultUser
+				}
+				n, err := seedMockServer(srv, seed, strings.ToUpper(owner))
+				if err != nil {
+					return fmt.Errorf("seed %s: %w", seed, err)
+				}
+				fmt.Fprintf(d.out, "seeded %d datasets, %d PDS members, %d jobs from %s\n", n.datasets, n.members, n.jobs, seed)
+			}
+			fmt.Fprintf(d.out, "mock z/OS FTP server listening on %s%s\n", srv.Addr(), tlsNote)
+
+			ctx, stop := d.signals(cmd.Context())
+			defer stop()
+			<-ctx.Done()
+			return nil
+		},
+	}
+	c.Flags().StringVar(&listen, "listen", "127.0.0.1:2121", "address to listen on")
+	c.Flags().StringVar(&seed, "seed", "", "directory to seed datasets, PDSes and jobs from")
+	return c
+}
+
+// seedCounts tallies what seedMockServer loaded.
+type seedCounts struct{ datasets, members, jobs int }
+
+// seedMockServer loads dir into srv: files as sequential datasets, directories
+// as PDSes of members, and jobs/ as JCL submitted under owner. Names are
+// uppercased; a member name drops its file extension. A name that is not a
+// valid dataset or member name fails, naming the file.
+func seedMockServer(srv *zftptest.Server, dir, owner string) (seedCounts, error) {
+	var n seedCounts
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return n, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		p := filepath.Join(dir, name)
+		switch {
+		case e.IsDir() && name == "jobs":
+			files, err := os.ReadDir(p)
+			if err != nil {
+				return n, err
+			}
+			for _, f := range files {
+				if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+					continue
+				}
+				jcl, err := os.ReadFile(filepath.Join(p, f.Name()))
+				if err != nil {
+					return n, err
+				}
+				if srv.SubmitJob(owner, string(jcl)) == "" {
+					return n, fmt.Errorf("jobs/%s: no JOB statement", f.Name())
+				}
+				n.jobs++
+			}
+		case e.IsDir():
+			dsn, err := datasetName(name)
+			if err != nil {
+				return n, err
+			}
+			files, err := os.ReadDir(p)
+			if err != nil {
+				return n, err
+			}
+			members := map[string][]string{}
+			var all []string
+			for _, f := range files {
+				if f.IsDir() || strings.HasPrefix(f.Name(), ".") {
+					continue
+				}
+				mem, err := zftp.MemberName(f.Name())
+				if err != nil {
+					return n, fmt.Errorf("%s/%s: %w", name, f.Name(), err)
+				}
+				recs, err := readRecords(filepath.Join(p, f.Name()))
+				if err != nil {
+					return n, err
+				}
+				members[mem] = recs
+				all = append(all, recs...)
+			}
+			srv.AddPDS(dsn, inferDCB(all))
+			for mem, recs := range members {
+				srv.AddMember(dsn, mem, recs...)
+				n.members++
+			}
+			n.datasets++
+		default:
+			dsn, err := datasetName(name)
+			if err != nil {
+				return n, err
+			}
+			recs, err := readRecords(p)
+			if err != nil {
+				return n, err
+			}
+			srv.AddDataset(dsn, inferDCB(recs), recs...)
+			n.datasets++
+		}
+	}
+	return n, nil
+}
+
+// datasetName validates the seed file or directory name file as a fully
+// qualified dataset name without member or generation, and uppercases it.
+func datasetName(file string) (string, error) {
+	d, err := zftp.ParseDSN("'" + file + "'")
+	if err == nil && (d.Member != "" || d.GDG) {
+		err = fmt.Errorf("%w: %s is not a dataset name", zftp.ErrInvalidDSN, file)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", file, err)
+	}
+	return d.Name(), nil
+}
+
+// readRecords reads a local text file as one record per line.
+func readRecords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var recs []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 32760)
+	for sc.Scan() {
+		recs = append(recs, strings.TrimSuffix(sc.Text(), "\r"))
+	}
+	return recs, sc.Err()
+}
+
+// inferDCB picks the DCB zftp.InferDCB would for the records, as put
+// --auto-dcb does; binary content leaves it to the mock's defaults.
+func inferDCB(recs []string) zftptest.DCB {
+	d, err := zftp.InferDCB(strings.NewReader(strings.Join(recs, "\n")), zftp.DCBOptions{})
+	if err != nil || d.Binary {
+		return zftptest.DCB{}
+	}
+	return zftptest.DCB{Recfm: string(d.Recfm), Lrecl: d.Lrecl, BlkSize: d.BlkSize}
+}
+
+// selfSignedTLS generates a one-day ECDSA certificate for localhost and the
+// listen address, returning the server config and the certificate's SHA-256
+// fingerprint for clients to pin or compare.
+func selfSignedTLS(addr string) (*tls.Config, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return nil, "", err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "zftp mock-server"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(der)
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02X", b)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return cfg, strings.Join(hex, ":"), nil
+}
+
+// notifySignals is the production deps.signals: the context ends on an
+// interrupt or SIGTERM.
+func notifySignals(ctx context.Context) (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
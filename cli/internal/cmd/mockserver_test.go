@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/zftptest"
+)
+
+// TestMockServerCmd_SeedAndTLS starts mock-server on an ephemeral port with a
+// seed directory and --tls, then, while it runs, logs in over TLS and reads the
+// seeded dataset, PDS member and job back before stopping it.
+func TestMockServerCmd_SeedAndTLS(t *testing.T) {
+	seed := t.TempDir()
+	write := func(rel, body string) {
+		p := filepath.Join(seed, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("ibmuser.input", "RECORD 1\nRECORD 2\n")
+	write("ibmuser.src/hello.cbl", "       IDENTIFICATION DIVISION.\n")
+	write("jobs/build.jcl", "//BUILD JOB (ACCT)\n//S1 EXEC PGM=IEFBR14\n")
+
+	var out bytes.Buffer
+	var checked bool
+	d := deps{
+		getenv: func(string) string { return "" },
+		out:    &out, errOut: &out,
+		signals: func(ctx context.Context) (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(ctx)
+			defer cancel()
+			checked = true
+			addr := regexp.MustCompile(`listening on (\S+?),`).FindStringSubmatch(out.String())
+			if addr == nil {
+				t.Errorf("no listen address in output: %s", out.String())
+				return ctx, cancel
+			}
+			s, err := zftp.Open(addr[1])
+			if err != nil {
+				t.Errorf("Open: %v", err)
+				return ctx, cancel
+			}
+			defer s.Close()
+			if err := s.AuthTLS(&tls.Config{InsecureSkipVerify: true}); err != nil { //nolint:gosec // self-signed
+				t.Errorf("AuthTLS: %v", err)
+				return ctx, cancel
+			}
+			if err := s.Login("IBMUSER", "pw"); err != nil {
+				t.Errorf("Login: %v", err)
+				return ctx, cancel
+			}
+			var got strings.Builder
+			if _, err := s.RetrieveIO("'IBMUSER.INPUT'", &got, zftp.TypeAscii); err != nil || got.String() != "RECORD 1\nRECORD 2\n" {
+				t.Errorf("dataset = %q, %v", got.String(), err)
+			}
+			if members, err := s.ListPds("'IBMUSER.SRC'"); err != nil || len(members) != 1 || members[0].Name.String() != "HELLO" {
+				t.Errorf("members = %v, %v", members, err)
+			}
+			if err := s.SetStatusOf().JesJobName("*"); err != nil {
+				t.Errorf("JesJobName: %v", err)
+			}
+			if jobs, err := s.ListSpool("*"); err != nil || len(jobs) != 1 || jobs[0].Name.String() != "BUILD" {
+				t.Errorf("jobs = %v, %v", jobs, err)
+			}
+			return ctx, cancel
+		},
+	}
+	root := newRootCmd(d, BuildInfo{Version: "test"})
+	root.SetArgs([]string{"mock-server", "--listen", "127.0.0.1:0", "--seed", seed, "--tls"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("mock-server: %v\n%s", err, out.String())
+	}
+	if !checked {
+		t.Fatal("mock-server returned before waiting for a signal")
+	}
+	if !strings.Contains(out.String(), "seeded 2 datasets, 1 PDS members, 1 jobs") {
+		t.Errorf("missing seed summary, got: %s", out.String())
+	}
+}
+
+// TestMockServerCmd_BadNames rejects a seed file whose name cannot be a member
+// or dataset name, naming the file.
+func TestMockServerCmd_BadNames(t *testing.T) {
+	for _, c := range []struct{ path, want string }{
+		{filepath.Join("ibmuser.src", "toolongname.cbl"), "ibmuser.src/toolongname.cbl"},
+		{filepath.Join("ibmuser.src", "1st.cbl"), "ibmuser.src/1st.cbl"},
+		{"ibmuser.9data", "ibmuser.9data"},
+		{filepath.Join("ibmuser.toolongqual", "ok.cbl"), "ibmuser.toolongqual"},
+	} {
+		seed := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(seed, filepath.Dir(c.path)), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(seed, c.path), []byte("X\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := runCLI(t, &fakeClient{}, nil, "mock-server", "--listen", "127.0.0.1:0", "--seed", seed)
+		if !errors.Is(err, zftp.ErrInvalidDSN) || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want ErrInvalidDSN naming %s", c.path, err, c.want)
+		}
+	}
+}
+
+// TestInferDCB_MatchesLibrary checks seeded datasets get the DCB put
+// --auto-dcb would choose: FB 80 for card images, VB for wider records.
+func TestInferDCB_MatchesLibrary(t *testing.T) {
+	for _, c := range []struct {
+		recs []string
+		want zftptest.DCB
+	}{
+		{[]string{"SHORT", strings.Repeat("X", 80)}, zftptest.DCB{Recfm: "FB", Lrecl: 80, BlkSize: 27920}},
+		{[]string{"SHORT", strings.Repeat("X", 100)}, zftptest.DCB{Recfm: "VB", Lrecl: 104, BlkSize: 27998}},
+	} {
+		if got := inferDCB(c.recs); got != c.want {
+			t.Errorf("inferDCB(longest %d) = %+v, want %+v", len(c.recs[1]), got, c.want)
+		}
+	}
+}
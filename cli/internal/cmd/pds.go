@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/ro-ag/zftp.v2"
+)
+
+// pdsFlags are the --pds-dir flags that turn get and put into bulk member
+// transfers.
+type pdsFlags struct {
+	dir              string
+	include, exclude []string
+}
+
+// register adds the flags to c; usage describes --pds-dir.
+func (p *pdsFlags) register(c *cobra.Command, usage string) {
+	c.Flags().StringVar(&p.dir, "pds-dir", "", usage)
+	c.Flags().StringSliceVar(&p.include, "include", nil, "with --pds-dir, only members matching these globs (member or file name)")
+	c.Flags().StringSliceVar(&p.exclude, "exclude", nil, "with --pds-dir, skip members matching these globs (member or file name)")
+}
+
+// options returns the library options for the flags.
+func (p pdsFlags) options(ascii bool, ext string) zftp.PdsOptions {
+	return zftp.PdsOptions{Mode: transferType(ascii), Include: p.include, Exclude: p.exclude, Ext: ext}
+}
+
+// pdsMember is the JSON form of a zftp.MemberTransfer.
+type pdsMember struct {
+	Member string `json:"member"`
+	File   string `json:"file"`
+	Error  string `json:"error,omitempty"`
+}
+
+// reportPds writes one line per member tried, then returns err with the count
+// of failed members when some failed.
+func reportPds(d deps, jsonOut bool, transfers []zftp.MemberTransfer, err error) error {
+	if transfers == nil && err != nil {
+		return err
+	}
+	view := make([]pdsMember, len(transfers))
+	for i, t := range transfers {
+		view[i] = pdsMember{Member: t.Member, File: t.File}
+		if t.Err != nil {
+			view[i].Error = t.Err.Error()
+		}
+	}
+	if eerr := emit(d, jsonOut, view, func(w io.Writer) {
+		for _, m := range view {
+			if m.Error != "" {
+				fmt.Fprintf(d.errOut, "%s\t%s\tfailed: %s\n", m.Member, m.File, m.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\n", m.Member, m.File)
+		}
+	}); eerr != nil {
+		return eerr
+	}
+	var pe *zftp.PdsError
+	if errors.As(err, &pe) {
+		return fmt.Errorf("%d of %d member(s) failed", len(pe.Failed), len(transfers))
+	}
+	return err
+}
@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Apache-2.0
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/ro-ag/zftp.v2"
+)
+
+func TestPdsDir(t *testing.T) {
+	env := map[string]string{"ZFTP_PASSWORD": "pw"}
+
+	t.Run("get", func(t *testing.T) {
+		fake := &fakeClient{members: []zftp.MemberTransfer{{Member: "PAYROLL", File: "src/PAYROLL.cbl"}}}
+		out, err := runCLI(t, fake, env, "get", "'ME.SRC'", "-H", "h", "-u", "me", "--pds-dir", "src",
+			"--ascii", "--include", "PAY*,TAX*", "--exclude", "*OLD", "--ext", ".cbl")
+		if err != nil {
+			t.Fatalf("get error: %v", err)
+		}
+		want := "GetPds:'ME.SRC'->src mode=A include=[PAY* TAX*] exclude=[*OLD] ext=.cbl"
+		if !contains(fake.calls, want) {
+			t.Errorf("calls %v does not contain %q", fake.calls, want)
+		}
+		if !strings.Contains(out, "PAYROLL\tsrc/PAYROLL.cbl") {
+			t.Errorf("output %q", out)
+		}
+	})
+
+	t.Run("put with failures", func(t *testing.T) {
+		bad := errors.New("550 denied")
+		fake := &fakeClient{members: []zftp.MemberTransfer{
+			{Member: "BUILD", File: "jcl/build.jcl"},
+			{Member: "DEPLOY", File: "jcl/deploy.jcl", Err: bad},
+		}}
+		fake.err = &zftp.PdsError{Failed: fake.members[1:]}
+		out, err := runCLI(t, fake, env, "put", "'ME.JCL'", "-H", "h", "-u", "me", "--pds-dir", "jcl")
+		if err == nil || !strings.Contains(err.Error(), "1 of 2 member(s) failed") {
+			t.Errorf("put error = %v", err)
+		}
+		if !contains(fake.calls, "PutPds:jcl->'ME.JCL' mode=I include=[] exclude=[]") {
+			t.Errorf("calls %v", fake.calls)
+		}
+		if !strings.Contains(out, "DEPLOY\tjcl/deploy.jcl\tfailed: 550 denied") {
+			t.Errorf("output %q", out)
+		}
+	})
+
+	t.Run("invalid combinations", func(t *testing.T) {
+		for _, argv := range [][]string{
+			{"get", "'ME.SRC'", "local", "--pds-dir", "src"},
+			{"get", "'ME.SRC'", "--pds-dir", "src", "--gzip"},
+			{"put", "'ME.JCL'", "--pds-dir", "jcl", "--auto-dcb"},
+		} {
+			fake := &fakeClient{}
+			if _, err := runCLI(t, fake, env, append(argv, "-H", "h", "-u", "me")...); err == nil {
+				t.Errorf("%v: expected an error", argv)
+			}
+			if len(fake.calls) != 0 {
+				t.Errorf("%v: calls %v", argv, fake.calls)
+			}
+		}
+	})
+}
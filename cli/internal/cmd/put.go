@@ -3,21 +3,41 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"path"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/ro-ag/zftp.v2"
 )
 
 // newPutCmd returns the "put" sub-command (STOR). It uploads a local file to a
-// remote dataset or path, with optional byte-offset resume.
+// remote dataset or path, with optional byte-offset resume. With --auto-dcb the
+// file is scanned first to choose the transfer mode and the dataset's RECFM,
+// LRECL and BLKSIZE. With --pds-dir the files of a local directory are uploaded
+// as members of a PDS.
 func newPutCmd(d deps, g *globalFlags) *cobra.Command {
-	var ascii bool
+	var ascii, autoDCB, strict bool
 	var offset int64
+	var maxLrecl int
+	var pds pdsFlags
 	c := &cobra.Command{
 		Use:   "put <local> [remote]",
 		Short: "Upload a file or dataset (STOR)",
 		Args:  cobra.RangeArgs(1, 2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if pds.dir != "" {
+				if len(args) != 1 || autoDCB || offset > 0 {
+					return errors.New("--pds-dir takes only the PDS and cannot be combined with --auto-dcb or --offset")
+				}
+				conn, err := dial(d, g)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				transfers, err := conn.PutPds(pds.dir, args[0], pds.options(ascii, ""))
+				return reportPds(d, g.jsonOut, transfers, err)
+			}
 			local := args[0]
 			remote := path.Base(local)
 			if len(args) == 2 {
@@ -26,11 +46,19 @@ func newPutCmd(d deps, g *globalFlags) *cobra.Command {
 			if ascii && offset > 0 {
 				return errors.New("--offset is binary-only; ASCII resume is unsupported")
 			}
+			if autoDCB && (ascii || offset > 0) {
+				return errors.New("--auto-dcb chooses the transfer mode and cannot be combined with --ascii or --offset")
+			}
 			conn, err := dial(d, g)
 			if err != nil {
 				return err
 			}
 			defer conn.Close()
+			if autoDCB {
+				dcb, err := conn.PutAutoDCB(local, remote, zftp.DCBOptions{MaxLrecl: maxLrecl, Strict: strict})
+				reportDCB(d.errOut, dcb)
+				return err
+			}
 			mode := transferType(ascii)
 			if offset > 0 {
 				return conn.PutAt(local, remote, mode, offset)
@@ -40,5 +68,27 @@ func newPutCmd(d deps, g *globalFlags) *cobra.Command {
 	}
 	c.Flags().BoolVar(&ascii, "ascii", false, "ASCII (text) transfer; default is binary")
 	c.Flags().Int64Var(&offset, "offset", 0, "resume at byte offset (binary only)")
+	c.Flags().BoolVar(&autoDCB, "auto-dcb", false, "infer transfer mode, RECFM, LRECL and BLKSIZE from the file")
+	c.Flags().IntVar(&maxLrecl, "max-lrecl", 0, "with --auto-dcb, the largest LRECL allowed (default 32760)")
+	c.Flags().BoolVar(&strict, "strict", false, "with --auto-dcb, fail instead of warning on lines longer than --max-lrecl")
+	pds.register(c, "upload the files of this directory as members of the PDS")
 	return c
 }
+
+// reportDCB writes the layout --auto-dcb chose, and the lines that do not fit.
+func reportDCB(w io.Writer, d zftp.DCB) {
+	switch {
+	case d.Binary:
+		fmt.Fprintln(w, "auto-dcb: binary content, transferring in binary")
+	case d.Recfm != "":
+		fmt.Fprintf(w, "auto-dcb: RECFM=%s LRECL=%d BLKSIZE=%d (%d lines, longest %d)\n", d.Recfm, d.Lrecl, d.BlkSize, d.Lines, d.Longest)
+	}
+	if n := len(d.LongLines); n > 0 {
+		const shown = 10
+		fmt.Fprintf(w, "warning: %d line(s) longer than LRECL %d: %v", n, d.Lrecl, d.LongLines[:min(n, shown)])
+		if n > shown {
+			fmt.Fprint(w, " ...")
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -4,6 +4,8 @@ package cmd
 import (
 	"strings"
 	"testing"
+
+	"gopkg.in/ro-ag/zftp.v2"
 )
 
 func TestPut(t *testing.T) {
@@ -60,4 +62,31 @@ func TestPut(t *testing.T) {
 			t.Errorf("calls %v does not contain %q", fake.calls, want)
 		}
 	})
+
+	t.Run("auto-dcb", func(t *testing.T) {
+		fake := &fakeClient{dcb: zftp.DCB{Recfm: zftp.RecfmVB, Lrecl: 84, BlkSize: 27998, Lines: 3, Longest: 100, LongLines: []int{2}}}
+		out, err := runCLI(t, fake, env, "put", "--auto-dcb", "--max-lrecl", "84", "local.txt", "'ME.TXT'")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "PutAutoDCB:local.txt->'ME.TXT' max=84 strict=false"
+		if !contains(fake.calls, want) {
+			t.Errorf("calls %v does not contain %q", fake.calls, want)
+		}
+		for _, w := range []string{"RECFM=VB LRECL=84 BLKSIZE=27998", "1 line(s) longer than LRECL 84: [2]"} {
+			if !strings.Contains(out, w) {
+				t.Errorf("output %q missing %q", out, w)
+			}
+		}
+	})
+
+	t.Run("auto-dcb with ascii rejected", func(t *testing.T) {
+		fake := &fakeClient{}
+		if _, err := runCLI(t, fake, env, "put", "--auto-dcb", "--ascii", "local", "R"); err == nil {
+			t.Fatal("expected error for --auto-dcb --ascii")
+		}
+		if len(fake.calls) != 0 {
+			t.Errorf("calls = %v, want none", fake.calls)
+		}
+	})
 }
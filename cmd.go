@@ -19,7 +19,17 @@ import (
 // The whole round-trip (write + reply) is serialized on the session mutex so the
 // control stream is never read or written by two goroutines at once, making
 // *FTPSession safe to share across goroutines.
+//
+// The command may change any server setting, so the session forgets the SITE
+// and TYPE values it knows (see Site).
 func (s *FTPSession) SendCommandWithContext(ctx context.Context, expect ReturnCode, command string, a ...string) (string, error) {
+	s.known.forget()
+	return s.sendContext(ctx, expect, command, a...)
+}
+
+// sendContext is SendCommandWithContext for commands the library issues itself,
+// which keep what the session knows of the server's settings.
+func (s *FTPSession) sendContext(ctx context.Context, expect ReturnCode, command string, a ...string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	return s.sendLocked(ctx, expect, command, a...)
@@ -115,11 +125,18 @@ func parseCommand(lg *log.Logger, cmd string, a ...string) []byte {
 // WithReplyTimeout, default 120s) so a server that accepts a command but never
 // replies cannot hang the caller forever; on expiry the control stream is
 // considered unrecoverable and the session is closed. Use SendCommandWithContext
-// to supply a different deadline or cancellation.
+// to supply a different deadline or cancellation. Like SendCommandWithContext it
+// makes the session forget the SITE and TYPE values it knows.
 func (s *FTPSession) SendCommand(expect ReturnCode, command string, a ...string) (string, error) {
+	s.known.forget()
+	return s.send(expect, command, a...)
+}
+
+// send is SendCommand for commands the library issues itself.
+func (s *FTPSession) send(expect ReturnCode, command string, a ...string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.dialCfg.replyTimeout())
 	defer cancel()
-	return s.SendCommandWithContext(ctx, expect, command, a...)
+	return s.sendContext(ctx, expect, command, a...)
 }
 
 // CheckLast reads the server message buffer and validate the return code.
@@ -207,10 +224,10 @@ func (s *FTPSession) System() (string, error) {
 		return sys, nil
 	}
 
-	return s.SendCommand(CodeSysType, "SYST")
+	return s.send(CodeSysType, "SYST")
 }
 
 // CWD changes the current working directory to the specified path.
 func (s *FTPSession) CWD(expression string) (string, error) {
-	return s.SendCommand(CodeFileActionOK, "CWD", expression)
+	return s.send(CodeFileActionOK, "CWD", expression)
 }
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/eol"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// runs builds binary data of byte runs from 1 to 200 long, zeros (filler) among
+// them, and stretches of distinct bytes longer than one data string.
+func runs() []byte {
+	var b []byte
+	for n := 1; n <= 200; n += 7 {
+		b = append(b, bytes.Repeat([]byte{byte(n)}, n)...)
+		b = append(b, make([]byte, n%50)...)
+	}
+	for i := range 300 {
+		b = append(b, byte(i))
+	}
+	return b
+}
+
+// TestCompressed_Retrieve downloads a blank-padded report in MODE C and reports
+// how much smaller it was on the wire.
+func TestCompressed_Retrieve(t *testing.T) {
+	s, srv := dialMock(t)
+	recs := []string{
+		"NAME" + strings.Repeat(" ", 40) + "TOTAL",
+		"ALICE" + strings.Repeat(" ", 39) + "100",
+		"BOB" + strings.Repeat(" ", 41) + "50",
+		strings.Repeat("-", 60),
+	}
+	srv.AddDataset("ME.REPORT", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}, recs...)
+
+	var st zftp.TransferStats
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'ME.REPORT'", &b, zftp.TypeAscii, zftp.WithCompressedMode(), zftp.WithTransferStats(&st)); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if want := strings.Join(recs, eol.System.NewLine()) + eol.System.NewLine(); b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+	if st.Mode != zftp.ModeCompressed || st.Bytes != int64(b.Len()) || st.Ratio() > 0.5 {
+		t.Errorf("stats = %+v, ratio %.2f", st, st.Ratio())
+	}
+	cmds := srv.Commands()
+	if i, j := cmdIndex(cmds, "MODE C"), cmdIndex(cmds, "MODE S"); i < 0 || j < i {
+		t.Errorf("commands = %v", cmds)
+	}
+
+	// Binary records come padded to LRECL and compress as replicated bytes.
+	var bin bytes.Buffer
+	if _, err := s.RetrieveIO("'ME.REPORT'", &bin, zftp.TypeBinary, zftp.WithCompressedMode(), zftp.WithTransferStats(&st)); err != nil {
+		t.Fatalf("RetrieveIO binary: %v", err)
+	}
+	if bin.Len() != 4*80 || st.WireBytes >= st.Bytes {
+		t.Errorf("binary: %d bytes, stats %+v", bin.Len(), st)
+	}
+}
+
+// TestCompressed_RoundTrip stores text and binary data in MODE C and reads them
+// back compressed.
+func TestCompressed_RoundTrip(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddFile("/u/me/runs.bin", nil)
+
+	data := runs()
+	var st zftp.TransferStats
+	if _, err := s.StoreIO("/u/me/runs.bin", bytes.NewReader(data), zftp.TypeBinary, zftp.WithCompressedMode(), zftp.WithTransferStats(&st)); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	if st.Bytes != int64(len(data)) || st.Ratio() >= 1 {
+		t.Errorf("store stats = %+v", st)
+	}
+	var got bytes.Buffer
+	if _, err := s.RetrieveIO("/u/me/runs.bin", &got, zftp.TypeBinary, zftp.WithCompressedMode()); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Errorf("round trip: %d bytes, want %d", got.Len(), len(data))
+	}
+
+	text := "A    B\n" + strings.Repeat(" ", 70) + "X\n\nZZZZZZZZ\n"
+	if _, err := s.StoreIO("'ME.TEXT'", strings.NewReader(text), zftp.TypeAscii, zftp.WithCompressedMode()); err != nil {
+		t.Fatalf("StoreIO ascii: %v", err)
+	}
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'ME.TEXT'", &b, zftp.TypeAscii, zftp.WithCompressedMode()); err != nil {
+		t.Fatalf("RetrieveIO ascii: %v", err)
+	}
+	if want := strings.ReplaceAll(text, "\n", eol.System.NewLine()); b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+// TestCompressed_SessionDefault makes MODE C the session's default and falls
+// back to stream mode, once, when the server rejects it.
+func TestCompressed_SessionDefault(t *testing.T) {
+	srv := mockzos.New(t)
+	srv.AddDataset("ME.DATA", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}, "ONE", "TWO")
+	open := func() *zftp.FTPSession {
+		s, err := zftp.Open(srv.Addr(), zftp.WithTransferMode(zftp.ModeCompressed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		if err := s.Login("ME", "PW"); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	s := open()
+	var st zftp.TransferStats
+	var b strings.Builder
+	if _, err := s.RetrieveIO("'ME.DATA'", &b, zftp.TypeAscii, zftp.WithTransferStats(&st)); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if st.Mode != zftp.ModeCompressed || !strings.HasPrefix(b.String(), "ONE") {
+		t.Errorf("default mode: %q, stats %+v", b.String(), st)
+	}
+
+	srv.Script("MODE C", "504 Data transfer mode C not supported.")
+	s = open()
+	before := countCmd(srv.Commands(), "MODE C")
+	for range 2 {
+		b.Reset()
+		if _, err := s.RetrieveIO("'ME.DATA'", &b, zftp.TypeAscii, zftp.WithTransferStats(&st)); err != nil {
+			t.Fatalf("RetrieveIO after rejection: %v", err)
+		}
+		if st.Mode != zftp.ModeStream || st.Ratio() != 1 || !strings.HasPrefix(b.String(), "ONE") {
+			t.Errorf("fallback: %q, stats %+v", b.String(), st)
+		}
+	}
+	if n := countCmd(srv.Commands(), "MODE C") - before; n != 1 {
+		t.Errorf("MODE C sent %d times after the rejection, want 1", n)
+	}
+}
@@ -0,0 +1,375 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig is the server status read from one STAT reply, typed: the
+// settings the ServerStatus getters report one XSTA query at a time. It is
+// meant for auditing, such as comparing the configuration of two LPARs with
+// Diff, and marshals to JSON with the field names as keys.
+//
+// Parsing is best effort, as for StatusSnapshot: a setting the server does not
+// report, or reports in wording the parser does not know, keeps its zero value.
+// Durations marshal as nanoseconds, as time.Duration does.
+type ServerConfig struct {
+	// FileType is SEQ, JES or SQL.
+	FileType string `json:"FileType"`
+	// Recfm is the record format of new datasets, such as FB or VB.
+	Recfm string `json:"Recfm"`
+	// Lrecl is the logical record length of new datasets.
+	Lrecl int `json:"Lrecl"`
+	// BlockSize is the block size of new datasets.
+	BlockSize int `json:"BlockSize"`
+	// SpaceUnit is TRACKS, CYLINDERS or BLOCKS, the unit of Primary and Secondary.
+	SpaceUnit string `json:"SpaceUnit"`
+	// Primary is the primary space of new datasets.
+	Primary int `json:"Primary"`
+	// Secondary is the secondary space of new datasets.
+	Secondary int `json:"Secondary"`
+	// Directory is the number of directory blocks of new partitioned datasets.
+	Directory int `json:"Directory"`
+	// DataClass is the SMS data class of new datasets.
+	DataClass string `json:"DataClass"`
+	// MgmtClass is the SMS management class of new datasets.
+	MgmtClass string `json:"MgmtClass"`
+	// StorageClass is the SMS storage class of new datasets.
+	StorageClass string `json:"StorageClass"`
+	// Unit is the unit type new datasets are allocated on.
+	Unit string `json:"Unit"`
+	// Volume is the volume serial new datasets are allocated on.
+	Volume string `json:"Volume"`
+	// UCount is the number of devices allocated for a new dataset.
+	UCount int `json:"UCount"`
+	// VCount is the number of tape volumes a new dataset may span.
+	VCount int `json:"VCount"`
+	// RetPD is the retention period of new datasets, in days.
+	RetPD int `json:"RetPD"`
+	// DSNType is the type of new datasets, such as SYSTEM, PDS or LIBRARY.
+	DSNType string `json:"DSNType"`
+	// PDSType is PDS or PDSE, the type of partitioned datasets made by MKD.
+	PDSType string `json:"PDSType"`
+	// EATTR is SYSTEM, NO or OPT, whether new datasets may have extended attributes.
+	EATTR string `json:"EATTR"`
+	// DCBDSN is the dataset whose attributes model new datasets.
+	DCBDSN string `json:"DCBDSN"`
+	// MigrateVol is the volume serial that identifies migrated datasets.
+	MigrateVol string `json:"MigrateVol"`
+	// ConditionDisposition is CATLG or DELETE, what happens to a new dataset
+	// when a store fails.
+	ConditionDisposition string `json:"ConditionDisposition"`
+	// BufNo is the number of access method buffers.
+	BufNo int `json:"BufNo"`
+	// CheckpointInterval is the number of records between block-mode restart
+	// markers.
+	CheckpointInterval int `json:"CheckpointInterval"`
+	// AutoRecall is whether migrated datasets are recalled when accessed.
+	AutoRecall bool `json:"AutoRecall"`
+	// AutoMount is whether volumes that are not mounted are mounted on access.
+	AutoMount bool `json:"AutoMount"`
+	// DirectoryMode is whether dataset qualifiers are treated as directories.
+	DirectoryMode bool `json:"DirectoryMode"`
+	// QuotesOverride is whether a quoted name overrides the working directory.
+	QuotesOverride bool `json:"QuotesOverride"`
+	// ISPFStats is whether ISPF statistics are kept for stored PDS members.
+	ISPFStats bool `json:"ISPFStats"`
+	// RDW is whether record descriptor words are transferred as data.
+	RDW bool `json:"RDW"`
+	// Truncate is whether records longer than LRECL are truncated rather than
+	// failing the store.
+	Truncate bool `json:"Truncate"`
+	// WrapRecord is whether records longer than LRECL wrap onto the next record.
+	WrapRecord bool `json:"WrapRecord"`
+	// TrailingBlanks is whether trailing blanks of fixed-length records are kept
+	// on retrieval.
+	TrailingBlanks bool `json:"TrailingBlanks"`
+	// ASATrans is whether ASA carriage control is translated to C0 controls.
+	ASATrans bool `json:"ASATrans"`
+	// ReadTapeFormat describes the record format of input tapes.
+	ReadTapeFormat string `json:"ReadTapeFormat"`
+	// WRTapeFastIO is whether tape writes may use BSAM I/O.
+	WRTapeFastIO bool `json:"WRTapeFastIO"`
+	// SPRead is whether spool files are retrieved with their carriage control.
+	SPRead bool `json:"SPRead"`
+	// Encoding is SBCS or MBCS, the conversion of the data connection.
+	Encoding string `json:"Encoding"`
+	// SBDataConn is the single-byte conversion of the data connection.
+	SBDataConn string `json:"SBDataConn"`
+	// MBDataConn is the multibyte conversion of the data connection.
+	MBDataConn string `json:"MBDataConn"`
+	// SBSendEol is the line end of single-byte downloads, such as CRLF.
+	SBSendEol string `json:"SBSendEol"`
+	// MBSendEol is the line end of multibyte downloads.
+	MBSendEol string `json:"MBSendEol"`
+	// MBRequireLastEol is whether the last record of a multibyte store must end
+	// with a line end.
+	MBRequireLastEol bool `json:"MBRequireLastEol"`
+	// SBSub is whether untranslatable single-byte characters are substituted.
+	SBSub bool `json:"SBSub"`
+	// SBSubChar is SPACE or the hexadecimal code of the substitution character.
+	SBSubChar string `json:"SBSubChar"`
+	// DBSub is whether untranslatable double-byte characters are substituted.
+	DBSub bool `json:"DBSub"`
+	// UCSHostCS is the host code set of Unicode conversion.
+	UCSHostCS string `json:"UCSHostCS"`
+	// UCSSub is whether characters without an EBCDIC equivalent are substituted.
+	UCSSub bool `json:"UCSSub"`
+	// UCSTrunc is whether records that grow past LRECL on Unicode conversion are
+	// truncated.
+	UCSTrunc bool `json:"UCSTrunc"`
+	// UnicodeFileSystemBOM is ASIS, ADD or REMOVE, the byte-order mark handling
+	// of Unicode files in z/OS UNIX.
+	UnicodeFileSystemBOM string `json:"UnicodeFileSystemBOM"`
+	// XLate is the translate table of the data connection.
+	XLate string `json:"XLate"`
+	// JesEntryLimit is the maximum number of entries a JES listing returns.
+	JesEntryLimit int `json:"JesEntryLimit"`
+	// JesGetByDSN is whether jobs are retrieved by dataset name.
+	JesGetByDSN bool `json:"JesGetByDSN"`
+	// JesJobName is the job name filter of JES listings.
+	JesJobName string `json:"JesJobName"`
+	// JesLrecl is the record length of submitted jobs.
+	JesLrecl int `json:"JesLrecl"`
+	// JesOwner is the owner filter of JES listings.
+	JesOwner string `json:"JesOwner"`
+	// JesRecfm is the record format of submitted jobs, as the server words it.
+	JesRecfm string `json:"JesRecfm"`
+	// JesStatus is the status filter of JES listings.
+	JesStatus string `json:"JesStatus"`
+	// ListLevel is the LISTLEVEL of dataset listings.
+	ListLevel int `json:"ListLevel"`
+	// ListSubDir is whether NLST lists the files of z/OS UNIX subdirectories.
+	ListSubDir bool `json:"ListSubDir"`
+	// UnixFileType is FILE or FIFO.
+	UnixFileType string `json:"UnixFileType"`
+	// UMask is the file mode creation mask of new z/OS UNIX files (0o027).
+	UMask int `json:"UMask"`
+	// SQLCol is NAMES, LABELS or ANY, the column headings of SQL results.
+	SQLCol string `json:"SQLCol"`
+	// DB2 is the DB2 subsystem of SQL queries.
+	DB2 string `json:"DB2"`
+	// Destination is the NJE destination stored datasets are routed to.
+	Destination string `json:"Destination"`
+	// TlsRfcLevel is the level of RFC 4217 support.
+	TlsRfcLevel string `json:"TlsRfcLevel"`
+	// InactiveTime is how long an idle session is kept.
+	InactiveTime time.Duration `json:"InactiveTime"`
+	// DSWaitTime is how long FTP waits for a dataset held by another job.
+	DSWaitTime time.Duration `json:"DSWaitTime"`
+	// DataKeepAlive is the keep-alive interval of data connections.
+	DataKeepAlive time.Duration `json:"DataKeepAlive"`
+	// FTPKeepAlive is the keep-alive interval of the control connection.
+	FTPKeepAlive time.Duration `json:"FTPKeepAlive"`
+	// FifoIoTime is how long a named pipe read or write may wait.
+	FifoIoTime time.Duration `json:"FifoIoTime"`
+	// FifoOpenTime is how long opening a named pipe may wait.
+	FifoOpenTime time.Duration `json:"FifoOpenTime"`
+}
+
+// ConfigChange is a setting that differs between two ServerConfigs.
+type ConfigChange struct {
+	// Field is the ServerConfig field name.
+	Field string `json:"Field"`
+	// From is the value in the config Diff was called on.
+	From any `json:"From"`
+	// To is the value in the other config.
+	To any `json:"To"`
+}
+
+// Diff returns the settings that differ between c and other, in field order;
+// nil when they are the same.
+func (c ServerConfig) Diff(other ServerConfig) []ConfigChange {
+	var changes []ConfigChange
+	a, b := reflect.ValueOf(c), reflect.ValueOf(other)
+	for i := range a.NumField() {
+		if x, y := a.Field(i).Interface(), b.Field(i).Interface(); x != y {
+			changes = append(changes, ConfigChange{Field: a.Type().Field(i).Name, From: x, To: y})
+		}
+	}
+	return changes
+}
+
+// Config reads the server status with a single STAT command and parses it into
+// a ServerConfig.
+func (s *ServerStatus) Config() (ServerConfig, error) {
+	snap, err := s.Snapshot()
+	if err != nil {
+		return ServerConfig{}, err
+	}
+	return snap.Config(), nil
+}
+
+// Config parses the status lines into a ServerConfig (see ServerStatus.Config).
+func (s StatusSnapshot) Config() ServerConfig {
+	var c ServerConfig
+	for _, ln := range s.lines {
+		if k, v, ok := splitStatusKV(ln); ok {
+			if set, found := configValues[strings.ToUpper(k)]; found {
+				set(&c, strings.TrimSuffix(v, "."))
+				continue
+			}
+		}
+		for _, r := range configLines {
+			if m := r.re.FindStringSubmatch(ln); m != nil {
+				r.apply(&c, m)
+				break
+			}
+		}
+	}
+	return c
+}
+
+// configValues set a field from a "KEY is VALUE" status line, by KEY.
+var configValues = map[string]func(c *ServerConfig, v string){
+	"FILETYPE":                        func(c *ServerConfig, v string) { c.FileType = firstWord(v) },
+	"DATACLASS":                       func(c *ServerConfig, v string) { c.DataClass = v },
+	"DATA CLASS":                      func(c *ServerConfig, v string) { c.DataClass = v },
+	"MGMTCLASS":                       func(c *ServerConfig, v string) { c.MgmtClass = v },
+	"MANAGEMENT CLASS":                func(c *ServerConfig, v string) { c.MgmtClass = v },
+	"STORCLASS":                       func(c *ServerConfig, v string) { c.StorageClass = v },
+	"STORAGE CLASS":                   func(c *ServerConfig, v string) { c.StorageClass = v },
+	"UNIT":                            func(c *ServerConfig, v string) { c.Unit = v },
+	"UNIT TYPE":                       func(c *ServerConfig, v string) { c.Unit = v },
+	"VOLUME":                          func(c *ServerConfig, v string) { c.Volume = v },
+	"UCOUNT":                          func(c *ServerConfig, v string) { c.UCount = atoi(v) },
+	"VCOUNT":                          func(c *ServerConfig, v string) { c.VCount = atoi(v) },
+	"RETPD":                           func(c *ServerConfig, v string) { c.RetPD = atoi(v) },
+	"DSNTYPE":                         func(c *ServerConfig, v string) { c.DSNType = v },
+	"PDSTYPE":                         func(c *ServerConfig, v string) { c.PDSType = v },
+	"EATTR":                           func(c *ServerConfig, v string) { c.EATTR = v },
+	"DCBDSN":                          func(c *ServerConfig, v string) { c.DCBDSN = v },
+	"MIGRATEVOL":                      func(c *ServerConfig, v string) { c.MigrateVol = v },
+	"CONDDISP":                        func(c *ServerConfig, v string) { c.ConditionDisposition = v },
+	"BUFNO":                           func(c *ServerConfig, v string) { c.BufNo = atoi(v) },
+	"NUMBER OF ACCESS METHOD BUFFERS": func(c *ServerConfig, v string) { c.BufNo = atoi(v) },
+	"CHKPTINT":                        func(c *ServerConfig, v string) { c.CheckpointInterval = atoi(v) },
+	"CHECKPOINT INTERVAL":             func(c *ServerConfig, v string) { c.CheckpointInterval = atoi(v) },
+	"ISPFSTATS":                       func(c *ServerConfig, v string) { c.ISPFStats = isTrue(v) },
+	"SPREAD":                          func(c *ServerConfig, v string) { c.SPRead = isTrue(v) },
+	"ENCODING":                        func(c *ServerConfig, v string) { c.Encoding = v },
+	"DATA TRANSFER ENCODING":          func(c *ServerConfig, v string) { c.Encoding = v },
+	"SBDATACONN":                      func(c *ServerConfig, v string) { c.SBDataConn = v },
+	"MBDATACONN":                      func(c *ServerConfig, v string) { c.MBDataConn = v },
+	"MULTIBYTE DATA CONVERSION":       func(c *ServerConfig, v string) { c.MBDataConn = strings.Trim(v, "()") },
+	"SBSENDEOL":                       func(c *ServerConfig, v string) { c.SBSendEol = v },
+	"MBSENDEOL":                       func(c *ServerConfig, v string) { c.MBSendEol = v },
+	"MBREQUIRELASTEOL":                func(c *ServerConfig, v string) { c.MBRequireLastEol = isTrue(v) },
+	"SBSUB":                           func(c *ServerConfig, v string) { c.SBSub = isTrue(v) },
+	"SBSUBCHAR":                       func(c *ServerConfig, v string) { c.SBSubChar = v },
+	"DBSUB":                           func(c *ServerConfig, v string) { c.DBSub = isTrue(v) },
+	"UCSHOSTCS":                       func(c *ServerConfig, v string) { c.UCSHostCS = v },
+	"UCSSUB":                          func(c *ServerConfig, v string) { c.UCSSub = isTrue(v) },
+	"UCSTRUNC":                        func(c *ServerConfig, v string) { c.UCSTrunc = isTrue(v) },
+	"UNICODEFILESYSTEMBOM":            func(c *ServerConfig, v string) { c.UnicodeFileSystemBOM = v },
+	"XLATE":                           func(c *ServerConfig, v string) { c.XLate = v },
+	"JESENTRYLIMIT":                   func(c *ServerConfig, v string) { c.JesEntryLimit = atoi(v) },
+	"JESGETBYDSN":                     func(c *ServerConfig, v string) { c.JesGetByDSN = isTrue(v) },
+	"JESJOBNAME":                      func(c *ServerConfig, v string) { c.JesJobName = v },
+	"JESLRECL":                        func(c *ServerConfig, v string) { c.JesLrecl = atoi(v) },
+	"JESOWNER":                        func(c *ServerConfig, v string) { c.JesOwner = v },
+	"JESRECFM":                        func(c *ServerConfig, v string) { c.JesRecfm = v },
+	"JESSTATUS":                       func(c *ServerConfig, v string) { c.JesStatus = v },
+	"LISTLEVEL":                       func(c *ServerConfig, v string) { c.ListLevel = atoi(v) },
+	"LISTSUBDIR":                      func(c *ServerConfig, v string) { c.ListSubDir = isTrue(v) },
+	"UNIXFILETYPE":                    func(c *ServerConfig, v string) { c.UnixFileType = v },
+	"UMASK":                           func(c *ServerConfig, v string) { c.UMask = octal(v) },
+	"UMASK VALUE":                     func(c *ServerConfig, v string) { c.UMask = octal(v) },
+	"SQLCOL":                          func(c *ServerConfig, v string) { c.SQLCol = v },
+	"DB2":                             func(c *ServerConfig, v string) { c.DB2 = v },
+	"DB2 SUBSYSTEM NAME":              func(c *ServerConfig, v string) { c.DB2 = v },
+	"DEST":                            func(c *ServerConfig, v string) { c.Destination = v },
+	"TLSRFCLEVEL":                     func(c *ServerConfig, v string) { c.TlsRfcLevel = v },
+	"INACTIVITY TIMER":                func(c *ServerConfig, v string) { c.InactiveTime = seconds(v) },
+	"INACTIVETIME":                    func(c *ServerConfig, v string) { c.InactiveTime = seconds(v) },
+	"DSWAITTIME":                      func(c *ServerConfig, v string) { c.DSWaitTime = time.Duration(atoi(v)) * time.Minute },
+	"DATAKEEPALIVE":                   func(c *ServerConfig, v string) { c.DataKeepAlive = seconds(v) },
+	"FTPKEEPALIVE":                    func(c *ServerConfig, v string) { c.FTPKeepAlive = seconds(v) },
+	"FIFOIOTIME":                      func(c *ServerConfig, v string) { c.FifoIoTime = seconds(v) },
+	"FIFOOPENTIME":                    func(c *ServerConfig, v string) { c.FifoOpenTime = seconds(v) },
+}
+
+// configLines set fields from the status lines z/OS words as prose; the first
+// matching rule applies.
+var configLines = []struct {
+	re    *regexp.Regexp
+	apply func(c *ServerConfig, m []string)
+}{
+	{fileTypeLine, func(c *ServerConfig, m []string) { c.FileType = m[1] }},
+	{recFmt, func(c *ServerConfig, m []string) {
+		c.Recfm, c.Lrecl, c.BlockSize = m[1], atoi(m[2]), atoi(m[3])
+	}},
+	{regexp.MustCompile(`(?i)^Primary allocation (\d+) (track|cylinder|block)s?, secondary allocation (\d+)`), func(c *ServerConfig, m []string) {
+		c.Primary, c.SpaceUnit, c.Secondary = atoi(m[1]), strings.ToUpper(m[2])+"S", atoi(m[3])
+	}},
+	{regexp.MustCompile(`(?i)created with (\d+) directory blocks`), func(c *ServerConfig, m []string) { c.Directory = atoi(m[1]) }},
+	{regexp.MustCompile(`(?i)^RDWs `), func(c *ServerConfig, m []string) {
+		v, _ := rdwValue(m[0])
+		c.RDW = v == "RDW"
+	}},
+	{regexp.MustCompile(`(?i)^Automatic recall.*`), func(c *ServerConfig, m []string) { c.AutoRecall = !negative(m[0]) }},
+	{regexp.MustCompile(`(?i)^Automatic mount.*`), func(c *ServerConfig, m []string) { c.AutoMount = !negative(m[0]) }},
+	{regexp.MustCompile(`(?i)^(Data set|Directory) mode`), func(c *ServerConfig, m []string) {
+		c.DirectoryMode = strings.EqualFold(m[1], "Directory")
+	}},
+	{regexp.MustCompile(`(?i)^Single quotes will .*`), func(c *ServerConfig, m []string) {
+		c.QuotesOverride = strings.Contains(strings.ToLower(m[0]), "override")
+	}},
+	{regexp.MustCompile(`(?i)^.*if a store operation ends abnormally`), func(c *ServerConfig, m []string) {
+		c.ConditionDisposition = "CATLG"
+		if strings.Contains(strings.ToLower(m[0]), "delete") {
+			c.ConditionDisposition = "DELETE"
+		}
+	}},
+	{regexp.MustCompile(`(?i)^Truncated records .*`), func(c *ServerConfig, m []string) { c.Truncate = negative(m[0]) }},
+	{regexp.MustCompile(`(?i)^.*\bwrapped\b.*`), func(c *ServerConfig, m []string) { c.WrapRecord = !negative(m[0]) }},
+	{regexp.MustCompile(`(?i)^Trailing blanks .*`), func(c *ServerConfig, m []string) {
+		c.TrailingBlanks = !strings.Contains(strings.ToLower(m[0]), "removed")
+	}},
+	{regexp.MustCompile(`(?i)^ASA control characters .*`), func(c *ServerConfig, m []string) {
+		c.ASATrans = strings.Contains(strings.ToUpper(m[0]), "C0")
+	}},
+	{regexp.MustCompile(`(?i)^Records on input tape are (.+?)\.?$`), func(c *ServerConfig, m []string) { c.ReadTapeFormat = m[1] }},
+	{regexp.MustCompile(`(?i)^Tape write .*BSAM`), func(c *ServerConfig, m []string) { c.WRTapeFastIO = !negative(m[0]) }},
+}
+
+var negativeWord = regexp.MustCompile(`(?i)\b(not|no|never|disabled)\b`)
+
+// negative reports whether a prose status line says a setting is off.
+func negative(line string) bool {
+	return negativeWord.MatchString(line)
+}
+
+func firstWord(v string) string {
+	if f := strings.Fields(v); len(f) > 0 {
+		return f[0]
+	}
+	return ""
+}
+
+func atoi(v string) int {
+	n, _ := strconv.Atoi(firstWord(v))
+	return n
+}
+
+func octal(v string) int {
+	n, _ := strconv.ParseInt(firstWord(v), 8, 32)
+	return int(n)
+}
+
+func seconds(v string) time.Duration {
+	return time.Duration(atoi(v)) * time.Second
+}
+
+func isTrue(v string) bool {
+	switch strings.ToUpper(firstWord(v)) {
+	case "TRUE", "ON", "YES":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeConfigStat is a fuller STAT reply in z/OS wording, covering the prose
+// lines ServerConfig parses as well as the "KEY is VALUE" forms.
+const fakeConfigStat = "211-Server FTP talking to host 10.0.0.1, port 49884\n" +
+	"211-User: MVSUSER  Working directory: MVSUSER.\n" +
+	"211-The control connection has transferred 1234 bytes\n" +
+	"211-There is no current data connection.\n" +
+	"211-Number of access method buffers is 5\n" +
+	"211-Checkpoint interval is 0\n" +
+	"211-Automatic recall of migrated data sets.\n" +
+	"211-Automatic mount of direct access volumes.\n" +
+	"211-Inactivity timer is set to 300\n" +
+	"211-Server site variable DSWAITTIME is set to 10\n" +
+	"211-Server site variable DATAKEEPALIVE is set to 60\n" +
+	"211-Server site variable FIFOIOTIME is set to 20\n" +
+	"211-Data set mode.  (Do not treat each qualifier as a directory.)\n" +
+	"211-Primary allocation 2 tracks, secondary allocation 1 track.\n" +
+	"211-Partitioned data sets will be created with 27 directory blocks.\n" +
+	"211-Record format FB, Lrecl: 80, Blocksize: 27920\n" +
+	"211-New data sets will be catalogued if a store operation ends abnormally\n" +
+	"211-Single quotes will override the current working directory.\n" +
+	"211-RDWs from variable format data sets are discarded.\n" +
+	"211-Trailing blanks are removed from a fixed format data set when it is\n" +
+	"211- retrieved.\n" +
+	"211-Data transfer encoding is SBCS\n" +
+	"211-SBDATACONN is (IBM-1047,ISO8859-1)\n" +
+	"211-SBSENDEOL is CRLF\n" +
+	"211-Server site variable ISPFSTATS is set to TRUE\n" +
+	"211-FileType SEQ (Sequential - default).\n" +
+	"211-JESLRECL is 80\n" +
+	"211-JESENTRYLIMIT is 200\n" +
+	"211-JESOWNER is MVSUSER\n" +
+	"211-UMASK value is 027\n" +
+	"211-Server site variable LISTLEVEL is set to 1\n" +
+	"211-Server site variable DB2 subsystem name is DB2\n" +
+	"211 *** end of status ***"
+
+func TestServerStatus_Config(t *testing.T) {
+	calls := 0
+	ss := &ServerStatus{stat: func(a ...string) (string, error) {
+		calls++
+		return fakeConfigStat, nil
+	}}
+	got, err := ss.Config()
+	if err != nil {
+		t.Fatalf("Config: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("STAT issued %d times, want 1", calls)
+	}
+	want := ServerConfig{
+		FileType: "SEQ", Recfm: "FB", Lrecl: 80, BlockSize: 27920,
+		SpaceUnit: "TRACKS", Primary: 2, Secondary: 1, Directory: 27,
+		ConditionDisposition: "CATLG", BufNo: 5,
+		AutoRecall: true, AutoMount: true, QuotesOverride: true, ISPFStats: true,
+		Encoding: "SBCS", SBDataConn: "(IBM-1047,ISO8859-1)", SBSendEol: "CRLF",
+		JesEntryLimit: 200, JesLrecl: 80, JesOwner: "MVSUSER", ListLevel: 1,
+		UMask: 0o027, DB2: "DB2",
+		InactiveTime: 300 * time.Second, DSWaitTime: 10 * time.Minute,
+		DataKeepAlive: time.Minute, FifoIoTime: 20 * time.Second,
+	}
+	if changes := want.Diff(got); changes != nil {
+		t.Errorf("Config differs from want: %+v", changes)
+	}
+}
+
+func TestServerConfig_JSONRoundTrip(t *testing.T) {
+	in := parseStatusSnapshot(fakeConfigStat).Config()
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out ServerConfig
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip changed the config: %+v", in.Diff(out))
+	}
+}
+
+func TestServerConfig_Diff(t *testing.T) {
+	a := ServerConfig{Lrecl: 80, Recfm: "FB", RDW: false, InactiveTime: time.Minute}
+	if d := a.Diff(a); d != nil {
+		t.Errorf("Diff with itself = %v, want nil", d)
+	}
+	b := a
+	b.Lrecl, b.RDW = 133, true
+	want := []ConfigChange{
+		{Field: "Lrecl", From: 80, To: 133},
+		{Field: "RDW", From: false, To: true},
+	}
+	if got := a.Diff(b); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package copybook_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/ro-ag/zftp.v2/copybook"
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+const customer = `
+000100* CUSTOMER MASTER RECORD
+000200 01  CUSTOMER-REC.
+000300     05  CUST-ID            PIC 9(6).
+000400     05  CUST-NAME          PIC X(10).
+000500     05  CUST-NAME-R        REDEFINES CUST-NAME.
+000600         10  NAME-NUM       PIC 9(10).
+000700     05  BALANCE            PIC S9(5)V99 COMP-3.
+000800     05  FLAGS              PIC S9(4) USAGE IS BINARY.
+000900     05  ORDER-COUNT        PIC 99 VALUE ZERO.
+001000         88  NO-ORDERS      VALUE 0.
+001100     05  FILLER             PIC XX.
+001200     05  RATE               COMP-2.
+001300     05  ORDERS OCCURS 0 TO 3 TIMES DEPENDING ON ORDER-COUNT
+001400                 INDEXED BY ORD-IX.
+001500         10  ORD-NO         PIC 9(4).
+001600         10  ORD-AMT        PIC S9(3)V9
+001700                            SIGN IS LEADING SEPARATE CHARACTER.
+`
+
+// record builds the test record: two orders, so the table takes 18 of its 27
+// bytes.
+func record() []byte {
+	cp := ebcdic.IBM037
+	var b []byte
+	b = append(b, cp.Encode("001234", ebcdic.KeepNL)...)
+	b = append(b, cp.Encode("ALICE     ", ebcdic.KeepNL)...)
+	b = append(b, 0x12, 0x34, 0x56, 0x7D) // -12345.67
+	b = append(b, 0xFF, 0xFE)             // -2
+	b = append(b, cp.Encode("02  ", ebcdic.KeepNL)...)
+	b = append(b, 0x40, 0x80, 0, 0, 0, 0, 0, 0) // 0.5
+	b = append(b, cp.Encode("0001+0150", ebcdic.KeepNL)...)
+	b = append(b, cp.Encode("0002-0025", ebcdic.KeepNL)...)
+	return b
+}
+
+func parse(t *testing.T, src string) *copybook.Layout {
+	t.Helper()
+	l, err := copybook.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return l
+}
+
+// TestParse checks kinds, sizes and offsets, including a redefinition and a
+// variable-length table at its maximum size.
+func TestParse(t *testing.T) {
+	l := parse(t, customer)
+	if l.Root.Name != "CUSTOMER-REC" || l.Size() != 61 {
+		t.Fatalf("root %s, size %d; want CUSTOMER-REC, 61", l.Root.Name, l.Size())
+	}
+	for i, want := range []struct {
+		name         string
+		kind         copybook.Kind
+		size, offset int
+	}{
+		{"CUST-ID", copybook.Zoned, 6, 0},
+		{"CUST-NAME", copybook.Alphanumeric, 10, 6},
+		{"CUST-NAME-R", copybook.Group, 10, 6},
+		{"BALANCE", copybook.Packed, 4, 16},
+		{"FLAGS", copybook.Binary, 2, 20},
+		{"ORDER-COUNT", copybook.Zoned, 2, 22},
+		{"FILLER", copybook.Alphanumeric, 2, 24},
+		{"RATE", copybook.Float, 8, 26},
+		{"ORDERS", copybook.Group, 9, 34},
+	} {
+		f := l.Root.Children[i]
+		if f.Name != want.name || f.Kind != want.kind || f.Size != want.size || f.Offset != want.offset {
+			t.Errorf("item %d = %s %s size %d offset %d; want %+v", i, f.Name, f.Kind, f.Size, f.Offset, want)
+		}
+	}
+	orders := l.Root.Children[8]
+	if orders.Occurs != 3 || orders.DependingOn != "ORDER-COUNT" || !orders.Children[1].SignSeparate {
+		t.Errorf("ORDERS = %+v", orders)
+	}
+}
+
+// TestDecode_JSON decodes every kind of item and writes the record as a JSON
+// line in copybook order.
+func TestDecode_JSON(t *testing.T) {
+	l := parse(t, customer)
+	var out bytes.Buffer
+	enc := copybook.NewEncoder(&out, l, copybook.JSON)
+	if err := enc.Encode(record()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"CUST-ID":1234,"CUST-NAME":"ALICE","CUST-NAME-R":{"NAME-NUM":null},"BALANCE":-12345.67,"FLAGS":-2,` +
+		`"ORDER-COUNT":2,"RATE":0.5,"ORDERS":[{"ORD-NO":1,"ORD-AMT":15.0},{"ORD-NO":2,"ORD-AMT":-2.5}]}` + "\n"
+	if out.String() != want {
+		t.Errorf("JSON\n got %s\nwant %s", out.String(), want)
+	}
+}
+
+// TestDecode_CSV writes a column per occurrence and leaves the occurrences the
+// DEPENDING ON count excludes empty.
+func TestDecode_CSV(t *testing.T) {
+	l := parse(t, customer)
+	var out bytes.Buffer
+	enc := copybook.NewEncoder(&out, l, copybook.CSV)
+	if err := enc.Encode(record()); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "CUST-ID,CUST-NAME,NAME-NUM,BALANCE,FLAGS,ORDER-COUNT,RATE," +
+		"ORD-NO(1),ORD-AMT(1),ORD-NO(2),ORD-AMT(2),ORD-NO(3),ORD-AMT(3)\n" +
+		"1234,ALICE,,-12345.67,-2,2,0.5,1,15.0,2,-2.5,,\n"
+	if out.String() != want {
+		t.Errorf("CSV\n got %q\nwant %q", out.String(), want)
+	}
+}
+
+// TestUnmarshal fills a struct by tag and by folded field name.
+func TestUnmarshal(t *testing.T) {
+	type order struct {
+		OrdNo  int
+		Amount copybook.Decimal `cobol:"ORD-AMT"`
+	}
+	var c struct {
+		CustID   uint32
+		CustName string
+		Balance  float64
+		Flags    int16
+		Rate     float64
+		Orders   []order
+		Ignored  string `cobol:"-"`
+	}
+	if err := parse(t, customer).Unmarshal(record(), &c); err != nil {
+		t.Fatal(err)
+	}
+	if c.CustID != 1234 || c.CustName != "ALICE" || c.Balance != -12345.67 || c.Flags != -2 || c.Rate != 0.5 {
+		t.Errorf("decoded %+v", c)
+	}
+	if len(c.Orders) != 2 || c.Orders[1].OrdNo != 2 || c.Orders[1].Amount.String() != "-2.5" {
+		t.Errorf("orders %+v", c.Orders)
+	}
+
+	var bad struct{ Balance int }
+	if err := parse(t, customer).Unmarshal(record(), &bad); err == nil || !strings.Contains(err.Error(), "fraction") {
+		t.Errorf("BALANCE into int: err = %v", err)
+	}
+}
+
+// TestDecode_Numbers covers signs in the zone of the last and first digits,
+// unsigned packed and binary items, COMP-1, and blank numeric items.
+func TestDecode_Numbers(t *testing.T) {
+	l := parse(t, `
+       01  NUMS.
+           05  TRAIL    PIC S999.
+           05  LEAD     PIC S9(3) SIGN LEADING.
+           05  UPACK    PIC 9(4) COMP-3.
+           05  UBIN     PIC 9(9) COMP-5.
+           05  SHORT    COMP-1.
+           05  EMPTY    PIC S9(3)V9.
+`)
+	rec := []byte{
+		0xF1, 0xF2, 0xD3, // -123
+		0xC4, 0xF5, 0xF6, // +456
+		0x01, 0x23, 0x4F, // 1234
+		0xFF, 0xFF, 0xFF, 0xFF, // 4294967295
+		0xC1, 0x10, 0x00, 0x00, // -1.0
+		0x40, 0x40, 0x40, 0x40,
+	}
+	m, err := l.Decode(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"TRAIL": "-123", "LEAD": "456", "UPACK": "1234", "UBIN": "4294967295"} {
+		if got, ok := m[name].(copybook.Decimal); !ok || got.String() != want {
+			t.Errorf("%s = %v, want %s", name, m[name], want)
+		}
+	}
+	if m["SHORT"] != -1.0 || m["EMPTY"] != nil {
+		t.Errorf("SHORT = %v, EMPTY = %v", m["SHORT"], m["EMPTY"])
+	}
+}
+
+// TestDecode_ZeroBinaryCount decodes a COMP counter of zero as 0, not blank,
+// so it can drive an empty OCCURS DEPENDING ON table.
+func TestDecode_ZeroBinaryCount(t *testing.T) {
+	l := parse(t, `
+       01  REC.
+           05  CNT      PIC S9(4) COMP.
+           05  ITEMS OCCURS 0 TO 2 TIMES DEPENDING ON CNT.
+               10  ITEM PIC X.
+`)
+	m, err := l.Decode([]byte{0x00, 0x00})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if got, ok := m["CNT"].(copybook.Decimal); !ok || got.String() != "0" {
+		t.Errorf("CNT = %v, want 0", m["CNT"])
+	}
+	if items, ok := m["ITEMS"].([]any); !ok || len(items) != 0 {
+		t.Errorf("ITEMS = %#v, want none", m["ITEMS"])
+	}
+}
+
+// TestDecode_Errors rejects bad digits, short records and out-of-range
+// DEPENDING ON counts.
+func TestDecode_Errors(t *testing.T) {
+	l := parse(t, customer)
+	rec := record()
+
+	bad := bytes.Clone(rec)
+	bad[19] = 0x71 // packed sign nibble 1
+	if _, err := l.Decode(bad); !errors.Is(err, copybook.ErrInvalidData) || !strings.Contains(err.Error(), "BALANCE") {
+		t.Errorf("bad packed: %v", err)
+	}
+	if _, err := l.Decode(rec[:len(rec)-1]); !errors.Is(err, copybook.ErrShortRecord) {
+		t.Errorf("short record: %v", err)
+	}
+	many := bytes.Clone(rec)
+	many[23] = 0xF4 // ORDER-COUNT 04
+	if _, err := l.Decode(many); !errors.Is(err, copybook.ErrInvalidData) {
+		t.Errorf("ORDER-COUNT 4: %v", err)
+	}
+}
+
+// TestParse_Records treats a second 01 as a redefinition of the first, and 77
+// items as records of their own.
+func TestParse_Records(t *testing.T) {
+	l := parse(t, `
+       01  HEADER-REC.
+           05  REC-TYPE   PIC X.
+           05  RUN-DATE   PIC 9(8).
+       01  DETAIL-REC.
+           05  FILLER     PIC X.
+           05  ITEM       PIC X(20).
+       77  TOTAL          PIC S9(7) COMP-3.
+`)
+	if l.Root.Name != "FILLER" || len(l.Root.Children) != 3 || l.Size() != 21 {
+		t.Fatalf("root %s with %d items, size %d", l.Root.Name, len(l.Root.Children), l.Size())
+	}
+	if d := l.Root.Children[1]; d.Redefines != "HEADER-REC" || d.Offset != 0 {
+		t.Errorf("DETAIL-REC redefines %q at %d", d.Redefines, d.Offset)
+	}
+	if tot := l.Root.Children[2]; tot.Redefines != "" || tot.Offset != 9 {
+		t.Errorf("TOTAL redefines %q at %d", tot.Redefines, tot.Offset)
+	}
+}
+
+// TestParse_Errors reports what cannot be laid out.
+func TestParse_Errors(t *testing.T) {
+	for _, src := range []string{
+		"       01  A PIC N(4).",
+		"       01  A PIC 9(4) COMP-3",
+		"       01  A PIC 9(19).",
+		"       01  A.\n           05  B PIC X.\n           05  C REDEFINES D PIC X.",
+		"       01  A PIC X.\n           05  B PIC X.",
+		"       01  A OCCURS X.",
+		"       01  A PIC X RENAMES B.",
+		"      *only a comment",
+	} {
+		if _, err := copybook.Parse(strings.NewReader(src)); !errors.Is(err, copybook.ErrSyntax) {
+			t.Errorf("Parse(%q) err = %v, want ErrSyntax", src, err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package copybook
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+var (
+	// ErrShortRecord is returned when a record ends before an item it should
+	// hold.
+	ErrShortRecord = errors.New("copybook: record shorter than layout")
+	// ErrInvalidData is returned for a numeric item whose bytes are not a valid
+	// encoding, or an OCCURS DEPENDING ON count out of range.
+	ErrInvalidData = errors.New("copybook: invalid data")
+)
+
+// Decimal is a fixed-point number: Unscaled × 10^-Scale. Zoned, packed and
+// binary items decode to a Decimal so no precision is lost.
+type Decimal struct {
+	// Unscaled holds every digit of the value, with its sign.
+	Unscaled int64
+	// Scale is the number of digits after the decimal point.
+	Scale int
+}
+
+// String formats d with Scale digits after the decimal point, e.g. "-12.50".
+func (d Decimal) String() string {
+	s := strconv.FormatInt(d.Unscaled, 10)
+	if d.Scale <= 0 {
+		return s
+	}
+	sign := ""
+	if d.Unscaled < 0 {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= d.Scale {
+		s = strings.Repeat("0", d.Scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+}
+
+// Float64 returns d as the nearest float64.
+func (d Decimal) Float64() float64 {
+	return float64(d.Unscaled) / math.Pow10(d.Scale)
+}
+
+// MarshalJSON writes d as a JSON number with its exact digits.
+func (d Decimal) MarshalJSON() ([]byte, error) { return []byte(d.String()), nil }
+
+// Decode decodes one record into a map from item names to values. A group is a
+// nested map[string]any and a table a []any (holding only the occurrences an
+// OCCURS DEPENDING ON count selects); FILLER items are left out. Elementary
+// values are a string with trailing spaces removed (alphanumeric), a Decimal
+// (zoned, packed and binary) or a float64 (COMP-1 and COMP-2). A numeric item
+// holding only spaces or low-values decodes to nil.
+//
+// Items that redefine storage are decoded as well; as only one of the
+// alternatives usually holds valid data, an alternative that cannot be decoded
+// is nil rather than an error.
+func (l *Layout) Decode(rec []byte) (map[string]any, error) {
+	d := &decoder{rec: rec, cp: l.CodePage, counts: map[string]int64{}}
+	if d.cp == nil {
+		d.cp = ebcdic.IBM037
+	}
+	m, _, err := d.group(l.Root, 0, false)
+	return m, err
+}
+
+type decoder struct {
+	rec    []byte
+	cp     *ebcdic.CodePage
+	counts map[string]int64 // last value of each numeric item, for DEPENDING ON
+}
+
+// group decodes the children of f starting at off and returns the bytes the
+// group takes in this record.
+func (d *decoder) group(f *Field, off int, lenient bool) (map[string]any, int, error) {
+	m := make(map[string]any, len(f.Children))
+	pos, end, starts := off, off, map[string]int{}
+	for _, c := range f.Children {
+		start := pos
+		if c.Redefines != "" {
+			start = starts[c.Redefines]
+		}
+		v, n, err := d.entry(c, start, lenient || c.Redefines != "")
+		if err != nil {
+			return nil, 0, err
+		}
+		if c.Redefines == "" {
+			starts[c.Name] = pos
+			pos += n
+		}
+		end = max(end, start+n)
+		if !c.IsFiller() {
+			m[c.Name] = v
+		}
+	}
+	return m, end - off, nil
+}
+
+// entry decodes f with its occurrences.
+func (d *decoder) entry(f *Field, off int, lenient bool) (any, int, error) {
+	if f.Occurs == 0 {
+		return d.item(f, off, lenient)
+	}
+	count := int64(f.Occurs)
+	if f.DependingOn != "" {
+		n, ok := d.counts[f.DependingOn]
+		if !ok {
+			return nil, 0, fmt.Errorf("%w: %s depends on %s, which precedes no value", ErrInvalidData, f.Name, f.DependingOn)
+		}
+		if n < int64(f.MinOccurs) || n > count {
+			return nil, 0, fmt.Errorf("%w: %s = %d, outside OCCURS %d TO %d of %s", ErrInvalidData, f.DependingOn, n, f.MinOccurs, f.Occurs, f.Name)
+		}
+		count = n
+	}
+	elems := make([]any, count)
+	pos := off
+	for i := range elems {
+		v, n, err := d.item(f, pos, lenient)
+		if err != nil {
+			return nil, 0, err
+		}
+		elems[i] = v
+		pos += n
+	}
+	return elems, pos - off, nil
+}
+
+// item decodes one occurrence of f.
+func (d *decoder) item(f *Field, off int, lenient bool) (any, int, error) {
+	if f.Kind == Group {
+		return d.group(f, off, lenient)
+	}
+	if off+f.Size > len(d.rec) {
+		if lenient {
+			return nil, f.Size, nil
+		}
+		return nil, 0, fmt.Errorf("%w: %s needs bytes %d to %d of a %d-byte record", ErrShortRecord, f.Name, off+1, off+f.Size, len(d.rec))
+	}
+	b := d.rec[off : off+f.Size]
+	v, err := d.value(f, b)
+	if err != nil {
+		if lenient {
+			return nil, f.Size, nil
+		}
+		return nil, 0, fmt.Errorf("%s at offset %d: %w", f.Name, off, err)
+	}
+	if dec, ok := v.(Decimal); ok && !f.IsFiller() {
+		d.counts[f.Name] = dec.Unscaled / int64(math.Pow10(dec.Scale))
+	}
+	return v, f.Size, nil
+}
+
+func (d *decoder) value(f *Field, b []byte) (any, error) {
+	switch f.Kind {
+	case Alphanumeric:
+		return strings.TrimRight(d.cp.Decode(b, ebcdic.KeepNL), " "), nil
+	case Float:
+		return hexFloat(b), nil
+	}
+	// Every bit pattern is a valid binary number: 0x0000 is zero, not blank.
+	if f.Kind != Binary && blank(b) {
+		return nil, nil
+	}
+	var (
+		n   int64
+		err error
+	)
+	switch f.Kind {
+	case Zoned:
+		n, err = zoned(f, b)
+	case Packed:
+		n, err = packed(f, b)
+	case Binary:
+		n, err = binaryInt(f, b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Decimal{Unscaled: n, Scale: f.Scale}, nil
+}
+
+// blank reports bytes that are all EBCDIC spaces or all low-values: an item
+// never initialized with a number.
+func blank(b []byte) bool {
+	return strings.Trim(string(b), "\x40") == "" || strings.Trim(string(b), "\x00") == ""
+}
+
+// negative reports whether a sign nibble is negative; ok is false for a nibble
+// that is not a sign.
+func negative(nibble byte) (neg, ok bool) {
+	switch nibble {
+	case 0xB, 0xD:
+		return true, true
+	case 0xA, 0xC, 0xE, 0xF:
+		return false, true
+	}
+	return false, false
+}
+
+func zoned(f *Field, b []byte) (int64, error) {
+	neg := false
+	digits := b
+	if f.SignSeparate {
+		sign := b[len(b)-1]
+		digits = b[:len(b)-1]
+		if f.SignLeading {
+			sign, digits = b[0], b[1:]
+		}
+		switch sign {
+		case 0x4E: // '+'
+		case 0x60: // '-'
+			neg = true
+		default:
+			return 0, fmt.Errorf("%w: sign byte %#02x", ErrInvalidData, sign)
+		}
+	}
+	signAt := -1
+	if f.Signed && !f.SignSeparate {
+		signAt = len(digits) - 1
+		if f.SignLeading {
+			signAt = 0
+		}
+	}
+	var n int64
+	for i, c := range digits {
+		zone, digit := c>>4, c&0x0F
+		if digit > 9 {
+			return 0, fmt.Errorf("%w: zoned digit %#02x", ErrInvalidData, c)
+		}
+		if i == signAt {
+			var ok bool
+			if neg, ok = negative(zone); !ok {
+				return 0, fmt.Errorf("%w: zoned sign %#02x", ErrInvalidData, c)
+			}
+		} else if zone != 0xF {
+			return 0, fmt.Errorf("%w: zoned digit %#02x", ErrInvalidData, c)
+		}
+		n = n*10 + int64(digit)
+	}
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+func packed(f *Field, b []byte) (int64, error) {
+	// An even digit count leaves a spare high nibble, which must be zero.
+	if 2*len(b)-1 > f.Digits && b[0]>>4 != 0 {
+		return 0, fmt.Errorf("%w: packed digit %#02x", ErrInvalidData, b[0])
+	}
+	var n int64
+	for i, c := range b {
+		hi, lo := c>>4, c&0x0F
+		if hi > 9 {
+			return 0, fmt.Errorf("%w: packed digit %#02x", ErrInvalidData, c)
+		}
+		n = n*10 + int64(hi)
+		if i < len(b)-1 {
+			if lo > 9 {
+				return 0, fmt.Errorf("%w: packed digit %#02x", ErrInvalidData, c)
+			}
+			n = n*10 + int64(lo)
+			continue
+		}
+		neg, ok := negative(lo)
+		if !ok {
+			return 0, fmt.Errorf("%w: packed sign %#02x", ErrInvalidData, c)
+		}
+		if neg {
+			n = -n
+		}
+	}
+	return n, nil
+}
+
+func binaryInt(f *Field, b []byte) (int64, error) {
+	var u uint64
+	switch len(b) {
+	case 2:
+		u = uint64(binary.BigEndian.Uint16(b))
+		if f.Signed {
+			return int64(int16(u)), nil
+		}
+	case 4:
+		u = uint64(binary.BigEndian.Uint32(b))
+		if f.Signed {
+			return int64(int32(u)), nil
+		}
+	default:
+		u = binary.BigEndian.Uint64(b)
+		if f.Signed {
+			return int64(u), nil
+		}
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("%w: unsigned binary %d overflows", ErrInvalidData, u)
+		}
+	}
+	return int64(u), nil
+}
+
+// hexFloat converts IBM hexadecimal floating point (4 or 8 bytes: sign bit,
+// 7-bit base-16 exponent biased by 64, then the fraction) to a float64.
+func hexFloat(b []byte) float64 {
+	var frac uint64
+	for _, c := range b[1:] {
+		frac = frac<<8 | uint64(c)
+	}
+	bits := 8 * (len(b) - 1)
+	v := math.Ldexp(float64(frac), 4*(int(b[0]&0x7F)-64)-bits)
+	if b[0]&0x80 != 0 {
+		v = -v
+	}
+	return v
+}
@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package copybook
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Format is an output format for decoded records.
+type Format uint8
+
+const (
+	// JSON writes one JSON object per record (JSON Lines), with keys in copybook
+	// order.
+	JSON Format = iota
+	// CSV writes a header row naming every elementary item, then one row per
+	// record. A table item has a column per occurrence, named with its
+	// subscripts: "AMOUNT(1)", "CELL(2,3)".
+	CSV
+)
+
+// String returns "json" or "csv".
+func (f Format) String() string {
+	switch f {
+	case JSON:
+		return "json"
+	case CSV:
+		return "csv"
+	}
+	return "Format(" + strconv.Itoa(int(f)) + ")"
+}
+
+// ParseFormat returns the Format named "json" or "csv", case-insensitively.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "json":
+		return JSON, nil
+	case "csv":
+		return CSV, nil
+	}
+	return 0, fmt.Errorf("copybook: unknown format %q (want json or csv)", s)
+}
+
+// Encoder decodes records with a Layout and writes them in a Format.
+type Encoder struct {
+	l       *Layout
+	f       Format
+	w       *bufio.Writer
+	csv     *csv.Writer
+	columns []column
+	row     []string
+}
+
+// NewEncoder returns an Encoder writing to w. Output is buffered; call Flush
+// when done.
+func NewEncoder(w io.Writer, l *Layout, f Format) *Encoder {
+	e := &Encoder{l: l, f: f, w: bufio.NewWriter(w)}
+	if f == CSV {
+		e.csv = csv.NewWriter(e.w)
+	}
+	return e
+}
+
+// Encode decodes rec and writes it.
+func (e *Encoder) Encode(rec []byte) error {
+	v, err := e.l.Decode(rec)
+	if err != nil {
+		return err
+	}
+	if e.f == CSV {
+		return e.writeRow(v)
+	}
+	buf, err := appendObject(nil, e.l.Root, v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(buf, '\n'))
+	return err
+}
+
+// Flush writes buffered output; a CSV header is written even with no records.
+func (e *Encoder) Flush() error {
+	if e.f == CSV {
+		if e.columns == nil {
+			if err := e.writeHeader(); err != nil {
+				return err
+			}
+		}
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// appendObject writes the decoded group m as a JSON object, keys in the order of
+// f's children.
+func appendObject(buf []byte, f *Field, m map[string]any) ([]byte, error) {
+	buf = append(buf, '{')
+	first := true
+	for _, c := range f.Children {
+		if c.IsFiller() {
+			continue
+		}
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = strconv.AppendQuote(buf, c.Name)
+		buf = append(buf, ':')
+		var err error
+		if buf, err = appendValue(buf, c, m[c.Name], true); err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+func appendValue(buf []byte, f *Field, v any, table bool) ([]byte, error) {
+	if elems, ok := v.([]any); ok && table && f.Occurs > 0 {
+		buf = append(buf, '[')
+		for i, x := range elems {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			var err error
+			if buf, err = appendValue(buf, f, x, false); err != nil {
+				return nil, err
+			}
+		}
+		return append(buf, ']'), nil
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		return appendObject(buf, f, v)
+	case Decimal:
+		return append(buf, v.String()...), nil
+	case nil:
+		return append(buf, "null"...), nil
+	}
+	b, err := json.Marshal(v)
+	return append(buf, b...), err
+}
+
+// column is one CSV column: the path from the record to an elementary item.
+type column struct {
+	name string
+	path []step
+}
+
+type step struct {
+	name  string
+	index int // occurrence, from 0; -1 for an item that is not a table
+}
+
+func (e *Encoder) writeHeader() error {
+	e.columns = columns(nil, e.l.Root, nil, nil)
+	seen := map[string]int{}
+	for _, c := range e.columns {
+		seen[c.name]++
+	}
+	header := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		header[i] = c.name
+		if seen[c.name] > 1 {
+			// Qualify an ambiguous name with its groups, outermost first.
+			header[i] = qualified(c)
+		}
+	}
+	e.row = make([]string, len(e.columns))
+	return e.csv.Write(header)
+}
+
+func columns(out []column, f *Field, path []step, subs []int) []column {
+	for _, c := range f.Children {
+		if c.IsFiller() {
+			continue
+		}
+		n := max(c.Occurs, 1)
+		for i := range n {
+			index, s := -1, subs
+			if c.Occurs > 0 {
+				index, s = i, append(subs[:len(subs):len(subs)], i+1)
+			}
+			p := append(path[:len(path):len(path)], step{c.Name, index})
+			if c.Kind == Group {
+				out = columns(out, c, p, s)
+				continue
+			}
+			out = append(out, column{name: c.Name + subscripts(s), path: p})
+		}
+	}
+	return out
+}
+
+func subscripts(subs []int) string {
+	if len(subs) == 0 {
+		return ""
+	}
+	parts := make([]string, len(subs))
+	for i, n := range subs {
+		parts[i] = strconv.Itoa(n)
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+func qualified(c column) string {
+	names := make([]string, len(c.path))
+	for i, s := range c.path {
+		names[i] = s.name
+	}
+	_, subs, _ := strings.Cut(c.name, "(")
+	if subs != "" {
+		subs = "(" + subs
+	}
+	return strings.Join(names, ".") + subs
+}
+
+func (e *Encoder) writeRow(m map[string]any) error {
+	if e.columns == nil {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	for i, c := range e.columns {
+		e.row[i] = cell(lookup(m, c.path))
+	}
+	return e.csv.Write(e.row)
+}
+
+// lookup follows path through decoded groups and tables; it returns nil for an
+// occurrence an OCCURS DEPENDING ON count left out.
+func lookup(m map[string]any, path []step) any {
+	var v any = m
+	for _, s := range path {
+		g, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		v = g[s.name]
+		if s.index >= 0 {
+			elems, ok := v.([]any)
+			if !ok || s.index >= len(elems) {
+				return nil
+			}
+			v = elems[s.index]
+		}
+	}
+	return v
+}
+
+func cell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case Decimal:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,591 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package copybook decodes binary z/OS records with the layout a COBOL copybook
+// describes. Parse reads a copybook into a Layout; the Layout then decodes
+// records fetched in binary (fixed-length, or variable-length with the RDW
+// removed) into maps or structs, and an Encoder writes them as JSON lines or
+// CSV.
+//
+// Supported data descriptions are level numbers 01–49 and 77, PIC/PICTURE with
+// repetition factors, USAGE DISPLAY (text and zoned decimal, with SIGN LEADING,
+// TRAILING and SEPARATE), COMP/COMP-4/BINARY and COMP-5, COMP-3/PACKED-DECIMAL,
+// COMP-1 and COMP-2 (hexadecimal floating point), group USAGE, OCCURS (including
+// DEPENDING ON) and REDEFINES. Level 88 condition names, level 66 RENAMES and
+// VALUE clauses are skipped; SYNCHRONIZED is accepted but no slack bytes are
+// inserted. The copybook is read in fixed format: columns 1–6 are a sequence
+// area, column 7 an indicator ('*' or '/' comment, '-' continuation), and the
+// code ends at column 72.
+//
+//	l, err := copybook.Parse(f)
+//	for rec, err := range s.RetrieveDatasetRecords("'HLQ.CUSTOMER'") {
+//		v, err := l.Decode(rec)
+//		…
+//	}
+package copybook
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+// Kind is the category of data an elementary item holds.
+type Kind uint8
+
+const (
+	// Group is an item with subordinate items.
+	Group Kind = iota
+	// Alphanumeric is text (PIC X or A, or a numeric-edited picture), decoded
+	// with the Layout's code page.
+	Alphanumeric
+	// Zoned is a USAGE DISPLAY numeric item: one digit per byte, with the sign in
+	// the zone of the first or last byte or in a separate byte.
+	Zoned
+	// Packed is a COMP-3/PACKED-DECIMAL item: two digits per byte and a sign
+	// nibble.
+	Packed
+	// Binary is a COMP, COMP-4, BINARY or COMP-5 big-endian integer.
+	Binary
+	// Float is a COMP-1 (4-byte) or COMP-2 (8-byte) hexadecimal floating-point
+	// number.
+	Float
+)
+
+var kindNames = [...]string{"group", "alphanumeric", "zoned", "packed", "binary", "float"}
+
+// String returns the lower-case kind name.
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return "Kind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Field is one data description entry of a copybook.
+type Field struct {
+	// Level is the level number (01–49, or 77).
+	Level int
+	// Name is the data name; "FILLER" for an unnamed item.
+	Name string
+	// Kind is the item's data category.
+	Kind Kind
+	// Picture is the PICTURE string as written, without repetition expanded.
+	Picture string
+	// Digits is the number of digit positions of a numeric item.
+	Digits int
+	// Scale is the number of digits after the implied decimal point (V).
+	Scale int
+	// Signed reports whether the picture has an S.
+	Signed bool
+	// SignLeading reports SIGN LEADING; the default is trailing.
+	SignLeading bool
+	// SignSeparate reports SIGN SEPARATE: the sign is a byte of its own.
+	SignSeparate bool
+	// Size is the length in bytes of one occurrence, counting a table's maximum
+	// number of occurrences for items that contain one.
+	Size int
+	// Offset is the item's byte offset within the record when every table
+	// before it has its maximum number of occurrences.
+	Offset int
+	// Occurs is the number of occurrences of a table item (the maximum for
+	// OCCURS DEPENDING ON), or 0 for an item that is not a table.
+	Occurs int
+	// MinOccurs is the minimum of an OCCURS n TO m DEPENDING ON table.
+	MinOccurs int
+	// DependingOn names the item holding the number of occurrences.
+	DependingOn string
+	// Redefines names the item this one redefines.
+	Redefines string
+	// Children are the subordinate items of a group.
+	Children []*Field
+
+	usage usage
+}
+
+// IsFiller reports whether the item is unnamed (FILLER), and so is left out of
+// decoded records.
+func (f *Field) IsFiller() bool { return f.Name == "FILLER" }
+
+// Layout is a parsed copybook.
+type Layout struct {
+	// Root is the record: the single 01-level group when the copybook has one,
+	// otherwise a group holding the top-level items. Further 01-level records
+	// redefine the first.
+	Root *Field
+	// CodePage decodes alphanumeric items. Parse sets it to ebcdic.IBM037.
+	CodePage *ebcdic.CodePage
+}
+
+// Size returns the maximum record length the layout describes.
+func (l *Layout) Size() int { return l.Root.Size }
+
+// ErrSyntax is wrapped by the errors Parse returns for a copybook it cannot
+// read.
+var ErrSyntax = errors.New("copybook: syntax error")
+
+type usage uint8
+
+const (
+	usageNone usage = iota
+	usageDisplay
+	usageBinary
+	usageNative // COMP-5
+	usagePacked
+	usageFloat4
+	usageFloat8
+)
+
+var usageWords = map[string]usage{
+	"DISPLAY": usageDisplay,
+	"COMP":    usageBinary, "COMPUTATIONAL": usageBinary, "COMP-4": usageBinary, "COMPUTATIONAL-4": usageBinary, "BINARY": usageBinary,
+	"COMP-5": usageNative, "COMPUTATIONAL-5": usageNative,
+	"COMP-3": usagePacked, "COMPUTATIONAL-3": usagePacked, "PACKED-DECIMAL": usagePacked,
+	"COMP-1": usageFloat4, "COMPUTATIONAL-1": usageFloat4,
+	"COMP-2": usageFloat8, "COMPUTATIONAL-2": usageFloat8,
+}
+
+// ignoredWords are clauses accepted without effect on the layout.
+var ignoredWords = map[string]bool{
+	"SYNC": true, "SYNCHRONIZED": true, "LEFT": true, "RIGHT": true,
+	"JUST": true, "JUSTIFIED": true, "GLOBAL": true, "EXTERNAL": true,
+	"BLANK": true, "WHEN": true, "ZERO": true, "ZEROS": true, "ZEROES": true, "IS": true,
+}
+
+// Parse reads a copybook and computes its layout.
+func Parse(r io.Reader) (*Layout, error) {
+	stmts, err := statements(r)
+	if err != nil {
+		return nil, err
+	}
+	root := &Field{Name: "FILLER", Kind: Group}
+	stack := []*Field{root}
+	var tops []*Field
+	for _, st := range stmts {
+		f, skip, err := parseEntry(st)
+		if err != nil {
+			return nil, err
+		}
+		if skip {
+			continue
+		}
+		for len(stack) > 1 && rank(stack[len(stack)-1]) >= rank(f) {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1]
+		if parent != root && parent.Kind != Group {
+			return nil, fmt.Errorf("%w: line %d: %s is subordinate to elementary item %s", ErrSyntax, st.line, f.Name, parent.Name)
+		}
+		if f.usage == usageNone {
+			f.usage = parent.usage
+		}
+		parent.Children = append(parent.Children, f)
+		if parent == root {
+			tops = append(tops, f)
+		}
+		stack = append(stack, f)
+	}
+	if len(tops) == 0 {
+		return nil, fmt.Errorf("%w: no data description entries", ErrSyntax)
+	}
+	if len(tops) > 1 && tops[0].Level == 1 {
+		for _, f := range tops[1:] {
+			if f.Level == 1 && f.Redefines == "" {
+				f.Redefines = tops[0].Name
+			}
+		}
+	}
+	if err := resolve(root); err != nil {
+		return nil, err
+	}
+	if len(tops) == 1 && tops[0].Kind == Group {
+		root = tops[0]
+	}
+	if err := layout(root, 0); err != nil {
+		return nil, err
+	}
+	return &Layout{Root: root, CodePage: ebcdic.IBM037}, nil
+}
+
+// statement is one period-terminated data description entry, tokenized.
+type statement struct {
+	line   int
+	tokens []string
+}
+
+// statements reads the code area of every line and splits it into entries.
+func statements(r io.Reader) ([]statement, error) {
+	var out []statement
+	cur := statement{}
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimRight(sc.Text(), "\r")
+		if len(line) > 72 {
+			line = line[:72]
+		}
+		if len(line) < 7 {
+			continue
+		}
+		switch line[6] {
+		case '*', '/':
+			continue
+		case ' ', '-', 'D', 'd':
+		default:
+			return nil, fmt.Errorf("%w: line %d: unexpected indicator %q in column 7", ErrSyntax, n, line[6])
+		}
+		toks, err := tokenize(line[7:], n)
+		if err != nil {
+			return nil, err
+		}
+		for _, tok := range toks {
+			if len(cur.tokens) == 0 {
+				cur.line = n
+			}
+			end := strings.HasSuffix(tok, ".") && tok[0] != '\'' && tok[0] != '"'
+			if end {
+				tok = strings.TrimSuffix(tok, ".")
+			}
+			if tok != "" {
+				cur.tokens = append(cur.tokens, tok)
+			}
+			if end && len(cur.tokens) > 0 {
+				out = append(out, cur)
+				cur = statement{}
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(cur.tokens) > 0 {
+		return nil, fmt.Errorf("%w: line %d: entry not terminated by a period", ErrSyntax, cur.line)
+	}
+	return out, nil
+}
+
+// tokenize splits a code area on blanks, keeping quoted literals (and a period
+// right after one) together.
+func tokenize(s string, line int) ([]string, error) {
+	var toks []string
+	for {
+		s = strings.TrimLeft(s, " \t,;")
+		if s == "" {
+			return toks, nil
+		}
+		if q := s[0]; q == '\'' || q == '"' {
+			end := strings.IndexByte(s[1:], q)
+			if end < 0 {
+				return nil, fmt.Errorf("%w: line %d: unterminated literal", ErrSyntax, line)
+			}
+			lit := s[:end+2]
+			s = s[end+2:]
+			if strings.HasPrefix(s, ".") {
+				toks = append(toks, lit, ".")
+				s = s[1:]
+				continue
+			}
+			toks = append(toks, lit)
+			continue
+		}
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		toks = append(toks, s[:end])
+		s = s[end:]
+	}
+}
+
+// parseEntry builds a Field from one entry. skip reports an entry that does not
+// describe storage (levels 66 and 88).
+func parseEntry(st statement) (f *Field, skip bool, err error) {
+	bad := func(format string, a ...any) (*Field, bool, error) {
+		return nil, false, fmt.Errorf("%w: line %d: %s", ErrSyntax, st.line, fmt.Sprintf(format, a...))
+	}
+	toks := st.tokens
+	level, err := strconv.Atoi(toks[0])
+	if err != nil {
+		return bad("expected a level number, found %q", toks[0])
+	}
+	switch {
+	case level == 66 || level == 88:
+		return nil, true, nil
+	case level == 77, level >= 1 && level <= 49:
+	default:
+		return bad("level number %d out of range", level)
+	}
+	f = &Field{Level: level, Name: "FILLER", Kind: Group}
+	i := 1
+	if i < len(toks) && !isClauseWord(toks[i]) {
+		f.Name = strings.ToUpper(toks[i])
+		i++
+	}
+	next := func() (string, bool) {
+		if i >= len(toks) {
+			return "", false
+		}
+		i++
+		return toks[i-1], true
+	}
+	optional := func(words ...string) {
+		for i < len(toks) {
+			match := false
+			for _, w := range words {
+				if strings.EqualFold(toks[i], w) {
+					match = true
+				}
+			}
+			if !match {
+				return
+			}
+			i++
+		}
+	}
+	for i < len(toks) {
+		word := strings.ToUpper(toks[i])
+		i++
+		switch {
+		case word == "REDEFINES":
+			name, ok := next()
+			if !ok {
+				return bad("REDEFINES without a name")
+			}
+			f.Redefines = strings.ToUpper(name)
+		case word == "OCCURS":
+			n, ok := next()
+			count, err := strconv.Atoi(n)
+			if !ok || err != nil || count < 0 {
+				return bad("OCCURS needs a count, found %q", n)
+			}
+			f.Occurs = count
+			if i < len(toks) && strings.EqualFold(toks[i], "TO") {
+				i++
+				m, _ := next()
+				hi, err := strconv.Atoi(m)
+				if err != nil || hi < count {
+					return bad("bad OCCURS %d TO %q", count, m)
+				}
+				f.MinOccurs, f.Occurs = count, hi
+			}
+			optional("TIMES")
+			if i < len(toks) && strings.EqualFold(toks[i], "DEPENDING") {
+				i++
+				optional("ON")
+				name, ok := next()
+				if !ok {
+					return bad("DEPENDING ON without a name")
+				}
+				f.DependingOn = strings.ToUpper(name)
+			}
+			// ASCENDING/DESCENDING KEY and INDEXED BY names do not affect storage.
+			for i < len(toks) && !isClauseWord(toks[i]) {
+				i++
+			}
+		case word == "PIC" || word == "PICTURE":
+			optional("IS")
+			pic, ok := next()
+			if !ok {
+				return bad("PICTURE without a character string")
+			}
+			f.Picture = strings.ToUpper(pic)
+		case word == "USAGE":
+			optional("IS")
+			u, ok := next()
+			if !ok || usageWords[strings.ToUpper(u)] == usageNone {
+				return bad("unsupported USAGE %q", u)
+			}
+			f.usage = usageWords[strings.ToUpper(u)]
+		case usageWords[word] != usageNone:
+			f.usage = usageWords[word]
+		case word == "SIGN" || word == "LEADING" || word == "TRAILING":
+			if word == "SIGN" {
+				optional("IS")
+				word, _ = next()
+				word = strings.ToUpper(word)
+			}
+			switch word {
+			case "LEADING":
+				f.SignLeading = true
+			case "TRAILING":
+			default:
+				return bad("SIGN must be LEADING or TRAILING, found %q", word)
+			}
+			if i < len(toks) && strings.EqualFold(toks[i], "SEPARATE") {
+				i++
+				f.SignSeparate = true
+				optional("CHARACTER")
+			}
+		case word == "VALUE" || word == "VALUES":
+			// Initial values do not affect storage; skip to the next clause.
+			for i < len(toks) && !isClauseWord(toks[i]) {
+				i++
+			}
+		case ignoredWords[word]:
+		default:
+			return bad("unsupported clause %q", toks[i-1])
+		}
+	}
+	return f, false, nil
+}
+
+// rank is the level number for nesting purposes: a 77 item is a record of its
+// own, like an 01.
+func rank(f *Field) int {
+	if f.Level == 77 {
+		return 1
+	}
+	return f.Level
+}
+
+func isClauseWord(tok string) bool {
+	w := strings.ToUpper(tok)
+	switch w {
+	case "REDEFINES", "OCCURS", "PIC", "PICTURE", "USAGE", "SIGN", "LEADING", "TRAILING", "VALUE", "VALUES":
+		return true
+	}
+	return usageWords[w] != usageNone || ignoredWords[w] && w != "IS"
+}
+
+// resolve classifies every elementary item from its picture and usage and
+// computes item sizes.
+func resolve(f *Field) error {
+	if len(f.Children) > 0 {
+		if f.Picture != "" {
+			return fmt.Errorf("%w: group %s has a PICTURE", ErrSyntax, f.Name)
+		}
+		f.Kind = Group
+		size, redefined := 0, map[string]int{}
+		end := 0
+		for _, c := range f.Children {
+			if err := resolve(c); err != nil {
+				return err
+			}
+			n := c.Size * max(c.Occurs, 1)
+			if c.Redefines != "" {
+				start, ok := redefined[c.Redefines]
+				if !ok {
+					return fmt.Errorf("%w: %s redefines %s, which is not an earlier item at its level", ErrSyntax, c.Name, c.Redefines)
+				}
+				end = max(end, start+n)
+				continue
+			}
+			redefined[c.Name] = size
+			size += n
+			end = max(end, size)
+		}
+		f.Size = end
+		return nil
+	}
+	switch f.usage {
+	case usageFloat4, usageFloat8:
+		if f.Picture != "" {
+			return fmt.Errorf("%w: %s: COMP-1 and COMP-2 items take no PICTURE", ErrSyntax, f.Name)
+		}
+		f.Kind, f.Size, f.Signed = Float, 4, true
+		if f.usage == usageFloat8 {
+			f.Size = 8
+		}
+		return nil
+	}
+	if f.Picture == "" {
+		return fmt.Errorf("%w: elementary item %s has no PICTURE", ErrSyntax, f.Name)
+	}
+	pic, err := expandPicture(f.Picture)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrSyntax, f.Name, err)
+	}
+	if strings.Trim(pic, "9SV") == "" {
+		f.Signed = strings.HasPrefix(pic, "S")
+		digits := strings.Replace(pic, "S", "", 1)
+		if strings.ContainsAny(digits, "S") || strings.Count(digits, "V") > 1 {
+			return fmt.Errorf("%w: %s: malformed numeric PICTURE %s", ErrSyntax, f.Name, f.Picture)
+		}
+		intPart, frac, _ := strings.Cut(digits, "V")
+		f.Digits = len(intPart) + len(frac)
+		f.Scale = len(frac)
+		if f.Digits == 0 || f.Digits > 18 {
+			return fmt.Errorf("%w: %s: %d digits; 1 to 18 are supported", ErrSyntax, f.Name, f.Digits)
+		}
+		switch f.usage {
+		case usagePacked:
+			f.Kind, f.Size = Packed, f.Digits/2+1
+		case usageBinary, usageNative:
+			f.Kind = Binary
+			switch {
+			case f.Digits <= 4:
+				f.Size = 2
+			case f.Digits <= 9:
+				f.Size = 4
+			default:
+				f.Size = 8
+			}
+		default:
+			f.Kind, f.Size = Zoned, f.Digits
+			if f.SignSeparate {
+				f.Size++
+			}
+		}
+		return nil
+	}
+	if strings.ContainsAny(pic, "NG") {
+		return fmt.Errorf("%w: %s: DBCS and national PICTURE %s is not supported", ErrSyntax, f.Name, f.Picture)
+	}
+	if strings.Contains(pic, "P") {
+		return fmt.Errorf("%w: %s: scaling position P in %s is not supported", ErrSyntax, f.Name, f.Picture)
+	}
+	if f.usage != usageNone && f.usage != usageDisplay {
+		return fmt.Errorf("%w: %s: PICTURE %s needs USAGE DISPLAY", ErrSyntax, f.Name, f.Picture)
+	}
+	// Alphanumeric, alphabetic and numeric-edited items are all read as text.
+	f.Kind, f.Size = Alphanumeric, len(strings.ReplaceAll(pic, "V", ""))
+	return nil
+}
+
+// expandPicture expands repetition factors: "S9(3)V99" becomes "S999V99".
+func expandPicture(pic string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pic); i++ {
+		c := pic[i]
+		if c != '(' {
+			b.WriteByte(c)
+			continue
+		}
+		end := strings.IndexByte(pic[i:], ')')
+		if end < 0 || b.Len() == 0 {
+			return "", fmt.Errorf("malformed repetition in PICTURE %s", pic)
+		}
+		n, err := strconv.Atoi(pic[i+1 : i+end])
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("bad repetition factor in PICTURE %s", pic)
+		}
+		prev := b.String()[b.Len()-1]
+		b.WriteString(strings.Repeat(string(prev), n-1))
+		i += end
+	}
+	return b.String(), nil
+}
+
+// layout assigns static offsets, with every table at its maximum size.
+func layout(f *Field, offset int) error {
+	f.Offset = offset
+	pos, starts := offset, map[string]int{}
+	for _, c := range f.Children {
+		start := pos
+		if c.Redefines != "" {
+			start = starts[c.Redefines]
+		} else {
+			starts[c.Name] = pos
+			pos += c.Size * max(c.Occurs, 1)
+		}
+		if err := layout(c, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package copybook
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+var decimalType = reflect.TypeFor[Decimal]()
+
+// Unmarshal decodes rec and stores it in the struct v points to. A struct field
+// takes the item named by its `cobol:"NAME"` tag or, without one, the item whose
+// name matches the field name ignoring case, hyphens and underscores
+// (CustName matches CUST-NAME). A tag of "-" skips the field; items without a
+// field are ignored.
+//
+// Groups go into structs (or pointers to structs), tables into slices or
+// arrays, alphanumeric items into strings, and numeric items into Decimal,
+// integer, float or string fields. A numeric item with a fraction does not fit
+// an integer field. Items that decode to nil leave their field unchanged.
+func (l *Layout) Unmarshal(rec []byte, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("copybook: Unmarshal needs a non-nil pointer to a struct, got %T", v)
+	}
+	m, err := l.Decode(rec)
+	if err != nil {
+		return err
+	}
+	return assignStruct(rv.Elem(), m)
+}
+
+func assignStruct(dst reflect.Value, m map[string]any) error {
+	byName := make(map[string]string, len(m))
+	for k := range m {
+		byName[fold(k)] = k
+	}
+	t := dst.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := fold(sf.Name)
+		if tag := sf.Tag.Get("cobol"); tag == "-" {
+			continue
+		} else if tag != "" {
+			key = fold(tag)
+		}
+		name, ok := byName[key]
+		if !ok {
+			continue
+		}
+		if err := assign(dst.Field(i), m[name]); err != nil {
+			return fmt.Errorf("copybook: %s into %s.%s: %w", name, t.Name(), sf.Name, err)
+		}
+	}
+	return nil
+}
+
+// fold normalizes a COBOL or Go name for matching.
+func fold(s string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", "_", "").Replace(s))
+}
+
+func assign(dst reflect.Value, v any) error {
+	if v == nil {
+		return nil
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return assign(dst.Elem(), v)
+	}
+	if dst.Kind() == reflect.Interface && dst.NumMethod() == 0 {
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		if dst.Kind() != reflect.Struct {
+			return fmt.Errorf("group needs a struct, not %s", dst.Type())
+		}
+		return assignStruct(dst, v)
+	case []any:
+		switch dst.Kind() {
+		case reflect.Slice:
+			dst.Set(reflect.MakeSlice(dst.Type(), len(v), len(v)))
+		case reflect.Array:
+			if dst.Len() < len(v) {
+				return fmt.Errorf("%d occurrences do not fit %s", len(v), dst.Type())
+			}
+		default:
+			return fmt.Errorf("table needs a slice or array, not %s", dst.Type())
+		}
+		for i, x := range v {
+			if err := assign(dst.Index(i), x); err != nil {
+				return err
+			}
+		}
+		return nil
+	case string:
+		if dst.Kind() != reflect.String {
+			return fmt.Errorf("alphanumeric item needs a string, not %s", dst.Type())
+		}
+		dst.SetString(v)
+		return nil
+	case Decimal:
+		return assignDecimal(dst, v)
+	case float64:
+		switch dst.Kind() {
+		case reflect.Float32, reflect.Float64:
+			dst.SetFloat(v)
+			return nil
+		}
+		return fmt.Errorf("floating-point item needs a float, not %s", dst.Type())
+	}
+	return fmt.Errorf("unexpected value %T", v)
+}
+
+func assignDecimal(dst reflect.Value, d Decimal) error {
+	if dst.Type() == decimalType {
+		dst.Set(reflect.ValueOf(d))
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(d.String())
+		return nil
+	case reflect.Float32, reflect.Float64:
+		dst.SetFloat(d.Float64())
+		return nil
+	}
+	pow := int64(math.Pow10(d.Scale))
+	if d.Unscaled%pow != 0 {
+		return fmt.Errorf("%s has a fraction", d)
+	}
+	n := d.Unscaled / pow
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, dst.Type())
+		}
+		dst.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 || dst.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%d overflows %s", n, dst.Type())
+		}
+		dst.SetUint(uint64(n))
+	default:
+		return fmt.Errorf("numeric item needs a number, Decimal or string, not %s", dst.Type())
+	}
+	return nil
+}
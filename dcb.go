@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"unicode/utf8"
+)
+
+// ErrLineTooLong is returned by InferDCB under DCBOptions.Strict when a line
+// does not fit the target LRECL. It is wrapped in a *LongLinesError.
+var ErrLineTooLong = errors.New("zftp: line longer than the target LRECL")
+
+// HalfTrack3390 is the largest block size that fits twice on a 3390 track, the
+// optimal BLKSIZE for DASD datasets.
+const HalfTrack3390 = 27998
+
+// DCBOptions tune InferDCB.
+type DCBOptions struct {
+	// MaxLrecl is the largest record length the dataset may have; longer lines
+	// are reported. 0 means the z/OS maximum, 32760.
+	MaxLrecl int
+	// Strict makes lines longer than MaxLrecl an error instead of a warning.
+	Strict bool
+}
+
+// DCB is the dataset layout InferDCB derives from a local file.
+type DCB struct {
+	// Binary is true when the file is not text and should be transferred in
+	// binary; the record attributes are then left to the server.
+	Binary bool
+	// Recfm is FB when every line fits a card image, VB otherwise.
+	Recfm Recfm
+	// Lrecl is 80 for FB; for VB it fits the longest line plus the 4-byte
+	// record descriptor word. It is capped at MaxLrecl.
+	Lrecl int
+	// BlkSize is the half-track 3390 block size for Recfm and Lrecl.
+	BlkSize int
+	// Lines is the number of lines in the file.
+	Lines int
+	// Longest is the length of the longest line, in characters.
+	Longest int
+	// LongLines are the 1-based numbers of the lines longer than MaxLrecl allows.
+	LongLines []int
+}
+
+// Specs returns the DataSpecs that allocate a dataset with this layout; none for
+// a binary file.
+func (d DCB) Specs() []DataSpec {
+	if d.Binary {
+		return nil
+	}
+	return []DataSpec{d.Recfm, lrecl(d.Lrecl), blksz(d.BlkSize)}
+}
+
+// Mode returns the transfer type for the file: TypeImage for a binary file,
+// TypeAscii otherwise.
+func (d DCB) Mode() TransferType {
+	if d.Binary {
+		return TypeImage
+	}
+	return TypeAscii
+}
+
+// LongLinesError reports the lines that do not fit the target LRECL.
+type LongLinesError struct {
+	// Lrecl is the longest line allowed, in characters.
+	Lrecl int
+	// Lines are the 1-based numbers of the offending lines.
+	Lines []int
+}
+
+// Error lists how many lines are too long and the first of them.
+func (e *LongLinesError) Error() string {
+	return fmt.Sprintf("%s: %d line(s) longer than %d, first at line %d", ErrLineTooLong, len(e.Lines), e.Lrecl, e.Lines[0])
+}
+
+// Unwrap lets errors.Is match ErrLineTooLong.
+func (e *LongLinesError) Unwrap() error { return ErrLineTooLong }
+
+// InferDCB scans a local text file and chooses the RECFM, LRECL and BLKSIZE of
+// the dataset it should be stored in. Lines end at LF, with an optional CR, and
+// are measured in characters, as they are after conversion to a single-byte
+// EBCDIC code page. A file with NUL bytes, invalid UTF-8 or control characters
+// other than tab, form feed, CR and LF is reported as Binary. A file whose
+// lines all fit in 80 characters, an empty one included, gets FB 80, the card
+// image; a wider one gets VB with room for the longest line.
+//
+// Lines longer than the target LRECL are listed in LongLines; under
+// opts.Strict InferDCB also returns a *LongLinesError.
+func InferDCB(r io.Reader, opts DCBOptions) (DCB, error) {
+	maxLrecl := opts.MaxLrecl
+	if maxLrecl <= 0 || maxLrecl > 32760 {
+		maxLrecl = 32760
+	}
+	var d DCB
+	type lineLen struct{ line, n int }
+	var candidates []lineLen // lines that may not fit once the RECFM is known
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+			if !isText(line) {
+				return DCB{Binary: true, Lines: d.Lines}, nil
+			}
+			d.Lines++
+			n := utf8.RuneCount(line)
+			d.Longest = max(d.Longest, n)
+			if n+4 > maxLrecl {
+				candidates = append(candidates, lineLen{d.Lines, n})
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return DCB{}, err
+		}
+	}
+
+	overhead := 0
+	if d.Longest <= 80 {
+		d.Recfm, d.Lrecl = RecfmFB, 80
+	} else {
+		// A VB record starts with a 4-byte descriptor, and a VB block holds
+		// another 4 besides it.
+		d.Recfm, d.Lrecl, maxLrecl, overhead = RecfmVB, d.Longest+4, min(maxLrecl, 32756), 4
+	}
+	if d.Lrecl > maxLrecl {
+		for _, c := range candidates {
+			if c.n+overhead > maxLrecl {
+				d.LongLines = append(d.LongLines, c.line)
+			}
+		}
+		d.Lrecl = maxLrecl
+	}
+	d.BlkSize = blockSize3390(d.Recfm, d.Lrecl)
+	if opts.Strict && len(d.LongLines) > 0 {
+		return d, &LongLinesError{Lrecl: maxLrecl - overhead, Lines: d.LongLines}
+	}
+	return d, nil
+}
+
+// blockSize3390 returns the half-track block size for the record format: the
+// most whole records that fit for FB, the half track for VB, and one record per
+// block when a record is longer than that.
+func blockSize3390(recfm Recfm, lrecl int) int {
+	if recfm == RecfmFB {
+		return max(HalfTrack3390/lrecl, 1) * lrecl
+	}
+	return max(HalfTrack3390, lrecl+4)
+}
+
+// PutAutoDCB uploads a local file to a new dataset laid out by InferDCB: text
+// in ASCII with the inferred RECFM, LRECL and BLKSIZE, anything else in binary.
+// a are applied after the inferred attributes, so they can override them. Lines
+// that do not fit are logged as a warning, or fail the upload before anything
+// is sent under opts.Strict. The inferred DCB is returned in both cases.
+func (s *FTPSession) PutAutoDCB(srcLocal, destRemote string, opts DCBOptions, a ...DataSpec) (DCB, error) {
+	file, err := os.Open(srcLocal)
+	if err != nil {
+		return DCB{}, fmt.Errorf("failed to open source file: %w", err)
+	}
+	d, err := InferDCB(file, opts)
+	if cerr := file.Close(); cerr != nil {
+		s.log.Errorf("failed to close file: %s", cerr)
+	}
+	if err != nil {
+		return d, err
+	}
+	if len(d.LongLines) > 0 {
+		s.log.Warningf("%s: %d line(s) longer than LRECL %d will be wrapped or truncated, first at line %d",
+			srcLocal, len(d.LongLines), d.Lrecl, d.LongLines[0])
+	}
+	s.log.Debugf("inferred DCB for %s: binary=%t RECFM=%s LRECL=%d BLKSIZE=%d", srcLocal, d.Binary, d.Recfm, d.Lrecl, d.BlkSize)
+	return d, s.Put(srcLocal, destRemote, d.Mode(), append(d.Specs(), a...)...)
+}
+
+// isText reports whether line has no NUL bytes, invalid UTF-8 or control
+// characters other than tab and form feed.
+func isText(line []byte) bool {
+	if !utf8.Valid(line) {
+		return false
+	}
+	for _, c := range line {
+		if c < 0x20 && c != '\t' && c != '\f' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+)
+
+func TestInferDCB(t *testing.T) {
+	long := strings.Repeat("X", 100)
+	for _, c := range []struct {
+		name, in string
+		opts     zftp.DCBOptions
+		want     zftp.DCB
+	}{
+		{"card image", "AAAA\r\nBBBB\r\n", zftp.DCBOptions{},
+			zftp.DCB{Recfm: zftp.RecfmFB, Lrecl: 80, BlkSize: 27920, Lines: 2, Longest: 4}},
+		{"ragged card image", "A\nBBB\n" + strings.Repeat("C", 80), zftp.DCBOptions{},
+			zftp.DCB{Recfm: zftp.RecfmFB, Lrecl: 80, BlkSize: 27920, Lines: 3, Longest: 80}},
+		{"variable", "A\n" + strings.Repeat("B", 81) + "\nCC", zftp.DCBOptions{},
+			zftp.DCB{Recfm: zftp.RecfmVB, Lrecl: 85, BlkSize: 27998, Lines: 3, Longest: 81}},
+		{"runes", "ÄÖÜ\nABC\n", zftp.DCBOptions{},
+			zftp.DCB{Recfm: zftp.RecfmFB, Lrecl: 80, BlkSize: 27920, Lines: 2, Longest: 3}},
+		{"empty", "", zftp.DCBOptions{},
+			zftp.DCB{Recfm: zftp.RecfmFB, Lrecl: 80, BlkSize: 27920}},
+		{"long lines", "short\n" + long + "\nok\n" + long + "\n", zftp.DCBOptions{MaxLrecl: 84},
+			zftp.DCB{Recfm: zftp.RecfmVB, Lrecl: 84, BlkSize: 27998, Lines: 4, Longest: 100, LongLines: []int{2, 4}}},
+		{"binary", "text\n\x00\x01\x02", zftp.DCBOptions{},
+			zftp.DCB{Binary: true, Lines: 1}},
+		{"invalid utf-8", "\xff\xfe", zftp.DCBOptions{},
+			zftp.DCB{Binary: true}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := zftp.InferDCB(strings.NewReader(c.in), c.opts)
+			if err != nil {
+				t.Fatalf("InferDCB: %v", err)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("InferDCB = %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestInferDCB_Strict(t *testing.T) {
+	in := strings.Repeat("Y", 81) + "\n" + strings.Repeat("Y", 81) + "\n"
+	_, err := zftp.InferDCB(strings.NewReader(in), zftp.DCBOptions{MaxLrecl: 80, Strict: true})
+	var long *zftp.LongLinesError
+	if !errors.As(err, &long) || !errors.Is(err, zftp.ErrLineTooLong) {
+		t.Fatalf("err = %v, want *LongLinesError", err)
+	}
+	if !reflect.DeepEqual(long.Lines, []int{1, 2}) || long.Lrecl != 76 {
+		t.Errorf("LongLinesError = %+v", long)
+	}
+}
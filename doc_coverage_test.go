@@ -16,14 +16,14 @@ import (
 // TestExportedIdentifiersAreDocumented enforces that every exported identifier in
 // the module's public packages carries a doc comment, so `go doc` is complete for
 // the public surface. It scans the root package plus the exported subpackages
-// (hfs, eol) directly (skipping test files and generated files), and treats a
+// listed in dirs below, skipping test files and generated files, and treats a
 // const/var/field as documented if it has a doc comment, a trailing line comment,
 // or belongs to a documented declaration block — matching what godoc renders.
 // Findings are reported as "pkg/ident" (the root package uses "." as its label).
 func TestExportedIdentifiersAreDocumented(t *testing.T) {
 	// Directories of the module's exported packages, relative to the repo root.
 	// The doc gate must hold for every package a consumer can import.
-	dirs := []string{".", "hfs", "eol"}
+	dirs := []string{".", "hfs", "eol", "ebcdic", "records", "copybook", "carriage", "zftptest"}
 
 	var undocumented []string
 	for _, dir := range dirs {
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+	"gopkg.in/ro-ag/zftp.v2/internal/dsn"
+)
+
+// ErrInvalidDSN is returned for a dataset name that breaks the z/OS naming
+// rules.
+var ErrInvalidDSN = dsn.ErrInvalid
+
+// DSN is a parsed z/OS dataset name, with an optional PDS member or GDG
+// relative generation: 'HLQ.SRC.COBOL(PAYROLL)', HLQ.BACKUP(+1). Its fields are
+// Qualifiers, Member, Generation, GDG and Quoted; String renders it as the
+// server takes it and Resolve qualifies an unquoted name. GetDSN, PutDSN,
+// DeleteDSN, RenameDSN and ListPdsDSN take one in place of a string.
+type DSN = dsn.DSN
+
+// ParseDSN parses and validates a dataset name as the FTP server takes it:
+// quoted when fully qualified, unquoted when relative to the working directory.
+// Names are case-insensitive and returned uppercased.
+//
+// A qualifier has 1 to 8 characters: a letter or national character (@, # or
+// $), then letters, digits, national characters or hyphens. A name has at most
+// 44 characters, a GDG base at most 35. A member follows the qualifier rules
+// without hyphens; a relative generation is 0 or signed, up to 255.
+func ParseDSN(name string) (DSN, error) {
+	return dsn.Parse(name)
+}
+
+// checkDSN validates name when it is a quoted dataset name without wildcards,
+// so that a malformed one fails before any command is sent. z/OS UNIX paths and
+// unquoted names, which the server may take as either, are left to the server;
+// the DSN variants, such as GetDSN, validate those too.
+func checkDSN(name string) error {
+	if !strings.HasPrefix(strings.TrimSpace(name), "'") || strings.ContainsAny(name, "*%") {
+		return nil
+	}
+	_, err := ParseDSN(name)
+	return err
+}
+
+// GetDSN is Get for a parsed DSN, validated again so that one built field by
+// field fails with ErrInvalidDSN before the local file is created.
+func (s *FTPSession) GetDSN(remote DSN, localFile string, mode TransferType) error {
+	name, err := remote.RemoteName()
+	if err != nil {
+		return err
+	}
+	return s.Get(name, localFile, mode)
+}
+
+// PutDSN is Put for a parsed DSN, validated again before anything is sent.
+func (s *FTPSession) PutDSN(srcLocal string, destRemote DSN, mode TransferType, a ...DataSpec) error {
+	name, err := destRemote.RemoteName()
+	if err != nil {
+		return err
+	}
+	return s.Put(srcLocal, name, mode, a...)
+}
+
+// DeleteDSN is Delete for a parsed DSN, validated again before DELE is sent.
+func (s *FTPSession) DeleteDSN(name DSN) error {
+	n, err := name.RemoteName()
+	if err != nil {
+		return err
+	}
+	return s.Delete(n)
+}
+
+// RenameDSN is Rename for parsed DSNs, validated again before RNFR is sent.
+func (s *FTPSession) RenameDSN(from, to DSN) error {
+	src, err := from.RemoteName()
+	if err != nil {
+		return err
+	}
+	dst, err := to.RemoteName()
+	if err != nil {
+		return err
+	}
+	return s.Rename(src, dst)
+}
+
+// ListPdsDSN is ListPds for a parsed DSN, validated again before LIST is sent.
+func (s *FTPSession) ListPdsDSN(pds DSN) ([]hfs.InfoPdsMember, error) {
+	name, err := pds.RemoteName()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListPds(name)
+}
+
+// PWD returns the working directory: a dataset name prefix such as
+// "'IBMUSER.'", or a z/OS UNIX path.
+func (s *FTPSession) PWD() (string, error) {
+	msg, err := s.send(CodeDirCreated, "PWD")
+	if err != nil {
+		return "", err
+	}
+	if first, last := strings.IndexByte(msg, '"'), strings.LastIndexByte(msg, '"'); first >= 0 && last > first {
+		return msg[first+1 : last], nil
+	}
+	return "", fmt.Errorf("zftp: unexpected PWD reply %q", msg)
+}
+
+// ResolveDSN parses name and resolves it when unquoted, as the server would:
+// against the working directory when it is a dataset name prefix, or the user
+// ID when the working directory is a z/OS UNIX directory.
+func (s *FTPSession) ResolveDSN(name string) (DSN, error) {
+	d, err := ParseDSN(name)
+	if err != nil || d.Quoted {
+		return d, err
+	}
+	prefix, err := s.PWD()
+	if err != nil {
+		return DSN{}, err
+	}
+	if strings.HasPrefix(prefix, "/") {
+		prefix = s.User()
+	}
+	return d.Resolve(prefix)
+}
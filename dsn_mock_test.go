@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+)
+
+// TestResolveDSN checks unquoted names resolve against the working directory
+// prefix, or the user ID while in a z/OS UNIX directory.
+func TestResolveDSN(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	srv.AddFile("/u/me/a.txt", []byte("x"))
+
+	if _, err := s.CWD("'PROJ.WORK'"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := s.ResolveDSN("src(pay)"); err != nil || d.String() != "'PROJ.WORK.SRC(PAY)'" {
+		t.Errorf("ResolveDSN = %s, %v", d, err)
+	}
+	if _, err := s.CWD("/u/me"); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := s.ResolveDSN("data"); err != nil || d.String() != "'"+s.User()+".DATA'" {
+		t.Errorf("ResolveDSN = %s, %v", d, err)
+	}
+	if d, err := s.ResolveDSN("'SYS1.MACLIB'"); err != nil || d.Name() != "SYS1.MACLIB" {
+		t.Errorf("ResolveDSN = %s, %v", d, err)
+	}
+}
+
+// TestInvalidDSN checks a malformed name, quoted or relative, fails before any
+// command.
+func TestInvalidDSN(t *testing.T) {
+	s, srv := dialMock(t)
+	local := filepath.Join(t.TempDir(), "out")
+	before := len(srv.Commands())
+	bad := "'HLQ.TOOLONGQUAL'"
+	for name, err := range map[string]error{
+		"Get":    s.Get(bad, local, zftp.TypeAscii),
+		"Put":    s.Put(local, bad, zftp.TypeAscii),
+		"Delete": s.Delete(bad),
+		"Rename": s.Rename("'HLQ.OK'", bad),
+	} {
+		if !errors.Is(err, zftp.ErrInvalidDSN) {
+			t.Errorf("%s: got %v, want ErrInvalidDSN", name, err)
+		}
+	}
+	if _, err := s.ListPds(bad); !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("ListPds: got %v, want ErrInvalidDSN", err)
+	}
+
+	relative := zftp.DSN{Qualifiers: []string{"SRC", "9COBOL"}}
+	ok := zftp.DSN{Qualifiers: []string{"HLQ", "OK"}, Quoted: true}
+	for name, err := range map[string]error{
+		"GetDSN":    s.GetDSN(relative, local, zftp.TypeAscii),
+		"PutDSN":    s.PutDSN(local, relative, zftp.TypeAscii),
+		"DeleteDSN": s.DeleteDSN(relative),
+		"RenameDSN": s.RenameDSN(ok, relative),
+	} {
+		if !errors.Is(err, zftp.ErrInvalidDSN) {
+			t.Errorf("%s: got %v, want ErrInvalidDSN", name, err)
+		}
+	}
+	if _, err := s.ListPdsDSN(relative); !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("ListPdsDSN: got %v, want ErrInvalidDSN", err)
+	}
+	if _, err := zftp.ParseDSN("SRC.9COBOL"); !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("ParseDSN(SRC.9COBOL): got %v, want ErrInvalidDSN", err)
+	}
+	if cmds := srv.Commands()[before:]; len(cmds) != 0 {
+		t.Errorf("commands = %v", cmds)
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+)
+
+func TestParseDSN(t *testing.T) {
+	for _, c := range []struct {
+		in   string
+		want zftp.DSN
+		str  string
+	}{
+		{"'hlq.src.cobol'", zftp.DSN{Qualifiers: []string{"HLQ", "SRC", "COBOL"}, Quoted: true}, "'HLQ.SRC.COBOL'"},
+		{" 'HLQ.SRC(Payroll)' ", zftp.DSN{Qualifiers: []string{"HLQ", "SRC"}, Member: "PAYROLL", Quoted: true}, "'HLQ.SRC(PAYROLL)'"},
+		{"DATA", zftp.DSN{Qualifiers: []string{"DATA"}}, "DATA"},
+		{"@SYS.#T$-1.A1", zftp.DSN{Qualifiers: []string{"@SYS", "#T$-1", "A1"}}, "@SYS.#T$-1.A1"},
+		{"'HLQ.BACKUP(+1)'", zftp.DSN{Qualifiers: []string{"HLQ", "BACKUP"}, Generation: 1, GDG: true, Quoted: true}, "'HLQ.BACKUP(+1)'"},
+		{"HLQ.BACKUP(0)", zftp.DSN{Qualifiers: []string{"HLQ", "BACKUP"}, GDG: true}, "HLQ.BACKUP(0)"},
+		{"HLQ.BACKUP(-12)", zftp.DSN{Qualifiers: []string{"HLQ", "BACKUP"}, Generation: -12, GDG: true}, "HLQ.BACKUP(-12)"},
+	} {
+		got, err := zftp.ParseDSN(c.in)
+		if err != nil {
+			t.Errorf("ParseDSN(%q): %v", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("ParseDSN(%q) = %+v, want %+v", c.in, got, c.want)
+		}
+		if got.String() != c.str {
+			t.Errorf("ParseDSN(%q).String() = %q, want %q", c.in, got.String(), c.str)
+		}
+	}
+}
+
+func TestParseDSN_Invalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"'HLQ.DATA",
+		"HLQ..DATA",
+		"HLQ.TOOLONGQUAL",
+		"1HLQ.DATA",
+		"HLQ.-DATA",
+		"HLQ.DA_TA",
+		"HLQ.DATA(MEMBER",
+		"HLQ.DATA(MEM-1)",
+		"HLQ.DATA(TOOLONGMB)",
+		"HLQ.DATA()",
+		"HLQ.GDG(1)",
+		"HLQ.GDG(+256)",
+		"AAAAAAAA.BBBBBBBB.CCCCCCCC.DDDDDDDD.EEEEEEEE.F",
+		"AAAAAAAA.BBBBBBBB.CCCCCCCC.DDDDDDDD.E(+1)",
+	} {
+		if d, err := zftp.ParseDSN(in); !errors.Is(err, zftp.ErrInvalidDSN) {
+			t.Errorf("ParseDSN(%q) = %+v, %v; want ErrInvalidDSN", in, d, err)
+		}
+	}
+}
+
+func TestDSN_Resolve(t *testing.T) {
+	d, err := zftp.ParseDSN("src.cobol(pay)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for prefix, want := range map[string]string{
+		"ibmuser":         "'IBMUSER.SRC.COBOL(PAY)'",
+		"'IBMUSER.PROJ.'": "'IBMUSER.PROJ.SRC.COBOL(PAY)'",
+		"IBMUSER.PROJ.":   "'IBMUSER.PROJ.SRC.COBOL(PAY)'",
+	} {
+		r, err := d.Resolve(prefix)
+		if err != nil || r.String() != want {
+			t.Errorf("Resolve(%q) = %s, %v; want %s", prefix, r, err, want)
+		}
+	}
+	if r, err := zftp.ParseDSN("'OTHER.DATA'"); err != nil {
+		t.Fatal(err)
+	} else if q, _ := r.Resolve("IBMUSER"); q.String() != "'OTHER.DATA'" {
+		t.Errorf("quoted name resolved to %s", q)
+	}
+	for _, prefix := range []string{"", "AAAAAAAA.BBBBBBBB.CCCCCCCC.DDDDDDDD"} {
+		if _, err := d.Resolve(prefix); !errors.Is(err, zftp.ErrInvalidDSN) {
+			t.Errorf("Resolve(%q): got %v, want ErrInvalidDSN", prefix, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ebcdic converts between EBCDIC single-byte code pages and UTF-8 on
+// the client, so a binary transfer can be translated locally instead of relying
+// on the server's translate table.
+//
+// The supported code pages are the common Latin-1 EBCDIC sets and their euro
+// updates: IBM-037 (US/Canada) and IBM-1140, IBM-1047 (Latin-1/Open Systems,
+// the z/OS UNIX default), IBM-273 (Germany/Austria) and IBM-1141, IBM-285 (UK)
+// and IBM-1146, and IBM-500 (International) and IBM-1148.
+//
+// Every EBCDIC byte decodes to one rune. Encoding substitutes the EBCDIC SUB
+// character (0x3F) for a rune the code page cannot represent.
+//
+//	r := ebcdic.NewReader(resp, ebcdic.IBM1047, ebcdic.NLToLF)
+package ebcdic
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sub is the EBCDIC substitute character written for a rune that has no
+// encoding in the code page.
+const Sub = 0x3F
+
+// NL is the EBCDIC new line control, which z/OS UNIX text uses as its line end.
+const NL = 0x15
+
+// CodePage is an EBCDIC single-byte coded character set.
+type CodePage struct {
+	name   string
+	ccsid  int
+	decode *[256]rune
+	encode map[rune]byte
+}
+
+func newCodePage(ccsid int, decode *[256]rune) *CodePage {
+	cp := &CodePage{name: fmt.Sprintf("IBM-%03d", ccsid), ccsid: ccsid, decode: decode, encode: make(map[rune]byte, 256)}
+	for b, r := range decode {
+		cp.encode[r] = byte(b)
+	}
+	return cp
+}
+
+// The supported code pages.
+var (
+	IBM037  = newCodePage(37, &ibm037)    // US, Canada, Netherlands, Portugal, Brazil
+	IBM273  = newCodePage(273, &ibm273)   // Germany, Austria
+	IBM285  = newCodePage(285, &ibm285)   // United Kingdom
+	IBM500  = newCodePage(500, &ibm500)   // International Latin-1
+	IBM1047 = newCodePage(1047, &ibm1047) // Latin-1/Open Systems, the z/OS UNIX default
+	IBM1140 = newCodePage(1140, &ibm1140) // IBM-037 with the euro sign at 0x9F
+	IBM1141 = newCodePage(1141, &ibm1141) // IBM-273 with the euro sign at 0x9F
+	IBM1146 = newCodePage(1146, &ibm1146) // IBM-285 with the euro sign at 0x9F
+	IBM1148 = newCodePage(1148, &ibm1148) // IBM-500 with the euro sign at 0x9F
+)
+
+var codePages = []*CodePage{IBM037, IBM273, IBM285, IBM500, IBM1047, IBM1140, IBM1141, IBM1146, IBM1148}
+
+// ErrUnknownCodePage is returned by Lookup for a name it does not recognize.
+var ErrUnknownCodePage = errors.New("ebcdic: unknown code page")
+
+// Lookup returns the code page for a name such as "IBM-1047", "IBM1047",
+// "CP1047" or "1047", case-insensitively.
+func Lookup(name string) (*CodePage, error) {
+	n := strings.ToUpper(strings.TrimSpace(name))
+	for _, prefix := range []string{"IBM-", "IBM", "CP"} {
+		if rest, ok := strings.CutPrefix(n, prefix); ok {
+			n = rest
+			break
+		}
+	}
+	if ccsid, err := strconv.Atoi(n); err == nil {
+		for _, cp := range codePages {
+			if cp.ccsid == ccsid {
+				return cp, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownCodePage, name)
+}
+
+// Name returns the code page name in the z/OS form, e.g. "IBM-1047".
+func (c *CodePage) Name() string { return c.name }
+
+// String returns Name.
+func (c *CodePage) String() string { return c.name }
+
+// CCSID returns the coded character set identifier, e.g. 1047.
+func (c *CodePage) CCSID() int { return c.ccsid }
+
+// Newline chooses how the EBCDIC NL control (0x15) converts.
+type Newline uint8
+
+const (
+	// KeepNL follows the IBM tables: NL is U+0085 (NEL) and LF (0x25) is '\n'.
+	KeepNL Newline = iota
+	// NLToLF treats NL as the line end, as z/OS UNIX text does: NL decodes to
+	// '\n' (so does LF), and '\n' encodes to NL.
+	NLToLF
+)
+
+// Decode converts EBCDIC bytes to a UTF-8 string.
+func (c *CodePage) Decode(b []byte, nl Newline) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, x := range b {
+		sb.WriteRune(c.rune(x, nl))
+	}
+	return sb.String()
+}
+
+// Encode converts a UTF-8 string to EBCDIC bytes, substituting Sub for runes the
+// code page lacks (including invalid UTF-8).
+func (c *CodePage) Encode(s string, nl Newline) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		out = append(out, c.byte(r, nl))
+	}
+	return out
+}
+
+func (c *CodePage) rune(b byte, nl Newline) rune {
+	if b == NL && nl == NLToLF {
+		return '\n'
+	}
+	return c.decode[b]
+}
+
+func (c *CodePage) byte(r rune, nl Newline) byte {
+	if r == '\n' && nl == NLToLF {
+		return NL
+	}
+	if b, ok := c.encode[r]; ok {
+		return b
+	}
+	return Sub
+}
+
+// Reader decodes the EBCDIC read from an underlying reader to UTF-8.
+type Reader struct {
+	r   io.Reader
+	cp  *CodePage
+	nl  Newline
+	in  []byte
+	out []byte // decoded bytes, out[off:] not yet returned
+	off int
+}
+
+// NewReader returns a Reader that decodes the EBCDIC bytes read from r to UTF-8.
+func NewReader(r io.Reader, cp *CodePage, nl Newline) *Reader {
+	return &Reader{r: r, cp: cp, nl: nl, in: make([]byte, 4096)}
+}
+
+// Read reads decoded UTF-8 into p.
+func (d *Reader) Read(p []byte) (int, error) {
+	for d.off == len(d.out) {
+		n, err := d.r.Read(d.in)
+		d.out, d.off = d.out[:0], 0
+		for _, x := range d.in[:n] {
+			d.out = utf8.AppendRune(d.out, d.cp.rune(x, d.nl))
+		}
+		if n == 0 && err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.out[d.off:])
+	d.off += n
+	return n, nil
+}
+
+// Writer encodes UTF-8 written to it as EBCDIC. A rune split across two Writes
+// is held until its remaining bytes arrive.
+type Writer struct {
+	w       *bufio.Writer
+	cp      *CodePage
+	nl      Newline
+	partial []byte
+}
+
+// NewWriter returns a Writer that encodes UTF-8 to EBCDIC and writes it to w.
+// Output is buffered; call Close (or Flush) when done.
+func NewWriter(w io.Writer, cp *CodePage, nl Newline) *Writer {
+	return &Writer{w: bufio.NewWriter(w), cp: cp, nl: nl}
+}
+
+// Write encodes p. It reports len(p) written unless the underlying writer fails.
+func (e *Writer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(e.partial) > 0 {
+		p = append(e.partial, p...)
+		e.partial = nil
+	}
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size <= 1 && !utf8.FullRune(p) {
+			e.partial = append([]byte(nil), p...)
+			break
+		}
+		if err := e.w.WriteByte(e.cp.byte(r, e.nl)); err != nil {
+			return 0, err
+		}
+		p = p[size:]
+	}
+	return n, nil
+}
+
+// Flush writes any buffered EBCDIC to the underlying writer.
+func (e *Writer) Flush() error { return e.w.Flush() }
+
+// Close encodes an incomplete trailing rune as Sub and flushes. It does not
+// close the underlying writer.
+func (e *Writer) Close() error {
+	if len(e.partial) > 0 {
+		e.partial = nil
+		if err := e.w.WriteByte(Sub); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// decodingWriter decodes the EBCDIC written to it and writes UTF-8 onward.
+type decodingWriter struct {
+	w   io.Writer
+	cp  *CodePage
+	nl  Newline
+	buf []byte
+}
+
+// NewDecodingWriter returns a writer that accepts EBCDIC, decodes it to UTF-8
+// and writes that to w. It is the download-side counterpart of NewReader, for
+// code that pushes data (such as a transfer writing to a destination).
+func NewDecodingWriter(w io.Writer, cp *CodePage, nl Newline) io.Writer {
+	return &decodingWriter{w: w, cp: cp, nl: nl}
+}
+
+func (d *decodingWriter) Write(p []byte) (int, error) {
+	d.buf = d.buf[:0]
+	for _, x := range p {
+		d.buf = utf8.AppendRune(d.buf, d.cp.rune(x, d.nl))
+	}
+	if _, err := d.w.Write(d.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// encodingReader reads UTF-8 from an underlying reader and yields EBCDIC.
+type encodingReader struct {
+	r    io.Reader
+	cp   *CodePage
+	nl   Newline
+	in   []byte
+	tail int // bytes of an incomplete rune carried at the start of in
+	out  []byte
+	off  int
+}
+
+// NewEncodingReader returns a reader that reads UTF-8 from r and yields it
+// encoded in cp. It is the upload-side counterpart of NewWriter, for code that
+// pulls data (such as a transfer reading from a source).
+func NewEncodingReader(r io.Reader, cp *CodePage, nl Newline) io.Reader {
+	return &encodingReader{r: r, cp: cp, nl: nl, in: make([]byte, 4096)}
+}
+
+func (e *encodingReader) Read(p []byte) (int, error) {
+	for e.off == len(e.out) {
+		n, err := e.r.Read(e.in[e.tail:])
+		data := e.in[:e.tail+n]
+		e.out, e.off = e.out[:0], 0
+		for len(data) > 0 {
+			if !utf8.FullRune(data) && err == nil {
+				break // finish the rune on the next read
+			}
+			r, size := utf8.DecodeRune(data)
+			e.out = append(e.out, e.cp.byte(r, e.nl))
+			data = data[size:]
+		}
+		e.tail = copy(e.in, data)
+		if len(e.out) == 0 && err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out[e.off:])
+	e.off += n
+	return n, nil
+}
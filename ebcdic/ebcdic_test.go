@@ -0,0 +1,137 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package ebcdic_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+var all = []*ebcdic.CodePage{
+	ebcdic.IBM037, ebcdic.IBM273, ebcdic.IBM285, ebcdic.IBM500, ebcdic.IBM1047,
+	ebcdic.IBM1140, ebcdic.IBM1141, ebcdic.IBM1146, ebcdic.IBM1148,
+}
+
+// TestRoundTrip decodes every byte of every code page and encodes it back.
+func TestRoundTrip(t *testing.T) {
+	in := make([]byte, 256)
+	for i := range in {
+		in[i] = byte(i)
+	}
+	for _, cp := range all {
+		if got := cp.Encode(cp.Decode(in, ebcdic.KeepNL), ebcdic.KeepNL); !bytes.Equal(got, in) {
+			t.Errorf("%s: round trip differs", cp)
+		}
+	}
+}
+
+// TestNationalCharacters checks the code points that tell the code pages apart.
+func TestNationalCharacters(t *testing.T) {
+	for _, tc := range []struct {
+		cp   *ebcdic.CodePage
+		b    byte
+		want string
+	}{
+		{ebcdic.IBM037, 0xBA, "["},
+		{ebcdic.IBM037, 0x5F, "¬"},
+		{ebcdic.IBM1047, 0xAD, "["},
+		{ebcdic.IBM1047, 0xBD, "]"},
+		{ebcdic.IBM1047, 0x5F, "^"},
+		{ebcdic.IBM273, 0x4A, "Ä"},
+		{ebcdic.IBM273, 0x7C, "§"},
+		{ebcdic.IBM285, 0x5B, "£"},
+		{ebcdic.IBM285, 0x4A, "$"},
+		{ebcdic.IBM500, 0x4A, "["},
+		{ebcdic.IBM500, 0x9F, "¤"},
+		{ebcdic.IBM1140, 0x9F, "€"},
+		{ebcdic.IBM1141, 0x9F, "€"},
+		{ebcdic.IBM1146, 0x9F, "€"},
+		{ebcdic.IBM1148, 0x9F, "€"},
+		{ebcdic.IBM037, 0xC1, "A"},
+		{ebcdic.IBM037, 0xF0, "0"},
+	} {
+		if got := tc.cp.Decode([]byte{tc.b}, ebcdic.KeepNL); got != tc.want {
+			t.Errorf("%s %#02x = %q, want %q", tc.cp, tc.b, got, tc.want)
+		}
+	}
+	if got := ebcdic.IBM037.Encode("€", ebcdic.KeepNL); !bytes.Equal(got, []byte{ebcdic.Sub}) {
+		t.Errorf("IBM-037 euro = % X, want SUB", got)
+	}
+}
+
+// TestNewline maps NL to and from '\n' only under NLToLF.
+func TestNewline(t *testing.T) {
+	if got := ebcdic.IBM1047.Decode([]byte{0xC1, 0x15, 0xC2, 0x25}, ebcdic.NLToLF); got != "A\nB\n" {
+		t.Errorf("NLToLF decode = %q", got)
+	}
+	if got := ebcdic.IBM1047.Decode([]byte{0x15}, ebcdic.KeepNL); got != "\u0085" {
+		t.Errorf("KeepNL decode = %q", got)
+	}
+	if got := ebcdic.IBM1047.Encode("A\n", ebcdic.NLToLF); !bytes.Equal(got, []byte{0xC1, 0x15}) {
+		t.Errorf("NLToLF encode = % X", got)
+	}
+	if got := ebcdic.IBM1047.Encode("A\n", ebcdic.KeepNL); !bytes.Equal(got, []byte{0xC1, 0x25}) {
+		t.Errorf("KeepNL encode = % X", got)
+	}
+}
+
+// TestStreams pushes text with multibyte runes through all four transformers
+// one byte at a time, so runes are split across reads and writes.
+func TestStreams(t *testing.T) {
+	const text = "Grüße [€] £5\n"
+	cp := ebcdic.IBM1141
+
+	var enc bytes.Buffer
+	w := ebcdic.NewWriter(&enc, cp, ebcdic.NLToLF)
+	for i := range len(text) {
+		if _, err := w.Write([]byte{text[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	pulled, err := io.ReadAll(ebcdic.NewEncodingReader(iotest.OneByteReader(strings.NewReader(text)), cp, ebcdic.NLToLF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(enc.Bytes(), pulled) || !bytes.Equal(enc.Bytes(), cp.Encode(text, ebcdic.NLToLF)) {
+		t.Fatalf("Writer % X, EncodingReader % X", enc.Bytes(), pulled)
+	}
+
+	got, err := io.ReadAll(ebcdic.NewReader(iotest.OneByteReader(bytes.NewReader(enc.Bytes())), cp, ebcdic.NLToLF))
+	if err != nil || string(got) != text {
+		t.Errorf("Reader = %q, %v", got, err)
+	}
+	var dec strings.Builder
+	dw := ebcdic.NewDecodingWriter(&dec, cp, ebcdic.NLToLF)
+	for _, b := range enc.Bytes() {
+		if _, err := dw.Write([]byte{b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dec.String() != text {
+		t.Errorf("DecodingWriter = %q", dec.String())
+	}
+}
+
+// TestLookup accepts the usual spellings of a code page name.
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"IBM-1047", "ibm1047", "CP1047", "1047"} {
+		if cp, err := ebcdic.Lookup(name); err != nil || cp != ebcdic.IBM1047 {
+			t.Errorf("Lookup(%q) = %v, %v", name, cp, err)
+		}
+	}
+	if cp, err := ebcdic.Lookup("IBM-037"); err != nil || cp.CCSID() != 37 || cp.Name() != "IBM-037" {
+		t.Errorf("Lookup(IBM-037) = %v, %v", cp, err)
+	}
+	if _, err := ebcdic.Lookup("IBM-930"); !errors.Is(err, ebcdic.ErrUnknownCodePage) {
+		t.Errorf("Lookup(IBM-930) err = %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
+)
+
+// TestLocalConversion round-trips a z/OS UNIX file through WithLocalConversion:
+// the upload reaches the server as IBM-1047 with NL line ends, and the download
+// decodes it back, both over TYPE I even though TypeAscii was passed.
+func TestLocalConversion(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddFile("/u/me/.profile", nil)
+	conv := zftp.WithLocalConversion(ebcdic.IBM1047, ebcdic.NLToLF)
+	const text = "key=[value]\nsum=^1\n"
+
+	n, err := s.StoreIO("/u/me/app.conf", strings.NewReader(text), zftp.TypeAscii, conv)
+	if err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	stored, _ := srv.File("/u/me/app.conf")
+	if want := ebcdic.IBM1047.Encode(text, ebcdic.NLToLF); !bytes.Equal(stored, want) || n != int64(len(want)) {
+		t.Fatalf("stored % X (n=%d), want % X", stored, n, want)
+	}
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("/u/me/app.conf", &out, zftp.TypeAscii, conv); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if out.String() != text {
+		t.Errorf("retrieved %q, want %q", out.String(), text)
+	}
+	for _, transfer := range []string{"STOR /u/me/app.conf", "RETR /u/me/app.conf"} {
+		typ := ""
+		for _, c := range srv.Commands()[:cmdIndex(srv.Commands(), transfer)] {
+			if strings.HasPrefix(c, "TYPE ") {
+				typ = c
+			}
+		}
+		if typ != "TYPE I" {
+			t.Errorf("%s ran under %q, want TYPE I", transfer, typ)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// TestFaults_ResetDataFailsRetrieve resets every RETR data connection part-way
+// through; the client must report the broken stream rather than short data.
+func TestFaults_ResetDataFailsRetrieve(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.DataFor("RETR", "", strings.Repeat("X", 256<<10))
+	srv.InjectFaults(11, mockzos.ResetData("RETR", 1))
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("'HLQ.DATA'", &out, zftp.TypeBinary); err == nil {
+		t.Fatalf("RetrieveIO succeeded with %d bytes over a reset data connection", out.Len())
+	}
+}
+
+// TestFaults_SlowLinkStillCompletes combines a bandwidth cap, a delayed closing
+// reply and split multiline replies; the transfer must still complete intact.
+func TestFaults_SlowLinkStillCompletes(t *testing.T) {
+	s, srv := dialMock(t)
+	payload := strings.Repeat("0123456789", 1000)
+	srv.DataFor("RETR", "", payload)
+	srv.CompletionReply("RETR", "250-Transfer statistics follow", "250 Transfer completed successfully.")
+	srv.InjectFaults(5,
+		mockzos.Bandwidth(100<<10),
+		mockzos.DelayCompletion("RETR", 30*time.Millisecond),
+		mockzos.SplitReplies())
+
+	start := time.Now()
+	var out strings.Builder
+	if _, err := s.RetrieveIO("'HLQ.DATA'", &out, zftp.TypeBinary); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if out.String() != payload {
+		t.Errorf("retrieved %d bytes, want %d", out.Len(), len(payload))
+	}
+	if el := time.Since(start); el < 100*time.Millisecond {
+		t.Errorf("10 KB at 100 KB/s plus a 30ms delay took %v", el)
+	}
+}
+
+// TestFaults_DisconnectAfterSurfacesError drops the session with a 421 once the
+// command budget is spent.
+func TestFaults_DisconnectAfterSurfacesError(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.InjectFaults(1, mockzos.DisconnectAfter(len(srv.Commands())))
+
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); err == nil {
+		t.Fatal("NOOP after the command budget succeeded")
+	}
+}
@@ -29,6 +29,8 @@ type FTPSession struct {
 	system      string
 	user        string
 	currType    atomic.Uint32 // current TransferType; atomic so transfers can read it lock-free
+	defMode     atomic.Uint32 // TransferMode RetrieveIO and StoreIO use by default
+	known       knownState    // SITE and TYPE values the server is known to have
 	jobPrefix   *regexp.Regexp
 	isClosed    atomic.Bool
 	reader      *bufio.Reader
@@ -108,6 +110,10 @@ func newSession(conn net.Conn, cfg dialOptions) *FTPSession {
 	// Seed currType so a transfer that restores the prior type before any explicit
 	// SetType (e.g. on a session used before Login) never emits "TYPE \x00".
 	s.currType.Store(uint32(TypeAscii))
+	s.defMode.Store(uint32(ModeStream))
+	if cfg.transferMode != 0 {
+		s.defMode.Store(uint32(cfg.transferMode))
+	}
 	return s
 }
 
@@ -267,6 +273,12 @@ func (s *FTPSession) IsClosed() bool {
 }
 
 // Login sends the USER and PASS commands to the FTP server
+//
+// Login forgets what the session knew of the server's settings but reads no
+// STAT itself: the STAT that seeds them is sent the first time a setting is
+// needed, so a session that never needs one pays no extra round trip and a
+// server that rejects STAT does not fail the login. Reading it lazily rather
+// than at login is deliberate.
 func (s *FTPSession) Login(user, pass string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -274,6 +286,7 @@ func (s *FTPSession) Login(user, pass string) error {
 	// The whole login handshake runs under s.mu, so every step uses the locked
 	// helpers (sendLocked / setTypeLocked / setStatusOfLocked) to avoid the
 	// re-entrant deadlock a sync.Mutex would otherwise cause.
+	s.known.reset()
 	_, err := s.sendLocked(context.Background(), CodeNeedPwd, "USER", user)
 	if err != nil {
 		return err
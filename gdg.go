@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+)
+
+// ErrNoGeneration is returned by ResolveGeneration for a relative generation
+// that is not cataloged.
+var ErrNoGeneration = errors.New("zftp: generation not found")
+
+// maxAbsoluteGeneration is the last absolute generation number before the
+// numbering wraps to G0000.
+const maxAbsoluteGeneration = 9999
+
+// generationQualifier matches the low-level qualifier of a generation, GnnnnVnn.
+var generationQualifier = regexp.MustCompile(`^G(\d{4})V(\d{2})$`)
+
+// Generation is a cataloged generation of a GDG, as returned by
+// ListGenerations.
+type Generation struct {
+	// Dataset is the listing entry of the generation, BASE.GnnnnVnn.
+	Dataset hfs.InfoDataset
+	// Absolute is the generation number, nnnn of GnnnnVnn.
+	Absolute int
+	// Version is the version number, nn of GnnnnVnn.
+	Version int
+	// Relative is the generation relative to the latest: 0 for the latest, -1
+	// for the one before, and so on.
+	Relative int
+}
+
+// gdgBase parses base as the name of a GDG base: no member or generation.
+func gdgBase(base string) (DSN, error) {
+	d, err := ParseDSN(base)
+	if err != nil {
+		return DSN{}, err
+	}
+	if d.Member != "" || d.GDG {
+		return DSN{}, fmt.Errorf("%w: %s is not a GDG base name", ErrInvalidDSN, base)
+	}
+	return d, nil
+}
+
+// generationName returns the name of the generation abs, version ver, of base,
+// quoted when base is.
+func generationName(base DSN, abs, ver int) string {
+	d := base
+	d.Qualifiers = slices.Concat(base.Qualifiers, []string{fmt.Sprintf("G%04dV%02d", abs, ver)})
+	return d.String()
+}
+
+// ListGenerations returns the cataloged generations of the GDG base, oldest
+// first, with their absolute and relative numbers. base is quoted or relative to
+// the working directory, as for ListDatasets; a base without generations
+// returns none. The numbering wraps from G9999 to G0000: after a wrap, G0000
+// and up are the newest.
+func (s *FTPSession) ListGenerations(base string) ([]Generation, error) {
+	d, err := gdgBase(base)
+	if err != nil {
+		return nil, err
+	}
+	// The listing shows fully qualified names; match them against the resolved
+	// base, not the name as given.
+	full := d
+	if !d.Quoted {
+		if full, err = s.ResolveDSN(base); err != nil {
+			return nil, err
+		}
+	}
+	pattern := d.Name() + ".G*"
+	if d.Quoted {
+		pattern = "'" + pattern + "'"
+	}
+	datasets, err := s.ListDatasets(pattern)
+	if err != nil && !errors.Is(err, CodeError(550)) {
+		return nil, err
+	}
+	var gens []Generation
+	for _, ds := range datasets {
+		low, ok := strings.CutPrefix(ds.Name(), full.Name()+".")
+		if !ok {
+			continue
+		}
+		m := generationQualifier.FindStringSubmatch(low)
+		if m == nil {
+			continue
+		}
+		abs, _ := strconv.Atoi(m[1])
+		ver, _ := strconv.Atoi(m[2])
+		gens = append(gens, Generation{Dataset: ds, Absolute: abs, Version: ver})
+	}
+	slices.SortFunc(gens, func(a, b Generation) int {
+		if a.Absolute != b.Absolute {
+			return a.Absolute - b.Absolute
+		}
+		return a.Version - b.Version
+	})
+	// A GDG holds at most 999 generations, so a gap of more than half the
+	// numbering between neighbours is where it wrapped: the oldest follow it.
+	for i := 1; i < len(gens); i++ {
+		if gens[i].Absolute-gens[i-1].Absolute > (maxAbsoluteGeneration+1)/2 {
+			gens = slices.Concat(gens[i:], gens[:i])
+			break
+		}
+	}
+	for i := range gens {
+		gens[i].Relative = i - (len(gens) - 1)
+	}
+	return gens, nil
+}
+
+// ResolveGeneration returns the absolute name, BASE.GnnnnVnn, of the relative
+// generation rel of the GDG base: 0 is the latest cataloged generation, -1 the
+// one before, and +1 the next one to create, version 00. The name is quoted
+// when base is. A generation that is not cataloged returns ErrNoGeneration;
+// +1 of a GDG without generations is G0001V00.
+func (s *FTPSession) ResolveGeneration(base string, rel int) (string, error) {
+	d, err := gdgBase(base)
+	if err != nil {
+		return "", err
+	}
+	gens, err := s.ListGenerations(base)
+	if err != nil {
+		return "", err
+	}
+	if rel > 0 {
+		next := rel
+		if len(gens) > 0 {
+			next = (gens[len(gens)-1].Absolute + rel) % (maxAbsoluteGeneration + 1)
+		}
+		return generationName(d, next, 0), nil
+	}
+	i := len(gens) - 1 + rel
+	if i < 0 {
+		return "", fmt.Errorf("%w: %s(%d)", ErrNoGeneration, d.Name(), rel)
+	}
+	return generationName(d, gens[i].Absolute, gens[i].Version), nil
+}
+
+// Server replies to a relative generation reference that retryGeneration
+// retries with the absolute name, matched case-insensitively in the 550 text.
+var (
+	// retrRejections are RETR replies for a reference the server does not
+	// resolve: "Request nonexistent data set or member", or relative
+	// generations not supported.
+	retrRejections = []string{"nonexistent", "not found", "not supported"}
+	// storRejections are STOR replies for a new generation the server takes
+	// as the name of an existing dataset.
+	storRejections = []string{"already exists"}
+)
+
+// retryGeneration runs transfer with remote and, when remote is a relative
+// generation reference such as BASE(+1) that the server rejects with a 550
+// containing one of rejections, again with the absolute name from
+// ResolveGeneration. Any other failure is returned as is.
+func (s *FTPSession) retryGeneration(remote string, rejections []string, transfer func(remote string) error) error {
+	err := transfer(remote)
+	var re *ReturnError
+	if err == nil || !errors.As(err, &re) || re.ReturnCode() != 550 || !containsAny(strings.ToLower(re.message), rejections) {
+		return err
+	}
+	d, perr := ParseDSN(remote)
+	if perr != nil || !d.GDG {
+		return err
+	}
+	base := d
+	base.GDG, base.Generation = false, 0
+	name, rerr := s.ResolveGeneration(base.String(), d.Generation)
+	if rerr != nil {
+		return fmt.Errorf("%w; resolving the generation: %w", err, rerr)
+	}
+	s.log.Debugf("server rejected %s, retrying as %s", remote, name)
+	return transfer(name)
+}
+
+// containsAny reports whether s contains any of subs.
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
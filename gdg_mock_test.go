@@ -0,0 +1,179 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// seedGDG catalogs three generations of ME.BACKUP out of order, and a dataset
+// under the base that is not a generation.
+func seedGDG(srv *mockzos.Server) {
+	fb := mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}
+	srv.AddDataset("ME.BACKUP.G0010V00", fb, "TENTH")
+	srv.AddDataset("ME.BACKUP.G0002V00", fb, "SECOND")
+	srv.AddDataset("ME.BACKUP.G0009V01", fb, "NINTH")
+	srv.AddDataset("ME.BACKUP.INDEX", fb, "NOT A GENERATION")
+}
+
+func TestListGenerations(t *testing.T) {
+	s, srv := dialMock(t)
+	seedGDG(srv)
+
+	gens, err := s.ListGenerations("'ME.BACKUP'")
+	if err != nil {
+		t.Fatalf("ListGenerations: %v", err)
+	}
+	type gen struct {
+		name          string
+		abs, ver, rel int
+	}
+	var got []gen
+	for _, g := range gens {
+		got = append(got, gen{g.Dataset.Name(), g.Absolute, g.Version, g.Relative})
+	}
+	want := []gen{
+		{"ME.BACKUP.G0002V00", 2, 0, -2},
+		{"ME.BACKUP.G0009V01", 9, 1, -1},
+		{"ME.BACKUP.G0010V00", 10, 0, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("generations = %v, want %v", got, want)
+	}
+
+	if gens, err := s.ListGenerations("'ME.EMPTY'"); err != nil || len(gens) != 0 {
+		t.Errorf("ListGenerations(empty) = %v, %v", gens, err)
+	}
+	if _, err := s.ListGenerations("'ME.BACKUP(0)'"); !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("ListGenerations(relative): got %v, want ErrInvalidDSN", err)
+	}
+}
+
+func TestResolveGeneration(t *testing.T) {
+	s, srv := dialMock(t)
+	seedGDG(srv)
+
+	for rel, want := range map[int]string{
+		1:  "'ME.BACKUP.G0011V00'",
+		3:  "'ME.BACKUP.G0013V00'",
+		0:  "'ME.BACKUP.G0010V00'",
+		-1: "'ME.BACKUP.G0009V01'",
+		-2: "'ME.BACKUP.G0002V00'",
+	} {
+		if got, err := s.ResolveGeneration("'ME.BACKUP'", rel); err != nil || got != want {
+			t.Errorf("ResolveGeneration(%d) = %q, %v; want %q", rel, got, err, want)
+		}
+	}
+	if _, err := s.ResolveGeneration("'ME.BACKUP'", -3); !errors.Is(err, zftp.ErrNoGeneration) {
+		t.Errorf("ResolveGeneration(-3): got %v, want ErrNoGeneration", err)
+	}
+	if got, err := s.ResolveGeneration("'ME.NEW'", 1); err != nil || got != "'ME.NEW.G0001V00'" {
+		t.Errorf("ResolveGeneration(new, +1) = %q, %v", got, err)
+	}
+	if _, err := s.ResolveGeneration("'ME.NEW'", 0); !errors.Is(err, zftp.ErrNoGeneration) {
+		t.Errorf("ResolveGeneration(new, 0): got %v, want ErrNoGeneration", err)
+	}
+}
+
+// TestGetPut_RelativeGeneration checks Get and Put fall back to the absolute
+// generation name when the server rejects a relative reference, and that a
+// base without generations fails with ErrNoGeneration.
+func TestGetPut_RelativeGeneration(t *testing.T) {
+	s, srv := dialMock(t)
+	seedGDG(srv)
+	dir := t.TempDir()
+
+	for ref, want := range map[string]string{"'ME.BACKUP(0)'": "TENTH\n", "'ME.BACKUP(-1)'": "NINTH\n"} {
+		local := filepath.Join(dir, "latest.txt")
+		if err := s.Get(ref, local, zftp.TypeAscii); err != nil {
+			t.Fatalf("Get(%s): %v", ref, err)
+		}
+		if got, _ := os.ReadFile(local); string(got) != want {
+			t.Errorf("Get(%s) = %q, want %q", ref, got, want)
+		}
+	}
+	if cmds := srv.Commands(); !hasCmd(cmds, "RETR 'ME.BACKUP.G0010V00'") || !hasCmd(cmds, "RETR 'ME.BACKUP.G0009V01'") {
+		t.Errorf("commands = %v", cmds)
+	}
+
+	src := filepath.Join(dir, "next.txt")
+	if err := os.WriteFile(src, []byte("ELEVENTH\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv.Script("STOR 'ME.BACKUP(+1)'", "550 STOR fails: ME.BACKUP(+1) already exists.")
+	if err := s.Put(src, "'ME.BACKUP(+1)'", zftp.TypeAscii); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got, _ := srv.Records("ME.BACKUP.G0011V00"); !reflect.DeepEqual(got, []string{"ELEVENTH"}) {
+		t.Errorf("records of G0011V00 = %q", got)
+	}
+	if cmds := srv.Commands(); cmdIndex(cmds, "STOR 'ME.BACKUP(+1)'") < 0 || cmdIndex(cmds, "STOR 'ME.BACKUP(+1)'") > cmdIndex(cmds, "STOR 'ME.BACKUP.G0011V00'") {
+		t.Errorf("commands = %v", cmds)
+	}
+
+	srv.Script("STOR 'ME.OTHER(0)'", "550 STOR fails: ME.OTHER(0) already exists.")
+	if err := s.Put(src, "'ME.OTHER(0)'", zftp.TypeAscii); !errors.Is(err, zftp.ErrNoGeneration) {
+		t.Errorf("Put(uncataloged): got %v, want ErrNoGeneration", err)
+	}
+
+	before := len(srv.Commands())
+	if err := s.Get("'ME.OTHER(0)'", filepath.Join(dir, "other.txt"), zftp.TypeAscii); !errors.Is(err, zftp.ErrNoGeneration) || !errors.Is(err, zftp.CodeError(550)) {
+		t.Errorf("Get(uncataloged): got %v, want the 550 and ErrNoGeneration", err)
+	}
+	if cmds := srv.Commands()[before:]; countPrefix(cmds, "RETR") != 1 {
+		t.Errorf("Get(uncataloged) retried: %v", cmds)
+	}
+}
+
+// TestListGenerations_Wrap checks generations numbered past G9999 come after
+// the ones before the wrap.
+func TestListGenerations_Wrap(t *testing.T) {
+	s, srv := dialMock(t)
+	fb := mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}
+	for _, g := range []string{"G0001V00", "G9998V00", "G0000V00", "G9999V00"} {
+		srv.AddDataset("ME.DAILY."+g, fb, g)
+	}
+
+	gens, err := s.ListGenerations("'ME.DAILY'")
+	if err != nil {
+		t.Fatalf("ListGenerations: %v", err)
+	}
+	var got []string
+	for _, g := range gens {
+		got = append(got, fmt.Sprintf("%04d:%d", g.Absolute, g.Relative))
+	}
+	if want := []string{"9998:-3", "9999:-2", "0000:-1", "0001:0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("generations = %v, want %v", got, want)
+	}
+	if next, err := s.ResolveGeneration("'ME.DAILY'", 1); err != nil || next != "'ME.DAILY.G0002V00'" {
+		t.Errorf("ResolveGeneration(+1) = %q, %v", next, err)
+	}
+}
+
+// TestListGenerations_ExactBase checks a relative base only matches the
+// generations of the base it resolves to, not of one ending in the same
+// qualifiers.
+func TestListGenerations_ExactBase(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.Script("PWD", `257 "'ME.'" is working directory.`)
+	srv.DataFor("LIST", "", "Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname\r\n"+
+		"FA00FF 3390   2023/06/02  1    1  FB      80 27920  PS  ME.BACKUP.G0003V00\r\n"+
+		"FA00FF 3390   2023/06/02  1    1  FB      80 27920  PS  ME.OLD.BACKUP.G0007V00\r\n"+
+		"FA00FF 3390   2023/06/02  1    1  FB      80 27920  PS  ME.BACKUP.GX.G0004V00\r\n")
+
+	gens, err := s.ListGenerations("backup")
+	if err != nil {
+		t.Fatalf("ListGenerations: %v", err)
+	}
+	if len(gens) != 1 || gens[0].Dataset.Name() != "ME.BACKUP.G0003V00" {
+		t.Errorf("generations = %+v", gens)
+	}
+}
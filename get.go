@@ -14,7 +14,14 @@ import (
 // Get retrieves a file from the FTP server and saves it to the local file system.
 // If the local file already exists, it is overwritten.
 // mode is the transfer mode, either ASCII or binary.
+// A malformed quoted dataset name fails with ErrInvalidDSN before the local
+// file is created; GetDSN takes a parsed DSN. A relative GDG reference, BASE(0)
+// or BASE(-1), that the server rejects as not found or not supported is
+// retried with the absolute generation name; see ResolveGeneration.
 func (s *FTPSession) Get(remote string, localFile string, mode TransferType) error {
+	if err := checkDSN(remote); err != nil {
+		return err
+	}
 	s.log.Debug("creating local file: ", localFile)
 	file, err := os.Create(localFile)
 	if err != nil {
@@ -33,7 +40,17 @@ func (s *FTPSession) Get(remote string, localFile string, mode TransferType) err
 	}()
 
 	s.log.Debug("starting transfer from: ", remote)
-	bytesTransferred, err := s.RetrieveIO(remote, file, mode)
+	var bytesTransferred int64
+	err = s.retryGeneration(remote, retrRejections, func(remote string) error {
+		if err := file.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		bytesTransferred, err = s.RetrieveIO(remote, file, mode)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to retrieve file: %w", err)
 	}
@@ -48,7 +65,7 @@ func (s *FTPSession) Get(remote string, localFile string, mode TransferType) err
 // Resume requires image/binary mode: a positive offset combined with TypeAscii
 // returns ErrAsciiResumeUnsupported before the local file is opened, created, or
 // truncated, because in ASCII mode the server's EOL/codepage translation makes a
-// byte offset corrupt the data.
+// byte offset corrupt the data; use GetRestart to resume an ASCII download.
 func (s *FTPSession) GetAt(remote string, localFile string, mode TransferType, offset int64) error {
 	if err := guardResume(mode, offset); err != nil {
 		return err
@@ -94,6 +111,59 @@ func (s *FTPSession) GetAt(remote string, localFile string, mode TransferType, o
 	return nil
 }
 
+// GetRestart retrieves a file in block mode (see WithBlockMode), keeping cp at
+// the server's latest restart marker, so that a download cut short, ASCII
+// included, can be resumed by calling GetRestart again with the same cp, on
+// this or a new session. With an empty cp the local file is created or
+// truncated; with a marker it is truncated to cp.Offset, dropping whatever
+// arrived after the marker, and the server resumes with REST <marker>.
+//
+//	var cp zftp.Checkpoint
+//	err := s.GetRestart("'HLQ.BIG.TEXT'", "big.txt", zftp.TypeAscii, &cp)
+//	// ... after an interruption, on a new session:
+//	err = s2.GetRestart("'HLQ.BIG.TEXT'", "big.txt", zftp.TypeAscii, &cp)
+func (s *FTPSession) GetRestart(remote string, localFile string, mode TransferType, cp *Checkpoint) (err error) {
+	if cp == nil {
+		cp = new(Checkpoint)
+	}
+	if cp.Marker == "" {
+		cp.Offset = 0
+	}
+
+	s.log.Debug("opening local file: ", localFile)
+	file, err := os.OpenFile(localFile, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+
+	defer func() {
+		cerr := file.Close()
+		if cerr != nil {
+			if err != nil {
+				err = fmt.Errorf("%w; also failed to close file: %w", err, cerr)
+			} else {
+				err = fmt.Errorf("failed to close file: %w", cerr)
+			}
+		}
+	}()
+
+	if err = file.Truncate(cp.Offset); err != nil {
+		return fmt.Errorf("failed to truncate file: %w", err)
+	}
+	if _, err = file.Seek(cp.Offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek: %w", err)
+	}
+
+	s.log.Debugf("starting block-mode transfer from %s at marker %q", remote, cp.Marker)
+	bytesTransferred, err := s.RetrieveIO(remote, file, mode, WithBlockMode(cp))
+	if err != nil {
+		return fmt.Errorf("failed to retrieve file: %w", err)
+	}
+
+	s.log.Debugf("successfully transferred %d bytes from %s", bytesTransferred, remote)
+	return nil
+}
+
 // GetAndGzip retrieves a file from the FTP server and compresses it using gzip.
 // The compressed file is saved to the local file system.
 // The local file name is the same as the remote file name, with the extension ".gz" appended.
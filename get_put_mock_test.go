@@ -178,3 +178,46 @@ func TestPutAt_AsciiOffsetRejected_BeforeOpen(t *testing.T) {
 		t.Errorf("server stored data on a rejected ASCII resume")
 	}
 }
+
+// TestPutAutoDCB checks a text file goes up in ASCII after a SITE carrying the
+// inferred DCB, and a binary file in image mode with no SITE.
+func TestPutAutoDCB(t *testing.T) {
+	s, srv := dialMock(t)
+	dir := t.TempDir()
+	text, bin := filepath.Join(dir, "src.txt"), filepath.Join(dir, "data.bin")
+	if err := os.WriteFile(text, []byte("//JOB1 JOB\n//STEP EXEC PGM=IEFBR14\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(bin, []byte{0, 1, 2, 0xff}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := s.PutAutoDCB(text, "'ME.JCL'", zftp.DCBOptions{})
+	if err != nil || d.Recfm != zftp.RecfmFB || d.Lrecl != 80 {
+		t.Fatalf("PutAutoDCB(text) = %+v, %v", d, err)
+	}
+	cmds := srv.Commands()
+	if i, j := cmdIndex(cmds, "SITE RECFM=FB LRECL=80 BLKSIZE=27920"), cmdIndex(cmds, "STOR 'ME.JCL'"); i < 0 || j < i || !hasCmd(cmds[:j], "TYPE A") {
+		t.Errorf("commands = %v", cmds)
+	}
+
+	before := len(srv.Commands())
+	if d, err := s.PutAutoDCB(bin, "'ME.BIN'", zftp.DCBOptions{}); err != nil || !d.Binary {
+		t.Fatalf("PutAutoDCB(binary) = %+v, %v", d, err)
+	}
+	cmds = srv.Commands()[before:]
+	if countPrefix(cmds, "SITE") != 0 || countPrefix(cmds, "TYPE A") != 0 {
+		t.Errorf("commands = %v", cmds)
+	}
+	if got, _ := srv.Stored("'ME.BIN'"); !bytes.Equal(got, []byte{0, 1, 2, 0xff}) {
+		t.Errorf("stored %q", got)
+	}
+
+	before = len(srv.Commands())
+	if _, err := s.PutAutoDCB(text, "'ME.JCL'", zftp.DCBOptions{MaxLrecl: 20, Strict: true}); !errors.Is(err, zftp.ErrLineTooLong) {
+		t.Errorf("strict: got %v, want ErrLineTooLong", err)
+	}
+	if n := len(srv.Commands()) - before; n != 0 {
+		t.Errorf("strict failure sent %d commands", n)
+	}
+}
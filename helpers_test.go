@@ -17,3 +17,15 @@ func cmdIndex(cmds []string, want string) int {
 
 // hasCmd reports whether want appears in the received command lines.
 func hasCmd(cmds []string, want string) bool { return cmdIndex(cmds, want) >= 0 }
+
+// countCmd returns how many received command lines equal want, compared like
+// cmdIndex.
+func countCmd(cmds []string, want string) int {
+	n := 0
+	for _, c := range cmds {
+		if strings.EqualFold(strings.TrimSpace(c), want) {
+			n++
+		}
+	}
+	return n
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/internal/dsn"
 )
 
 // InfoDataset is a struct that represents a z/OS dataset
@@ -45,8 +47,12 @@ type InfoDataset struct {
 // InfoDataset satisfies fmt.Stringer by value (ListDatasets returns []InfoDataset).
 var _ fmt.Stringer = InfoDataset{}
 
-// Name returns DName but without the quotes
+// Name returns Dsname without the quotes. A name that does not parse as a
+// dataset name is returned with its quotes trimmed.
 func (d *InfoDataset) Name() string {
+	if n, err := dsn.Parse(d.Dsname.String()); err == nil {
+		return n.Name()
+	}
 	return strings.Trim(d.Dsname.String(), "'")
 }
 
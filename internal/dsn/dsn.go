@@ -0,0 +1,193 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package dsn parses and validates z/OS dataset names. It is shared by the
+// zftp package, which exposes it as zftp.DSN, and the hfs listing types.
+package dsn
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for a dataset name that breaks the z/OS naming rules.
+var ErrInvalid = errors.New("zftp: invalid dataset name")
+
+const (
+	maxDSNLength       = 44  // dataset name, dots included
+	maxGDGBaseLength   = 35  // leaves room for .GnnnnVnn
+	maxQualifierLength = 8   // qualifier or member name
+	maxGenerations     = 255 // furthest relative generation
+)
+
+// DSN is a parsed z/OS dataset name, with an optional PDS member or GDG
+// relative generation: 'HLQ.SRC.COBOL(PAYROLL)', HLQ.BACKUP(+1).
+type DSN struct {
+	// Qualifiers are the uppercased parts of the name, the high-level qualifier
+	// first.
+	Qualifiers []string
+	// Member is the PDS member, or "".
+	Member string
+	// Generation is the relative generation of a GDG reference, such as +1, 0
+	// or -1; meaningful when GDG is true.
+	Generation int
+	// GDG is true for a relative generation reference, BASE(n).
+	GDG bool
+	// Quoted is true for a fully qualified name. An unquoted name is relative to
+	// the session's prefix; see Resolve.
+	Quoted bool
+}
+
+// Parse parses and validates a dataset name as the FTP server takes it:
+// quoted when fully qualified, unquoted when relative to the working directory.
+// Names are case-insensitive and returned uppercased.
+//
+// A qualifier has 1 to 8 characters: a letter or national character (@, # or
+// $), then letters, digits, national characters or hyphens. A name has at most
+// 44 characters, a GDG base at most 35. A member follows the qualifier rules
+// without hyphens; a relative generation is 0 or signed, up to 255.
+func Parse(name string) (DSN, error) {
+	var d DSN
+	s := strings.TrimSpace(name)
+	if strings.HasPrefix(s, "'") {
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return DSN{}, fmt.Errorf("%w: %s: unbalanced quotes", ErrInvalid, name)
+		}
+		s, d.Quoted = s[1:len(s)-1], true
+	}
+	s = strings.ToUpper(s)
+	if open := strings.IndexByte(s, '('); open >= 0 {
+		if !strings.HasSuffix(s, ")") {
+			return DSN{}, fmt.Errorf("%w: %s: unbalanced parentheses", ErrInvalid, name)
+		}
+		if err := d.setSuffix(s[open+1 : len(s)-1]); err != nil {
+			return DSN{}, fmt.Errorf("%w: %s: %w", ErrInvalid, name, err)
+		}
+		s = s[:open]
+	}
+	d.Qualifiers = strings.Split(s, ".")
+	if err := d.check(); err != nil {
+		return DSN{}, fmt.Errorf("%w: %s: %w", ErrInvalid, name, err)
+	}
+	return d, nil
+}
+
+// setSuffix parses what is between the parentheses: a member or a generation.
+func (d *DSN) setSuffix(s string) error {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '+' && r != '-' }) < 0 {
+		n, err := strconv.Atoi(s)
+		switch {
+		case err != nil:
+			return fmt.Errorf("generation %q is not a number", s)
+		case n != 0 && s[0] != '+' && s[0] != '-':
+			return fmt.Errorf("generation %q needs a sign", s)
+		case n > maxGenerations || n < -maxGenerations:
+			return fmt.Errorf("generation %q is beyond %d", s, maxGenerations)
+		}
+		d.Generation, d.GDG = n, true
+		return nil
+	}
+	if err := checkName("member", s, false); err != nil {
+		return err
+	}
+	d.Member = s
+	return nil
+}
+
+// check validates the qualifiers and the length of the name.
+func (d DSN) check() error {
+	for _, q := range d.Qualifiers {
+		if err := checkName("qualifier", q, true); err != nil {
+			return err
+		}
+	}
+	limit := maxDSNLength
+	if d.GDG {
+		limit = maxGDGBaseLength
+	}
+	if n := len(d.Name()); n > limit {
+		return fmt.Errorf("%d characters, more than %d", n, limit)
+	}
+	return nil
+}
+
+// checkName validates a qualifier or member name.
+func checkName(kind, s string, hyphen bool) error {
+	if s == "" || len(s) > maxQualifierLength {
+		return fmt.Errorf("%s %q must have 1 to %d characters", kind, s, maxQualifierLength)
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'A' && r <= 'Z', r == '@', r == '#', r == '$':
+		case i > 0 && (r >= '0' && r <= '9' || hyphen && r == '-'):
+		default:
+			return fmt.Errorf("%s %q has an invalid character %q", kind, s, r)
+		}
+	}
+	return nil
+}
+
+// Name returns the dataset name, qualifiers only: no quotes, member or
+// generation. For a GDG reference it is the base.
+func (d DSN) Name() string {
+	return strings.Join(d.Qualifiers, ".")
+}
+
+// String returns the name as the FTP server takes it, quoted when fully
+// qualified: 'HLQ.SRC(MEMBER)', HLQ.BACKUP(+1).
+func (d DSN) String() string {
+	name := d.Name()
+	switch {
+	case d.Member != "":
+		name += "(" + d.Member + ")"
+	case d.GDG && d.Generation > 0:
+		name += fmt.Sprintf("(+%d)", d.Generation)
+	case d.GDG:
+		name += fmt.Sprintf("(%d)", d.Generation)
+	}
+	if d.Quoted {
+		return "'" + name + "'"
+	}
+	return name
+}
+
+// Resolve returns the fully qualified name of d: d itself when quoted,
+// otherwise d behind prefix, such as "IBMUSER" or "IBMUSER.PROJECT.". The
+// result is validated again, as the prefix counts towards the 44 characters.
+func (d DSN) Resolve(prefix string) (DSN, error) {
+	if d.Quoted {
+		return d, nil
+	}
+	prefix = strings.Trim(strings.ToUpper(strings.TrimSpace(prefix)), "'.")
+	if prefix == "" {
+		return DSN{}, fmt.Errorf("%w: %s: no prefix to resolve it against", ErrInvalid, d)
+	}
+	r := d
+	r.Qualifiers = append(strings.Split(prefix, "."), d.Qualifiers...)
+	r.Quoted = true
+	if err := r.check(); err != nil {
+		return DSN{}, fmt.Errorf("%w: %s: %w", ErrInvalid, r, err)
+	}
+	return r, nil
+}
+
+// CheckMember validates a PDS member name: 1 to 8 letters, digits or national
+// characters, not starting with a digit.
+func CheckMember(name string) error {
+	return checkName("member", name, false)
+}
+
+// RemoteName returns the name as the server takes it, after checking it again:
+// a DSN built field by field has not been through Parse.
+func (d DSN) RemoteName() (string, error) {
+	if err := d.check(); err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrInvalid, d, err)
+	}
+	if d.Member != "" {
+		if err := CheckMember(d.Member); err != nil {
+			return "", fmt.Errorf("%w: %s: %w", ErrInvalid, d, err)
+		}
+	}
+	return d.String(), nil
+}
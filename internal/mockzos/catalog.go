@@ -0,0 +1,492 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attrs are the DCB attributes of a dataset held by the stateful catalog. A zero
+// field takes the z/OS FTP default (RECFM=VB, LRECL=256, BLKSIZE=6233) when the
+// dataset is seeded; datasets created by STOR/MKD take the SITE values last set
+// on the session instead.
+type Attrs struct {
+	Recfm   string
+	Lrecl   int
+	BlkSize int
+	Volume  string
+}
+
+// defaultAttrs are the z/OS FTP server allocation defaults.
+var defaultAttrs = Attrs{Recfm: "VB", Lrecl: 256, BlkSize: 6233, Volume: "MOCK01"}
+
+// withDefaults fills the zero fields of a from defaultAttrs.
+func (a Attrs) withDefaults() Attrs {
+	if a.Recfm == "" {
+		a.Recfm = defaultAttrs.Recfm
+	}
+	if a.Lrecl == 0 {
+		a.Lrecl = defaultAttrs.Lrecl
+	}
+	if a.BlkSize == 0 {
+		a.BlkSize = defaultAttrs.BlkSize
+	}
+	if a.Volume == "" {
+		a.Volume = defaultAttrs.Volume
+	}
+	a.Recfm = strings.ToUpper(a.Recfm)
+	return a
+}
+
+// isFixed reports whether the record format is fixed-length (F, FB, FBA, ...).
+func (a Attrs) isFixed() bool { return strings.HasPrefix(a.Recfm, "F") }
+
+// isVariable reports whether the record format is variable-length (V, VB, ...).
+func (a Attrs) isVariable() bool { return strings.HasPrefix(a.Recfm, "V") }
+
+// trackBytes is the usable capacity of one 3390 track, used to derive the Used
+// column of a dataset listing from its content size.
+const trackBytes = 56664
+
+// dataset is a cataloged sequential or partitioned dataset.
+type dataset struct {
+	name     string
+	attrs    Attrs
+	library  bool // DSNTYPE=LIBRARY (PDSE): rendered as Dsorg "PO-E"
+	referred time.Time
+	records  [][]byte           // sequential content, one entry per record
+	members  map[string]*member // nil for a sequential dataset
+}
+
+func (d *dataset) partitioned() bool { return d.members != nil }
+
+func (d *dataset) dsorg() string {
+	switch {
+	case d.library:
+		return "PO-E"
+	case d.partitioned():
+		return "PO"
+	default:
+		return "PS"
+	}
+}
+
+// used returns the track count reported in the listing's Used column.
+func (d *dataset) used() int {
+	n := 0
+	for _, r := range d.records {
+		n += len(r)
+	}
+	for _, m := range d.members {
+		for _, r := range m.records {
+			n += len(r)
+		}
+	}
+	return max(1, (n+trackBytes-1)/trackBytes)
+}
+
+// member is a PDS member with its ISPF statistics.
+type member struct {
+	name    string
+	records [][]byte
+	ver     int
+	mod     int
+	created time.Time
+	changed time.Time
+	init    int
+	id      string
+}
+
+// touch records an update to the member's content in its ISPF statistics: a new
+// member starts at 01.00, and every later save bumps the modification level.
+func (m *member) touch(now time.Time, id string) {
+	if m.ver == 0 {
+		m.ver, m.mod = 1, 0
+		m.created = now
+		m.init = len(m.records)
+	} else {
+		m.mod++
+	}
+	m.changed = now
+	m.id = id
+}
+
+// ussNode is a z/OS UNIX file or directory.
+type ussNode struct {
+	dir   bool
+	data  []byte
+	mtime time.Time
+}
+
+// catalog is the in-memory state of the virtual z/OS system: the MVS catalog of
+// sequential and partitioned datasets plus a z/OS UNIX file tree. It is shared
+// by every connection to the server.
+type catalog struct {
+	mu       sync.Mutex
+	datasets map[string]*dataset
+	uss      map[string]*ussNode
+	jes      *jes
+}
+
+func newCatalog() *catalog {
+	return &catalog{
+		datasets: map[string]*dataset{},
+		uss:      map[string]*ussNode{"/": {dir: true}},
+		jes:      newJES(),
+	}
+}
+
+// EnableState switches the server into stateful mode: instead of answering
+// transfers only from DataFor payloads, it keeps an in-memory catalog of
+// sequential datasets, PDS members (with ISPF statistics) and a z/OS UNIX tree.
+// STOR creates or replaces entries using the SITE RECFM/LRECL/BLKSIZE last set,
+// RETR returns them, LIST renders z/OS-format listings, and DELE/RNFR/RNTO/MKD
+// mutate the catalog. Scripts, DataFor payloads and the fault hooks still take
+// precedence, so a stateful test can override a single reply.
+//
+// The Add… seeding helpers enable state implicitly.
+func (s *Server) EnableState() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cat == nil {
+		s.cat = newCatalog()
+	}
+}
+
+// state returns the catalog, or nil when the server is not in stateful mode.
+func (s *Server) state() *catalog {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cat
+}
+
+// AddDataset seeds a sequential dataset whose records are the given lines. The
+// name may be quoted or not; it is always cataloged as a fully qualified name.
+//
+//	srv.AddDataset("HLQ.DATA", mockzos.Attrs{Recfm: "FB", Lrecl: 80}, "LINE 1", "LINE 2")
+func (s *Server) AddDataset(dsn string, a Attrs, records ...string) {
+	s.EnableState()
+	c := s.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := normalizeDSN(dsn)
+	c.datasets[name] = &dataset{name: name, attrs: a.withDefaults(), referred: s.now(), records: toRecords(records)}
+}
+
+// AddPDS seeds an empty partitioned dataset.
+func (s *Server) AddPDS(dsn string, a Attrs) {
+	s.EnableState()
+	c := s.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name := normalizeDSN(dsn)
+	c.datasets[name] = &dataset{name: name, attrs: a.withDefaults(), referred: s.now(), members: map[string]*member{}}
+}
+
+// AddMember seeds a member of a PDS previously added with AddPDS, stamping ISPF
+// statistics (version 01.00, line counts, and "MOCK" as the last updater). It
+// fails the test when the PDS does not exist.
+func (s *Server) AddMember(pds, name string, records ...string) {
+	s.tb.Helper()
+	c := s.state()
+	if c == nil {
+		s.tb.Fatalf("mockzos: AddMember(%s, %s): no such PDS", pds, name)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ds, ok := c.datasets[normalizeDSN(pds)]
+	if !ok || !ds.partitioned() {
+		s.tb.Fatalf("mockzos: AddMember(%s, %s): no such PDS", pds, name)
+		return
+	}
+	m := &member{name: strings.ToUpper(name), records: toRecords(records)}
+	m.touch(s.now(), "MOCK")
+	ds.members[m.name] = m
+}
+
+// AddFile seeds a z/OS UNIX file at the absolute path p, creating any missing
+// parent directories.
+func (s *Server) AddFile(p string, data []byte) {
+	s.EnableState()
+	c := s.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p = path.Clean(p)
+	c.mkdirAll(path.Dir(p), s.now())
+	c.uss[p] = &ussNode{data: slices.Clone(data), mtime: s.now()}
+}
+
+// Records returns the records of a cataloged sequential dataset or PDS member
+// ("HLQ.PDS(MEM)"), so a test can assert on what a STOR left behind.
+func (s *Server) Records(dsn string) ([]string, bool) {
+	c := s.state()
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name, mem := splitMember(normalizeDSN(dsn))
+	ds, ok := c.datasets[name]
+	if !ok {
+		return nil, false
+	}
+	recs := ds.records
+	if mem != "" {
+		m, ok := ds.members[mem]
+		if !ok {
+			return nil, false
+		}
+		recs = m.records
+	} else if ds.partitioned() {
+		return nil, false
+	}
+	out := make([]string, len(recs))
+	for i, r := range recs {
+		out[i] = string(r)
+	}
+	return out, true
+}
+
+// File returns the content of a z/OS UNIX file.
+func (s *Server) File(p string) ([]byte, bool) {
+	c := s.state()
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, ok := c.uss[path.Clean(p)]
+	if !ok || n.dir {
+		return nil, false
+	}
+	return slices.Clone(n.data), true
+}
+
+// Exists reports whether a dataset, PDS member or z/OS UNIX path is present.
+func (s *Server) Exists(name string) bool {
+	c := s.state()
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if strings.HasPrefix(name, "/") {
+		_, ok := c.uss[path.Clean(name)]
+		return ok
+	}
+	dsn, mem := splitMember(normalizeDSN(name))
+	ds, ok := c.datasets[dsn]
+	if !ok || mem == "" {
+		return ok
+	}
+	_, ok = ds.members[mem]
+	return ok
+}
+
+// mkdirAll creates p and its missing parents. The caller must hold c.mu.
+func (c *catalog) mkdirAll(p string, now time.Time) {
+	for p != "/" && p != "." {
+		if _, ok := c.uss[p]; ok {
+			return
+		}
+		c.uss[p] = &ussNode{dir: true, mtime: now}
+		p = path.Dir(p)
+	}
+}
+
+func toRecords(lines []string) [][]byte {
+	out := make([][]byte, len(lines))
+	for i, l := range lines {
+		out[i] = []byte(l)
+	}
+	return out
+}
+
+// normalizeDSN strips the surrounding quotes of a dataset name and uppercases it.
+func normalizeDSN(name string) string {
+	return strings.ToUpper(strings.Trim(strings.TrimSpace(name), "'"))
+}
+
+// splitMember splits "HLQ.PDS(MEM)" into its dataset and member names.
+func splitMember(name string) (dsn, mem string) {
+	if i := strings.IndexByte(name, '('); i > 0 && strings.HasSuffix(name, ")") {
+		return name[:i], name[i+1 : len(name)-1]
+	}
+	return name, ""
+}
+
+// dsnPattern compiles a z/OS dataset-name pattern: '*' matches within one
+// qualifier, '**' across qualifiers, and '%' exactly one character.
+func dsnPattern(p string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^.]*")
+		case p[i] == '%':
+			b.WriteString("[^.]")
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+const (
+	datasetHeader = "Volume Unit    Referred Ext Used Recfm Lrecl BlkSz Dsorg Dsname"
+	memberHeader  = " Name     VV.MM   Created       Changed      Size  Init   Mod   Id"
+)
+
+// datasetLine renders one dataset row in the z/OS FTP LIST column geometry.
+func datasetLine(d *dataset) string {
+	return fmt.Sprintf("%-6s %-4s   %10s %2d %4d  %-4s%6d%6d  %-4s'%s'",
+		d.attrs.Volume, "3390", d.referred.Format("2006/01/02"), 1, d.used(),
+		d.attrs.Recfm, d.attrs.Lrecl, d.attrs.BlkSize, d.dsorg(), d.name)
+}
+
+// memberLine renders one PDS member row with its ISPF statistics.
+func memberLine(m *member) string {
+	return fmt.Sprintf("%-8s  %02d.%02d %s %s%6d%6d%6d %s",
+		m.name, m.ver, m.mod, m.created.Format("2006/01/02"), m.changed.Format("2006/01/02 15:04"),
+		len(m.records), m.init, 0, m.id)
+}
+
+// ussLine renders one z/OS UNIX entry in "ls -l" form.
+func ussLine(name string, n *ussNode) string {
+	mode, size := "-rw-r--r--", len(n.data)
+	if n.dir {
+		mode, size = "drwxr-xr-x", 8192
+	}
+	return fmt.Sprintf("%s   1 %-8s %-8s %10d %s %s", mode, "MOCK", "SYS1", size, n.mtime.Format("Jan _2 15:04"), name)
+}
+
+// sortedDatasets returns the datasets whose names match re, in name order.
+func (c *catalog) sortedDatasets(re *regexp.Regexp) []*dataset {
+	var out []*dataset
+	for name, d := range c.datasets {
+		if re.MatchString(name) {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// sortedMembers returns the members of d whose names match re, in name order.
+func (d *dataset) sortedMembers(re *regexp.Regexp) []*member {
+	var out []*member
+	for name, m := range d.members {
+		if re.MatchString(name) {
+			out = append(out, m)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].name < out[j].name })
+	return out
+}
+
+// ussChildren returns the names of the direct children of dir, sorted.
+func (c *catalog) ussChildren(dir string) []string {
+	var out []string
+	for p := range c.uss {
+		if p != dir && path.Dir(p) == dir {
+			out = append(out, path.Base(p))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// encodeRecords renders records for a RETR. In ASCII mode every record is
+// followed by eol (fixed records lose their trailing blanks, as with the z/OS
+// TRAILINGBLANKS default). In image mode fixed records are padded to LRECL, and
+// variable records carry a 4-byte RDW only when SITE RDW is in effect.
+func encodeRecords(a Attrs, recs [][]byte, ascii, rdw bool, eol string) []byte {
+	var out []byte
+	for _, r := range recs {
+		switch {
+		case ascii:
+			if a.isFixed() {
+				r = []byte(strings.TrimRight(string(r), " "))
+			}
+			out = append(out, r...)
+			out = append(out, eol...)
+		case a.isFixed():
+			out = append(out, r...)
+			for i := len(r); i < a.Lrecl; i++ {
+				out = append(out, ' ')
+			}
+		case a.isVariable() && rdw:
+			out = binary.BigEndian.AppendUint16(out, uint16(len(r)+4))
+			out = append(out, 0, 0)
+			out = append(out, r...)
+		default:
+			out = append(out, r...)
+		}
+	}
+	return out
+}
+
+// decodeRecords splits an uploaded payload into records. ASCII uploads are split
+// on line ends; image uploads are cut at LRECL for fixed formats, parsed by RDW
+// (when SITE RDW is set) or cut at LRECL-4 for variable formats, and cut at
+// BLKSIZE for RECFM=U. Records longer than the dataset allows are wrapped when
+// wrap is set and otherwise truncated; truncated reports whether any was.
+func decodeRecords(a Attrs, data []byte, ascii, rdw, wrap bool) (recs [][]byte, truncated bool) {
+	limit := a.Lrecl
+	switch {
+	case a.isVariable():
+		limit = a.Lrecl - 4
+	case !a.isFixed():
+		limit = a.BlkSize
+	}
+	if ascii {
+		text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+		if text == "" {
+			return nil, false
+		}
+		for _, line := range strings.Split(text, "\n") {
+			for len(line) > limit {
+				if !wrap {
+					truncated = true
+					line = line[:limit]
+					break
+				}
+				recs = append(recs, []byte(line[:limit]))
+				line = line[limit:]
+			}
+			recs = append(recs, []byte(line))
+		}
+		return recs, truncated
+	}
+	if a.isVariable() && rdw {
+		for len(data) >= 4 {
+			n := int(binary.BigEndian.Uint16(data))
+			if n < 4 || n > len(data) {
+				break
+			}
+			recs = append(recs, slices.Clone(data[4:n]))
+			data = data[n:]
+		}
+		return recs, false
+	}
+	for len(data) > 0 {
+		n := min(limit, len(data))
+		recs = append(recs, slices.Clone(data[:n]))
+		data = data[n:]
+	}
+	return recs, false
+}
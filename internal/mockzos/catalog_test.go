@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+)
+
+// TestDatasetLine_ParsesWithHfs renders catalog rows in the z/OS column geometry
+// and feeds them through the hfs parser the client uses, so a drift between the
+// mock's listing and the real format fails here.
+func TestDatasetLine_ParsesWithHfs(t *testing.T) {
+	ref := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	seq := &dataset{name: "HLQ.DATA", attrs: Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 27920}.withDefaults(), referred: ref}
+	lib := &dataset{name: "HLQ.LOADLIB", attrs: Attrs{Recfm: "U", Lrecl: 0, BlkSize: 32760}.withDefaults(), library: true, referred: ref, members: map[string]*member{}}
+
+	parser := hfs.NewDatasetListParser(datasetHeader)
+	for _, tc := range []struct {
+		d                  *dataset
+		recfm, dsorg, name string
+		lrecl, blksz       uint32
+	}{
+		{seq, "FB", "PS", "HLQ.DATA", 80, 27920},
+		{lib, "U", "PO-E", "HLQ.LOADLIB", 256, 32760},
+	} {
+		got, err := parser.Parse(datasetLine(tc.d))
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", datasetLine(tc.d), err)
+		}
+		if got.Name() != tc.name || got.Recfm.String() != tc.recfm || got.Dsorg.String() != tc.dsorg {
+			t.Errorf("parsed %v, want name=%s recfm=%s dsorg=%s", got, tc.name, tc.recfm, tc.dsorg)
+		}
+		if got.Lrecl.Value() != tc.lrecl || got.BlkSz.Value() != tc.blksz {
+			t.Errorf("parsed lrecl=%d blksz=%d, want %d/%d", got.Lrecl.Value(), got.BlkSz.Value(), tc.lrecl, tc.blksz)
+		}
+		if got.Volume.String() != "MOCK01" || got.Used.Value() != 1 {
+			t.Errorf("parsed volume=%q used=%d, want MOCK01/1", got.Volume.String(), got.Used.Value())
+		}
+	}
+}
+
+func TestMemberLine_ParsesWithHfs(t *testing.T) {
+	m := &member{name: "PROG1", records: toRecords([]string{"A", "B", "C"})}
+	m.touch(time.Date(2024, 2, 1, 9, 30, 0, 0, time.UTC), "IBMUSER")
+	m.records = append(m.records, []byte("D"))
+	m.touch(time.Date(2024, 2, 3, 17, 5, 0, 0, time.UTC), "IBMUSER")
+
+	got, err := hfs.ParseInfoPdsMember(memberLine(m))
+	if err != nil {
+		t.Fatalf("ParseInfoPdsMember(%q): %v", memberLine(m), err)
+	}
+	if got.Name.String() != "PROG1" || got.Id.String() != "IBMUSER" {
+		t.Errorf("name/id = %q/%q", got.Name.String(), got.Id.String())
+	}
+	if got.VvMm.String() != "01.01" {
+		t.Errorf("VV.MM = %q, want 01.01", got.VvMm.String())
+	}
+	if got.Size.Value() != 4 || got.Init.Value() != 3 {
+		t.Errorf("size/init = %d/%d, want 4/3", got.Size.Value(), got.Init.Value())
+	}
+	if got.Changed.String() != "2024/02/03 17:05" {
+		t.Errorf("changed = %q", got.Changed.String())
+	}
+}
+
+func TestRecords_RoundTrip(t *testing.T) {
+	fb := Attrs{Recfm: "FB", Lrecl: 8, BlkSize: 800}
+	vb := Attrs{Recfm: "VB", Lrecl: 12, BlkSize: 800}
+
+	t.Run("ascii fixed strips trailing blanks", func(t *testing.T) {
+		recs, trunc := decodeRecords(fb, []byte("AB\r\nCDEF  \r\n"), true, false, false)
+		if trunc || len(recs) != 2 {
+			t.Fatalf("recs=%q trunc=%v", recs, trunc)
+		}
+		if got := encodeRecords(fb, recs, true, false, "\n"); string(got) != "AB\nCDEF\n" {
+			t.Errorf("encoded %q", got)
+		}
+	})
+
+	t.Run("ascii truncates or wraps long lines", func(t *testing.T) {
+		recs, trunc := decodeRecords(fb, []byte("0123456789\n"), true, false, false)
+		if !trunc || len(recs) != 1 || string(recs[0]) != "01234567" {
+			t.Errorf("truncate: recs=%q trunc=%v", recs, trunc)
+		}
+		recs, trunc = decodeRecords(fb, []byte("0123456789\n"), true, false, true)
+		if trunc || len(recs) != 2 || string(recs[1]) != "89" {
+			t.Errorf("wrap: recs=%q trunc=%v", recs, trunc)
+		}
+	})
+
+	t.Run("binary fixed pads to lrecl", func(t *testing.T) {
+		recs, _ := decodeRecords(fb, []byte("0123456789"), false, false, false)
+		if got := encodeRecords(fb, recs, false, false, ""); string(got) != "0123456789      " {
+			t.Errorf("encoded %q", got)
+		}
+	})
+
+	t.Run("binary variable with RDW", func(t *testing.T) {
+		in := []byte{0, 6, 0, 0, 'H', 'I', 0, 5, 0, 0, '!'}
+		recs, _ := decodeRecords(vb, in, false, true, false)
+		if len(recs) != 2 || string(recs[0]) != "HI" || string(recs[1]) != "!" {
+			t.Fatalf("recs=%q", recs)
+		}
+		if got := encodeRecords(vb, recs, false, true, ""); !bytes.Equal(got, in) {
+			t.Errorf("encoded % x, want % x", got, in)
+		}
+		if got := encodeRecords(vb, recs, false, false, ""); string(got) != "HI!" {
+			t.Errorf("encoded without RDW %q", got)
+		}
+	})
+}
+
+func TestDsnPattern(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"HLQ.*", "HLQ.DATA", true},
+		{"HLQ.*", "HLQ.DATA.X", false},
+		{"HLQ.**", "HLQ.DATA.X", true},
+		{"HLQ.D%TA", "HLQ.DATA", true},
+		{"HLQ.D%TA", "HLQ.DTA", false},
+		{"PROG*", "PROG1", true},
+	} {
+		if got := dsnPattern(tc.pattern).MatchString(tc.name); got != tc.want {
+			t.Errorf("dsnPattern(%q).Match(%q) = %v, want %v", tc.pattern, tc.name, got, tc.want)
+		}
+	}
+}
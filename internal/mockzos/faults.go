@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+// Fault is one network misbehavior of a fault profile. Build faults with
+// Latency, Bandwidth, ResetData, DelayCompletion, SplitReplies and
+// DisconnectAfter, and install them together with InjectFaults.
+type Fault func(*faultProfile)
+
+// faultProfile is the set of faults in effect and the seed their random choices
+// derive from.
+type faultProfile struct {
+	seed       uint64
+	latency    map[string][2]time.Duration // full line or verb (upper), "*" for any -> [min, max]
+	bandwidth  int                         // data connection bytes per second; 0 is unlimited
+	resets     map[string]float64          // transfer verb (upper) -> probability of a reset
+	delayDone  map[string]time.Duration    // transfer verb (upper) -> pause before the closing reply
+	split      bool                        // write replies in several TCP segments
+	disconnect int                         // reply 421 and hang up after this many commands; 0 is never
+}
+
+// resetWindow bounds the random offset at which ResetData aborts an upload,
+// whose length the server cannot know in advance.
+const resetWindow = 64 << 10
+
+// Latency delays the reply to a command by a random duration in [min, max]. The
+// key is a full command line or a verb, like Script, or "*" for every command.
+//
+//	srv.InjectFaults(7, mockzos.Latency("RETR", 50*time.Millisecond, 200*time.Millisecond))
+func Latency(command string, min, max time.Duration) Fault {
+	return func(p *faultProfile) {
+		p.latency[strings.ToUpper(strings.TrimSpace(command))] = [2]time.Duration{min, max}
+	}
+}
+
+// Bandwidth caps every data connection, in both directions, at bytesPerSecond.
+func Bandwidth(bytesPerSecond int) Fault {
+	return func(p *faultProfile) { p.bandwidth = bytesPerSecond }
+}
+
+// ResetData makes a transfer of verb (RETR/LIST/NLST or STOR/STOU/APPE) abort its
+// data connection with a TCP RST with the given probability. A download is cut
+// at a random offset of its payload; an upload after a random number of bytes
+// within the first 64 KiB. The control connection then reports 426.
+func ResetData(verb string, probability float64) Fault {
+	return func(p *faultProfile) { p.resets[strings.ToUpper(verb)] = probability }
+}
+
+// DelayCompletion holds back the closing reply (250, or 226 when scripted with
+// CompletionReply) of a transfer of verb for d after the data connection is
+// closed, so the client's wait for the final reply is exercised.
+func DelayCompletion(verb string, d time.Duration) Fault {
+	return func(p *faultProfile) { p.delayDone[strings.ToUpper(verb)] = d }
+}
+
+// SplitReplies writes each control reply in several TCP segments cut at random
+// points, so a multiline reply (and even a single line) arrives in pieces.
+func SplitReplies() Fault {
+	return func(p *faultProfile) { p.split = true }
+}
+
+// DisconnectAfter makes each control connection answer its (n+1)th command with
+// "421 Service not available, closing control connection." and hang up.
+func DisconnectAfter(n int) Fault {
+	return func(p *faultProfile) { p.disconnect = n }
+}
+
+// InjectFaults replaces the server's fault profile with faults. Every random
+// choice a fault makes is drawn from a generator seeded with seed and the
+// connection's sequence number, so a failing run reproduces with the same seed.
+// InjectFaults with no faults clears the profile.
+func (s *Server) InjectFaults(seed int64, faults ...Fault) {
+	var p *faultProfile
+	if len(faults) > 0 {
+		p = &faultProfile{
+			seed:      uint64(seed),
+			latency:   map[string][2]time.Duration{},
+			resets:    map[string]float64{},
+			delayDone: map[string]time.Duration{},
+		}
+		for _, f := range faults {
+			f(p)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = p
+}
+
+// faultsFor returns the fault profile in effect and the session's generator for
+// it, or nil when no profile is installed. The generator is reseeded whenever
+// the profile changes.
+func (s *Server) faultsFor(sess *session) (*faultProfile, *rand.Rand) {
+	s.mu.Lock()
+	p := s.faults
+	s.mu.Unlock()
+	if p == nil {
+		return nil, nil
+	}
+	if sess.rngFor != p {
+		sess.rng = rand.New(rand.NewPCG(p.seed, sess.id))
+		sess.rngFor = p
+	}
+	return p, sess.rng
+}
+
+// commandFaults applies the per-command faults before a command is dispatched.
+// It reports true when the session must end (DisconnectAfter).
+func (s *Server) commandFaults(sess *session, line, verb string) bool {
+	p, rng := s.faultsFor(sess)
+	if p == nil {
+		return false
+	}
+	if p.disconnect > 0 && sess.commands > p.disconnect {
+		writeLines(sess.conn, []string{"421 Service not available, closing control connection."})
+		return true
+	}
+	lat, ok := p.latency[strings.ToUpper(strings.TrimSpace(line))]
+	if !ok {
+		lat, ok = p.latency[verb]
+	}
+	if !ok {
+		lat, ok = p.latency["*"]
+	}
+	if ok {
+		time.Sleep(between(rng, lat[0], lat[1]))
+	}
+	return false
+}
+
+func between(rng *rand.Rand, lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rng.Int64N(int64(hi-lo)+1))
+}
+
+// resetOffset reports whether this transfer of verb is to be reset and, if so,
+// after how many bytes (limit is the payload size, or 0 for an upload).
+func (s *Server) resetOffset(sess *session, verb string, limit int) (int, bool) {
+	p, rng := s.faultsFor(sess)
+	if p == nil {
+		return 0, false
+	}
+	prob, ok := p.resets[verb]
+	if !ok || rng.Float64() >= prob {
+		return 0, false
+	}
+	if limit <= 0 {
+		limit = resetWindow
+	}
+	return rng.IntN(limit), true
+}
+
+// delayCompletion sleeps for the DelayCompletion of verb, if any.
+func (s *Server) delayCompletion(sess *session, verb string) {
+	if p, _ := s.faultsFor(sess); p != nil {
+		time.Sleep(p.delayDone[verb])
+	}
+}
+
+// reset aborts a data connection with a RST (SO_LINGER 0) instead of a FIN.
+func reset(dc net.Conn) {
+	if t, ok := dc.(*throttledConn); ok {
+		dc = t.Conn
+	}
+	if t, ok := dc.(*protectedConn); ok {
+		dc = t.raw
+	}
+	if tcp, ok := dc.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = dc.Close()
+}
+
+// replyConn is the control connection as the session writes to it. Under a
+// SplitReplies profile each write leaves in several segments.
+type replyConn struct {
+	net.Conn
+	s    *Server
+	sess *session
+}
+
+func (c *replyConn) Write(b []byte) (int, error) {
+	p, rng := c.s.faultsFor(c.sess)
+	if p == nil || !p.split || len(b) < 2 {
+		return c.Conn.Write(b)
+	}
+	written := 0
+	for len(b) > 0 {
+		n := 1 + rng.IntN(len(b))
+		w, err := c.Conn.Write(b[:n])
+		written += w
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+		if len(b) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return written, nil
+}
+
+// throttledConn caps a data connection at bps bytes per second by moving the
+// data in twentieth-of-a-second slices.
+type throttledConn struct {
+	net.Conn
+	bps int
+}
+
+func (c *throttledConn) slice() int { return max(c.bps/20, 1) }
+
+func (c *throttledConn) pause(n int) {
+	time.Sleep(time.Duration(n) * time.Second / time.Duration(c.bps))
+}
+
+func (c *throttledConn) Write(b []byte) (int, error) {
+	written := 0
+	for len(b) > 0 {
+		n := min(len(b), c.slice())
+		w, err := c.Conn.Write(b[:n])
+		written += w
+		if err != nil {
+			return written, err
+		}
+		c.pause(w)
+		b = b[n:]
+	}
+	return written, nil
+}
+
+func (c *throttledConn) Read(b []byte) (int, error) {
+	if len(b) > c.slice() {
+		b = b[:c.slice()]
+	}
+	n, err := c.Conn.Read(b)
+	c.pause(n)
+	return n, err
+}
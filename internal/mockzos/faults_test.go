@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaults_DisconnectAfter(t *testing.T) {
+	s := New(t)
+	s.InjectFaults(1, DisconnectAfter(2))
+	c, r := dial(t, s)
+	readReply(t, r, "220")
+	send(t, c, "USER me")
+	readReply(t, r, "331")
+	send(t, c, "PASS pw")
+	readReply(t, r, "230")
+	send(t, c, "SYST")
+	readReply(t, r, "421")
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Error("control connection still open after 421")
+	}
+}
+
+func TestFaults_SplitRepliesKeepContent(t *testing.T) {
+	s := New(t)
+	s.Script("STAT", "211-first line", "211-second line", "211 end")
+	s.InjectFaults(3, SplitReplies(), Latency("STAT", 10*time.Millisecond, 20*time.Millisecond))
+	c, r := dial(t, s)
+	readReply(t, r, "220")
+	start := time.Now()
+	send(t, c, "STAT")
+	var got []string
+	for _, code := range []string{"211-", "211-", "211 "} {
+		got = append(got, readReply(t, r, code))
+	}
+	if strings.Join(got, "|") != "211-first line|211-second line|211 end" {
+		t.Errorf("reply = %q", got)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Errorf("STAT answered in %v, want at least the 10ms latency", time.Since(start))
+	}
+}
+
+// TestFaults_SeedReproduces checks that the random choices of a profile depend
+// only on the seed and the connection's sequence number.
+func TestFaults_SeedReproduces(t *testing.T) {
+	draw := func(seed int64) []int {
+		s := New(t)
+		s.InjectFaults(seed, ResetData("RETR", 1))
+		sess := &session{id: 1}
+		var offs []int
+		for range 5 {
+			off, ok := s.resetOffset(sess, "RETR", 1000)
+			if !ok {
+				t.Fatal("probability 1 did not reset")
+			}
+			offs = append(offs, off)
+		}
+		return offs
+	}
+	a, b, c := draw(42), draw(42), draw(43)
+	if !slices.Equal(a, b) {
+		t.Errorf("same seed drew %v and %v", a, b)
+	}
+	if slices.Equal(a, c) {
+		t.Errorf("different seeds drew the same offsets %v", a)
+	}
+}
@@ -0,0 +1,649 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Job phases, as reported in the STATUS column of a JES listing.
+const (
+	jobInput  = "INPUT"
+	jobActive = "ACTIVE"
+	jobOutput = "OUTPUT"
+)
+
+// endOfSpoolFile separates spool files when a whole job's output is retrieved
+// (RETR JOBnnnnn.x or a submit-and-wait RETR of a dataset).
+const endOfSpoolFile = " !! END OF JES SPOOL FILE !!"
+
+// spoolFile is one JES spool dataset of a job: the JES2 system files or a
+// SYSOUT DD of a step.
+type spoolFile struct {
+	step     string
+	procstep string
+	class    string
+	ddname   string
+	records  []string
+}
+
+func (f spoolFile) bytes() int {
+	n := 0
+	for _, r := range f.records {
+		n += len(r)
+	}
+	return n
+}
+
+// job is a job submitted to the mock's JES spool. Its phase is derived from the
+// server clock: it sits on the input queue for the configured input time, runs
+// for the active time, and is on the output queue afterwards.
+type job struct {
+	id         string
+	name       string
+	owner      string
+	class      string
+	completion string // "RC=0000", "ABEND=S0C4" or "JCL error"
+	submitted  time.Time
+	input      time.Duration
+	active     time.Duration
+	files      []spoolFile
+}
+
+func (j *job) phase(now time.Time) string {
+	switch {
+	case now.Before(j.submitted.Add(j.input)):
+		return jobInput
+	case now.Before(j.submitted.Add(j.input + j.active)):
+		return jobActive
+	default:
+		return jobOutput
+	}
+}
+
+// spool returns the spool files visible in the job's current phase: none while
+// it waits for an initiator, and all of them once it has run.
+func (j *job) spool(now time.Time) []spoolFile {
+	if j.phase(now) != jobOutput {
+		return nil
+	}
+	return j.files
+}
+
+// jes is the JES2 side of the virtual system: the spool, the job-number counter,
+// the per-job-name completion overrides and the queue timings.
+type jes struct {
+	jobs        map[string]*job
+	next        int
+	completions map[string]string // job name -> completion
+	input       time.Duration
+	active      time.Duration
+}
+
+func newJES() *jes {
+	return &jes{jobs: map[string]*job{}, next: 1, completions: map[string]string{}}
+}
+
+var (
+	jobIDPattern    = regexp.MustCompile(`^(?:JOB|STC|TSU)\d{5}$`)
+	spoolIDPattern  = regexp.MustCompile(`^((?:JOB|STC|TSU)\d{5})\.(\d+|X)$`)
+	jclClassPattern = regexp.MustCompile(`(?:^|,)CLASS=(\w)`)
+	jclMsgPattern   = regexp.MustCompile(`(?:^|,)MSGCLASS=(\w)`)
+	jclPgmPattern   = regexp.MustCompile(`(?:^|,)PGM=([\w@#$]+)`)
+	jclSysoutRegexp = regexp.MustCompile(`(?:^|,)SYSOUT=\(?([\w*])`)
+)
+
+// SetClock replaces the clock the stateful mode reads (time.Now by default). It
+// stamps catalog entries and drives JES jobs through INPUT, ACTIVE and OUTPUT,
+// so a test can step a job's lifecycle deterministically. A nil now restores
+// the wall clock.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = now
+}
+
+// now returns the server's current time; stateful entries are stamped with it.
+func (s *Server) now() time.Time {
+	s.mu.Lock()
+	clock := s.clock
+	s.mu.Unlock()
+	if clock == nil {
+		return time.Now()
+	}
+	return clock()
+}
+
+// JESTiming sets how long jobs submitted from now on wait on the input queue
+// and then run before reaching the output queue. Both default to zero, so a job
+// is complete as soon as it is submitted. It enables stateful mode.
+func (s *Server) JESTiming(input, active time.Duration) {
+	s.EnableState()
+	c := s.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jes.input, c.jes.active = input, active
+}
+
+// JobCompletion sets how jobs named jobName end from now on: "RC=nnnn" (the
+// default is "RC=0000"), "ABEND=Scde"/"ABEND=Unnnn", or "JCL error". The spool
+// and the listings both reflect it. It enables stateful mode.
+//
+//	srv.JobCompletion("PAYROLL", "ABEND=S0C7")
+func (s *Server) JobCompletion(jobName, completion string) {
+	s.EnableState()
+	c := s.state()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.jes.completions[strings.ToUpper(jobName)] = completion
+}
+
+// SubmitJob places jcl on the spool as if owner had submitted it and returns
+// the assigned job id. It fails the test when the JCL has no JOB statement.
+func (s *Server) SubmitJob(owner, jcl string) string {
+	s.tb.Helper()
+	s.EnableState()
+	j, err := s.submit(s.state(), strings.ToUpper(owner), strings.Split(strings.ReplaceAll(jcl, "\r\n", "\n"), "\n"))
+	if err != nil {
+		s.tb.Fatalf("mockzos: SubmitJob: %v", err)
+		return ""
+	}
+	return j.id
+}
+
+// JobStatus reports the phase (INPUT, ACTIVE or OUTPUT) of a job on the spool.
+func (s *Server) JobStatus(id string) (string, bool) {
+	c := s.state()
+	if c == nil {
+		return "", false
+	}
+	now := s.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	j, ok := c.jes.jobs[strings.ToUpper(id)]
+	if !ok {
+		return "", false
+	}
+	return j.phase(now), true
+}
+
+// submit interprets JCL, builds the job's spool, and queues it.
+func (s *Server) submit(c *catalog, owner string, jcl []string) (*job, error) {
+	now := s.now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j := &job{owner: owner, class: "A", submitted: now, input: c.jes.input, active: c.jes.active}
+	st, err := parseJCL(jcl)
+	if err != nil {
+		return nil, err
+	}
+	j.name, j.class = st.name, st.class
+	j.id = fmt.Sprintf("JOB%05d", c.jes.next)
+	c.jes.next++
+	j.completion = "RC=0000"
+	if cc, ok := c.jes.completions[j.name]; ok {
+		j.completion = cc
+	}
+	j.files = st.spool(j)
+	c.jes.jobs[j.id] = j
+	return j, nil
+}
+
+// jclJob is what the mock understands of a job stream: the JOB statement, the
+// EXEC steps and their SYSOUT and in-stream DDs.
+type jclJob struct {
+	name     string
+	class    string
+	msgclass string
+	jcl      []string
+	steps    []jclStep
+}
+
+type jclStep struct {
+	name     string
+	pgm      string
+	sysout   []spoolFile
+	instream map[string][]string
+}
+
+// parseJCL reads the JOB, EXEC and DD statements the spool is built from.
+// Anything it does not model (continuations, procedures, symbols) is echoed in
+// JESJCL but otherwise ignored.
+func parseJCL(lines []string) (*jclJob, error) {
+	jj := &jclJob{class: "A", msgclass: "A"}
+	var dd string // in-stream DD being read, if any
+	var step *jclStep
+	for _, raw := range lines {
+		line := strings.TrimRight(raw, "\r ")
+		if line == "" && dd == "" {
+			continue
+		}
+		if dd != "" {
+			if !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "/*") {
+				step.instream[dd] = append(step.instream[dd], line)
+				continue
+			}
+			dd = ""
+			if strings.HasPrefix(line, "/*") {
+				continue
+			}
+		}
+		jj.jcl = append(jj.jcl, line)
+		if !strings.HasPrefix(line, "//") || strings.HasPrefix(line, "//*") {
+			continue
+		}
+		name, op, operands := jclFields(line[2:])
+		switch op {
+		case "JOB":
+			if jj.name != "" {
+				continue
+			}
+			jj.name = name
+			if m := jclClassPattern.FindStringSubmatch(operands); m != nil {
+				jj.class = m[1]
+			}
+			if m := jclMsgPattern.FindStringSubmatch(operands); m != nil {
+				jj.msgclass = m[1]
+			}
+		case "EXEC":
+			if name == "" {
+				name = fmt.Sprintf("STEP%d", len(jj.steps)+1)
+			}
+			jj.steps = append(jj.steps, jclStep{name: name, instream: map[string][]string{}})
+			step = &jj.steps[len(jj.steps)-1]
+			if m := jclPgmPattern.FindStringSubmatch(operands); m != nil {
+				step.pgm = m[1]
+			}
+		case "DD":
+			if step == nil || name == "" {
+				continue
+			}
+			if m := jclSysoutRegexp.FindStringSubmatch(operands); m != nil {
+				class := m[1]
+				if class == "*" {
+					class = jj.msgclass
+				}
+				step.sysout = append(step.sysout, spoolFile{step: step.name, class: class, ddname: name})
+			}
+			if operands == "*" || operands == "DATA" || strings.HasPrefix(operands, "*,") || strings.HasPrefix(operands, "DATA,") {
+				dd = name
+			}
+		}
+	}
+	if jj.name == "" {
+		return nil, fmt.Errorf("no JOB statement found")
+	}
+	return jj, nil
+}
+
+// jclFields splits a JCL statement (without its leading "//") into its name,
+// operation and operand fields.
+func jclFields(stmt string) (name, op, operands string) {
+	if !strings.HasPrefix(stmt, " ") {
+		name, stmt, _ = strings.Cut(stmt, " ")
+	}
+	f := strings.Fields(stmt)
+	if len(f) > 0 {
+		op = f[0]
+	}
+	if len(f) > 1 {
+		operands = f[1]
+	}
+	return strings.ToUpper(name), strings.ToUpper(op), operands
+}
+
+// spool builds the job's spool: the JES2 job log, the JCL listing and the
+// system messages, followed by each step's SYSOUT DDs. IEBGENER copies its
+// in-stream SYSUT1 to a SYSOUT SYSUT2; other programs write nothing.
+func (jj *jclJob) spool(j *job) []spoolFile {
+	start := j.submitted.Add(j.input)
+	end := start.Add(j.active)
+	stamp := func(t time.Time, text string) string {
+		return fmt.Sprintf(" %s %s  %s", t.Format("15.04.05"), j.id, text)
+	}
+	jclError := strings.EqualFold(j.completion, "JCL error")
+
+	msglg := []string{
+		"1                    J E S 2  J O B  L O G  --  S Y S T E M  M O C K  --  N O D E  M O C K Z O S",
+		"0",
+		fmt.Sprintf(" %s %s ---- %s ----", start.Format("15.04.05"), j.id, strings.ToUpper(start.Format("Monday, 02 Jan 2006"))),
+	}
+	var sysmsg []string
+	if jclError {
+		msglg = append(msglg, stamp(start, fmt.Sprintf("IEFC452I %s - JOB NOT RUN - JCL ERROR", j.name)))
+		sysmsg = append(sysmsg, fmt.Sprintf("IEFC452I %s - JOB NOT RUN - JCL ERROR", j.name))
+	} else {
+		msglg = append(msglg, stamp(start, fmt.Sprintf("$HASP373 %-8s STARTED - INIT 1    - CLASS %s        - SYS MOCK", j.name, j.class)))
+		for i, st := range jj.steps {
+			cc := "0000"
+			last := i == len(jj.steps)-1
+			sysmsg = append(sysmsg, fmt.Sprintf("IEF236I ALLOC. FOR %s %s", j.name, st.name))
+			switch {
+			case last && strings.HasPrefix(j.completion, "ABEND"):
+				code := strings.TrimPrefix(strings.TrimPrefix(j.completion, "ABEND"), "=")
+				line := fmt.Sprintf("IEF450I %s %s - ABEND=%s U0000 REASON=00000000", j.name, st.name, code)
+				if strings.HasPrefix(code, "U") {
+					line = fmt.Sprintf("IEF450I %s %s - ABEND=S000 %s REASON=00000000", j.name, st.name, code)
+				}
+				msglg = append(msglg, stamp(end, line))
+				sysmsg = append(sysmsg, line)
+			default:
+				if last {
+					cc = strings.TrimPrefix(j.completion, "RC=")
+				}
+				sysmsg = append(sysmsg, fmt.Sprintf("IEF142I %s %s - STEP WAS EXECUTED - COND CODE %s", j.name, st.name, cc))
+			}
+			sysmsg = append(sysmsg,
+				fmt.Sprintf("IEF373I STEP/%-8s/START %s", st.name, start.Format("2006002.1504")),
+				fmt.Sprintf("IEF374I STEP/%-8s/STOP  %s CPU    0MIN 00.01SEC SRB    0MIN 00.00SEC VIRT   4K SYS   244K EXT       0K SYS   10780K", st.name, end.Format("2006002.1504")))
+		}
+	}
+	if jclError {
+		msglg = append(msglg, stamp(end, fmt.Sprintf("$HASP395 %-8s ENDED", j.name)))
+	} else {
+		msglg = append(msglg, stamp(end, fmt.Sprintf("$HASP395 %-8s ENDED - %s", j.name, j.completion)))
+	}
+
+	jcl := make([]string, len(jj.jcl))
+	for i, l := range jj.jcl {
+		jcl[i] = fmt.Sprintf("%10d %s", i+1, l)
+	}
+
+	files := []spoolFile{
+		{step: "JES2", class: jj.msgclass, ddname: "JESMSGLG", records: msglg},
+		{step: "JES2", class: jj.msgclass, ddname: "JESJCL", records: jcl},
+		{step: "JES2", class: jj.msgclass, ddname: "JESYSMSG", records: sysmsg},
+	}
+	if jclError {
+		return files
+	}
+	for _, st := range jj.steps {
+		for _, f := range st.sysout {
+			if st.pgm == "IEBGENER" && f.ddname == "SYSUT2" {
+				f.records = st.instream["SYSUT1"]
+			}
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// jesCommand handles the commands whose meaning changes under SITE
+// FILETYPE=JES: STOR submits, LIST/NLST show the spool, RETR fetches spool
+// files (or submits a cataloged dataset and waits for it), and DELE purges.
+func (s *Server) jesCommand(c *catalog, sess *session, verb, arg string) bool {
+	switch verb {
+	case "STOR":
+		s.jesSubmit(c, sess, arg)
+	case "LIST", "NLST":
+		s.jesList(c, sess, verb, arg)
+	case "RETR":
+		s.jesRetr(c, sess, arg)
+	case "DELE":
+		s.jesPurge(c, sess, arg)
+	default:
+		return false
+	}
+	return true
+}
+
+func (s *Server) jesSubmit(c *catalog, sess *session, arg string) {
+	data, ok := s.receiveUpload(sess, "STOR", arg)
+	if !ok {
+		return
+	}
+	j, err := s.submit(c, sess.user, strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"))
+	if err != nil {
+		writeLines(sess.conn, []string{"550 Job not submitted: " + err.Error()})
+		return
+	}
+	writeLines(sess.conn, []string{"250-It is known to JES as " + j.id, "250 Transfer completed successfully."})
+}
+
+// jesFilter returns the session's JESJOBNAME, JESOWNER and JESSTATUS filters,
+// defaulting (as z/OS does) to the user's own jobs in any status.
+func (sess *session) jesFilter() (name, owner, status string) {
+	name, owner, status = sess.user+"*", sess.user, "ALL"
+	if v, ok := sess.site.other["JESJOBNAME"]; ok && v != "" {
+		name = v
+	}
+	if v, ok := sess.site.other["JESOWNER"]; ok && v != "" {
+		owner = v
+	}
+	if v, ok := sess.site.other["JESSTATUS"]; ok && v != "" {
+		status = v
+	}
+	return name, owner, status
+}
+
+func (sess *session) jesLevel() int {
+	if sess.site.other["JESINTERFACELEVEL"] == "1" {
+		return 1
+	}
+	return 2
+}
+
+// jesList renders the spool the way the z/OS server does for the session's JES
+// interface level: a job table filtered by JESJOBNAME/JESOWNER/JESSTATUS, or,
+// for a job id, that job's row followed (at level 2) by its spool-file detail.
+func (s *Server) jesList(c *catalog, sess *session, verb, arg string) {
+	arg = strings.ToUpper(strings.TrimSpace(arg))
+	level := sess.jesLevel()
+	now := s.now()
+	c.mu.Lock()
+	var lines []string
+	if jobIDPattern.MatchString(arg) {
+		j, ok := c.jes.jobs[arg]
+		if !ok {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 No jobs found on Held queue"})
+			return
+		}
+		if verb == "NLST" {
+			for i := range j.spool(now) {
+				lines = append(lines, fmt.Sprintf("%s.%d", j.id, i+1))
+			}
+		} else {
+			lines = jobDetail(j, now, level)
+		}
+	} else {
+		name, owner, status := sess.jesFilter()
+		if arg != "" && arg != "*" {
+			name = arg
+		}
+		for _, j := range c.jes.sorted() {
+			if !jesMatch(name, j.name) || !jesMatch(owner, j.owner) || status != "ALL" && status != j.phase(now) {
+				continue
+			}
+			lines = append(lines, pick(verb == "NLST", j.id, jobLine(j, now, level)))
+		}
+		if len(lines) == 0 {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 No jobs found on Held queue"})
+			return
+		}
+		if verb == "LIST" && level == 2 {
+			lines = append([]string{jobHeader}, lines...)
+		}
+	}
+	c.mu.Unlock()
+
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\r\n")
+	}
+	s.sendDownload(sess, verb, b.String())
+}
+
+func jesMatch(pattern, name string) bool {
+	ok, err := path.Match(strings.ToUpper(pattern), name)
+	return err == nil && ok
+}
+
+// sorted returns the jobs on the spool, newest first as JES lists them.
+func (q *jes) sorted() []*job {
+	out := make([]*job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		out = append(out, j)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].id > out[b].id })
+	return out
+}
+
+const (
+	jobHeader    = "JOBNAME  JOBID    OWNER    STATUS CLASS"
+	detailHeader = "         ID  STEPNAME PROCSTEP C DDNAME   BYTE-COUNT"
+)
+
+// jobLine renders one job row at the given JES interface level. A job on the
+// output queue carries its completion and spool-file count.
+func jobLine(j *job, now time.Time, level int) string {
+	phase := j.phase(now)
+	if level == 1 {
+		return fmt.Sprintf("%-8s %-8s %-7s %d spool files", j.name, j.id, phase, len(j.spool(now)))
+	}
+	if phase != jobOutput {
+		return fmt.Sprintf("%-8s %-8s %-8s %-6s %s", j.name, j.id, j.owner, phase, j.class)
+	}
+	return fmt.Sprintf("%-8s %-8s %-8s %-6s %-8s %s %d spool files", j.name, j.id, j.owner, phase, j.class, j.completion, len(j.files))
+}
+
+// jobDetail renders LIST JOBnnnnn: at level 2 the job row, then (once the job
+// has output) a separator, the per-DD detail and the spool-file count.
+func jobDetail(j *job, now time.Time, level int) []string {
+	if level == 1 {
+		return []string{jobLine(j, now, level)}
+	}
+	phase := j.phase(now)
+	row := fmt.Sprintf("%-8s %-8s %-8s %-6s %s", j.name, j.id, j.owner, phase, j.class)
+	if phase == jobOutput {
+		row = fmt.Sprintf("%-8s %-8s %-8s %-6s %-8s %s", j.name, j.id, j.owner, phase, j.class, j.completion)
+	}
+	lines := []string{jobHeader, row}
+	files := j.spool(now)
+	if len(files) == 0 {
+		return lines
+	}
+	lines = append(lines, "--------", detailHeader)
+	for i, f := range files {
+		procstep := "   N/A  "
+		if f.procstep != "" {
+			procstep = fmt.Sprintf("%-8s", f.procstep)
+		}
+		lines = append(lines, fmt.Sprintf("         %03d %-8s %s %s %-8s %10d", i+1, f.step, procstep, f.class, f.ddname, f.bytes()))
+	}
+	return append(lines, fmt.Sprintf("%d spool files", len(files)))
+}
+
+// jesRetr serves JOBnnnnn.n (one spool file) or JOBnnnnn.x (all of them). Any
+// other name is a cataloged dataset to submit: the reply then waits, on the
+// server clock and within JESPUTGETTO seconds of real time, for the job to reach
+// the output queue, and returns its whole spool.
+func (s *Server) jesRetr(c *catalog, sess *session, arg string) {
+	if m := spoolIDPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(arg))); m != nil {
+		now := s.now()
+		c.mu.Lock()
+		j, ok := c.jes.jobs[m[1]]
+		var files []spoolFile
+		if ok {
+			files = j.spool(now)
+			if m[2] != "X" {
+				n, _ := strconv.Atoi(m[2])
+				if n < 1 || n > len(files) {
+					files = nil
+				} else {
+					files = files[n-1 : n]
+				}
+			}
+		}
+		c.mu.Unlock()
+		if len(files) == 0 {
+			writeLines(sess.conn, []string{"550 No spool files available for " + m[1] + "."})
+			return
+		}
+		s.sendDownload(sess, "RETR", spoolText(files, m[2] == "X", sess.site.eol))
+		return
+	}
+
+	t := sess.resolve(arg)
+	c.mu.Lock()
+	ds, ok := c.datasets[t.dsn]
+	var recs [][]byte
+	if ok && t.member != "" {
+		if m, mok := ds.members[t.member]; mok {
+			recs = m.records
+		} else {
+			ok = false
+		}
+	} else if ok {
+		recs = ds.records
+	}
+	c.mu.Unlock()
+	if t.uss || !ok {
+		writeLines(sess.conn, []string{"550 Request nonexistent data set or member " + t.String()})
+		return
+	}
+	jcl := make([]string, len(recs))
+	for i, r := range recs {
+		jcl[i] = string(r)
+	}
+	j, err := s.submit(c, sess.user, jcl)
+	if err != nil {
+		writeLines(sess.conn, []string{"550 Job not submitted: " + err.Error()})
+		return
+	}
+
+	timeout := 600 * time.Second
+	if n, err := strconv.Atoi(sess.site.other["JESPUTGETTO"]); err == nil {
+		timeout = time.Duration(n) * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	for j.phase(s.now()) != jobOutput {
+		if time.Now().After(deadline) || s.closed.Load() {
+			writeLines(sess.conn, []string{"550 JESPUTGETTO interval expired for " + j.id})
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	s.sendDownloadReplies(sess, "RETR", spoolText(j.files, true, sess.site.eol),
+		[]string{"125-Submitting job outlined in " + t.String() + " FIFO write", "125 When " + j.id + " is done, will retrieve its output"},
+		[]string{"250-It is known to JES as " + j.id, "250 Transfer completed successfully."})
+}
+
+// spoolText renders spool files as an ASCII transfer. When all files are sent
+// each is followed by the end-of-file marker line.
+func spoolText(files []spoolFile, marked bool, eol string) string {
+	var b strings.Builder
+	for _, f := range files {
+		for _, r := range f.records {
+			b.WriteString(r)
+			b.WriteString(eol)
+		}
+		if marked {
+			b.WriteString(endOfSpoolFile)
+			b.WriteString(eol)
+		}
+	}
+	return b.String()
+}
+
+func (s *Server) jesPurge(c *catalog, sess *session, arg string) {
+	id := strings.ToUpper(strings.TrimSpace(arg))
+	c.mu.Lock()
+	_, ok := c.jes.jobs[id]
+	delete(c.jes.jobs, id)
+	c.mu.Unlock()
+	if !ok {
+		writeLines(sess.conn, []string{"550 " + id + " not found"})
+		return
+	}
+	writeLines(sess.conn, []string{"250 Cancel successful"})
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+)
+
+const genJCL = `//COPY JOB (ACCT),'TEST',CLASS=B,MSGCLASS=X
+//STEP1 EXEC PGM=IEBGENER
+//SYSPRINT DD SYSOUT=*
+//SYSUT1 DD *
+HELLO
+WORLD
+/*
+//SYSUT2 DD SYSOUT=*
+//SYSIN DD DUMMY
+`
+
+func TestParseJCL_IEBGENERCopiesInstream(t *testing.T) {
+	jj, err := parseJCL(splitLines(genJCL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jj.name != "COPY" || jj.class != "B" || jj.msgclass != "X" {
+		t.Errorf("job = %s class=%s msgclass=%s", jj.name, jj.class, jj.msgclass)
+	}
+	j := &job{id: "JOB00001", name: jj.name, class: jj.class, completion: "RC=0000"}
+	files := jj.spool(j)
+	var dds []string
+	for _, f := range files {
+		dds = append(dds, f.ddname)
+	}
+	if want := []string{"JESMSGLG", "JESJCL", "JESYSMSG", "SYSPRINT", "SYSUT2"}; !slices.Equal(dds, want) {
+		t.Fatalf("dds = %v, want %v", dds, want)
+	}
+	if got := files[4].records; !slices.Equal(got, []string{"HELLO", "WORLD"}) {
+		t.Errorf("SYSUT2 = %q", got)
+	}
+	if files[4].class != "X" || files[4].step != "STEP1" {
+		t.Errorf("SYSUT2 class/step = %s/%s", files[4].class, files[4].step)
+	}
+	if _, err := parseJCL([]string{"//S1 EXEC PGM=IEFBR14"}); err == nil {
+		t.Error("JCL without a JOB statement was accepted")
+	}
+}
+
+// TestJobListing_ParsesWithHfs feeds the rendered level 1 and level 2 spool
+// listings through the hfs parsers the client uses.
+func TestJobListing_ParsesWithHfs(t *testing.T) {
+	jj, err := parseJCL(splitLines(genJCL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	done := &job{id: "JOB00002", name: "COPY", owner: "ME", class: "B", completion: "ABEND=S0C4", submitted: sub}
+	done.files = jj.spool(done)
+	running := &job{id: "JOB00003", name: "COPY", owner: "ME", class: "B", completion: "RC=0000", submitted: sub, active: time.Hour}
+	now := sub.Add(time.Minute)
+
+	for _, level := range []int{1, 2} {
+		lines := []string{jobLine(running, now, level), jobLine(done, now, level)}
+		if level == 2 {
+			lines = append([]string{jobHeader}, lines...)
+		}
+		jobs, err := hfs.ParseInfoJob(lines)
+		if err != nil {
+			t.Fatalf("level %d: ParseInfoJob(%q): %v", level, lines, err)
+		}
+		if len(jobs) != 2 || jobs[0].Status.String() != "ACTIVE" || jobs[1].Status.String() != "OUTPUT" {
+			t.Errorf("level %d: jobs = %v", level, jobs)
+		}
+	}
+
+	jd, err := hfs.ParseInfoJobDetail(jobDetail(done, now, 2))
+	if err != nil {
+		t.Fatalf("ParseInfoJobDetail: %v", err)
+	}
+	if code, ok := jd.AbendCode(); !ok || code != "S0C4" {
+		t.Errorf("AbendCode = %q, %v", code, ok)
+	}
+	if len(jd.Detail()) != 5 || jd.Detail()[4].DDName.String() != "SYSUT2" || jd.Detail()[4].ByteCount.Value() != 10 {
+		t.Errorf("detail = %v", jd.Detail())
+	}
+	if _, err := hfs.ParseInfoJobDetail(jobDetail(running, now, 2)); err != hfs.ErrActiveJob {
+		t.Errorf("active job detail err = %v, want ErrActiveJob", err)
+	}
+}
+
+func splitLines(s string) []string {
+	var out []string
+	for l := range strings.Lines(s) {
+		out = append(out, strings.TrimSuffix(l, "\n"))
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+)
+
+// Block descriptor flags of MODE B (RFC 959), also the escape descriptors of
+// MODE C.
+const (
+	blockEOR     = 0x80
+	blockEOF     = 0x40
+	blockRestart = 0x10
+)
+
+// handleMode answers MODE: stream, block and compressed are supported, as on
+// z/OS. Script "MODE C" to model a server that rejects compression.
+func (s *Server) handleMode(sess *session, arg string) {
+	m := strings.ToUpper(strings.TrimSpace(arg))
+	switch m {
+	case "S", "B", "C":
+		sess.mode = m
+		writeLines(sess.conn, []string{"200 Data transfer mode is " + map[string]string{"S": "Stream", "B": "Block", "C": "Compressed"}[m]})
+	default:
+		writeLines(sess.conn, []string{"504 Data transfer mode " + arg + " not supported."})
+	}
+}
+
+// appendBlock appends one block, split at the 16-bit count limit so only the
+// last piece carries desc.
+func appendBlock(out []byte, desc byte, data []byte) []byte {
+	for len(data) > 0xFFFF {
+		out = append(out, 0, 0xFF, 0xFF)
+		out = append(out, data[:0xFFFF]...)
+		data = data[0xFFFF:]
+	}
+	out = append(out, desc)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(data)))
+	return append(out, data...)
+}
+
+// blockRecords frames recs as MODE B records, skipping the first skip of them
+// (a REST marker), with a restart marker after every interval records (none
+// when interval is 0) and a final EOF block. A marker is the number of records
+// sent before it.
+func blockRecords(recs [][]byte, skip, interval int) []byte {
+	var out []byte
+	for i := skip; i < len(recs); i++ {
+		out = appendBlock(out, blockEOR, recs[i])
+		if interval > 0 && (i+1)%interval == 0 && i+1 < len(recs) {
+			out = appendBlock(out, blockRestart, []byte(strconv.Itoa(i+1)))
+		}
+	}
+	return appendBlock(out, blockEOF, nil)
+}
+
+// compressRecords is blockRecords for MODE C: each record is encoded and ends
+// with an EOR escape, a restart marker is an escape followed by a data string,
+// and an EOF escape ends the data. filler is the byte filler strings stand for.
+func compressRecords(recs [][]byte, skip, interval int, filler byte) []byte {
+	var out []byte
+	for i := skip; i < len(recs); i++ {
+		out = compress(out, recs[i], filler)
+		out = append(out, 0, blockEOR)
+		if interval > 0 && (i+1)%interval == 0 && i+1 < len(recs) {
+			m := strconv.Itoa(i + 1)
+			out = append(out, 0, blockRestart, byte(len(m)))
+			out = append(out, m...)
+		}
+	}
+	return append(out, 0, blockEOF)
+}
+
+// compress appends data in MODE C strings: runs of three or more bytes become a
+// filler string (of filler) or a replicated byte, the rest data strings.
+func compress(out, data []byte, filler byte) []byte {
+	lit := 0 // index of the data string being collected
+	flush := func(end int) {
+		for lit < end {
+			n := min(end-lit, 0x7F)
+			out = append(out, byte(n))
+			out = append(out, data[lit:lit+n]...)
+			lit += n
+		}
+	}
+	for i := 0; i < len(data); {
+		run := 1
+		for i+run < len(data) && data[i+run] == data[i] && run < 0x3F {
+			run++
+		}
+		if run < 3 {
+			i++
+			continue
+		}
+		flush(i)
+		if data[i] == filler {
+			out = append(out, 0xC0|byte(run))
+		} else {
+			out = append(out, 0x80|byte(run), data[i])
+		}
+		i += run
+		lit = i
+	}
+	flush(len(data))
+	return out
+}
+
+// uncompress turns a MODE C upload back into the stream a MODE S upload would
+// have sent, like unblock.
+func uncompress(data []byte, ascii bool, filler byte) []byte {
+	var out []byte
+	for len(data) > 0 {
+		h := data[0]
+		data = data[1:]
+		switch {
+		case h == 0:
+			if len(data) == 0 {
+				return out
+			}
+			desc := data[0]
+			data = data[1:]
+			if desc&blockRestart != 0 && len(data) > 0 {
+				data = data[min(1+int(data[0]), len(data)):]
+			}
+			if ascii && desc&blockEOR != 0 {
+				out = append(out, '\r', '\n')
+			}
+			if desc&blockEOF != 0 {
+				return out
+			}
+		case h&0x80 == 0:
+			n := min(int(h), len(data))
+			out = append(out, data[:n]...)
+			data = data[n:]
+		case h&0x40 != 0:
+			out = append(out, bytes.Repeat([]byte{filler}, int(h&0x3F))...)
+		default:
+			if len(data) == 0 {
+				return out
+			}
+			out = append(out, bytes.Repeat(data[:1], int(h&0x3F))...)
+			data = data[1:]
+		}
+	}
+	return out
+}
+
+// filler is the byte of a MODE C filler string: a space for text, zero for
+// binary.
+func (sess *session) filler() byte {
+	if sess.ascii {
+		return ' '
+	}
+	return 0
+}
+
+// frame encodes records in the session's transmission mode (block or
+// compressed), skipping the first skip and marking restarts every SITE
+// CHKPTINT records.
+func (sess *session) frame(recs [][]byte, skip int) []byte {
+	if sess.mode == "C" {
+		return compressRecords(recs, skip, sess.site.checkpointInterval(), sess.filler())
+	}
+	return blockRecords(recs, skip, sess.site.checkpointInterval())
+}
+
+// frameStream encodes a stream-mode payload (a listing, a spool file, scripted
+// data) in the session's transmission mode: in ASCII each line is a record, in
+// binary the whole payload is one.
+func (sess *session) frameStream(payload string) string {
+	switch {
+	case sess.mode == "S":
+		return payload
+	case sess.ascii:
+		return string(sess.frame(textRecords([]byte(strings.ReplaceAll(payload, "\r\n", "\n"))), 0))
+	default:
+		return string(sess.frame([][]byte{[]byte(payload)}, 0))
+	}
+}
+
+// unframe turns an upload in the session's transmission mode back into the
+// stream a MODE S upload would have sent.
+func (sess *session) unframe(data []byte) []byte {
+	switch sess.mode {
+	case "B":
+		return unblock(data, sess.ascii)
+	case "C":
+		return uncompress(data, sess.ascii, sess.filler())
+	}
+	return data
+}
+
+// textRecords splits UNIX file text into the records a block-mode ASCII
+// download carries: its lines, without line ends.
+func textRecords(data []byte) [][]byte {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	var recs [][]byte
+	for line := range strings.SplitSeq(text, "\n") {
+		recs = append(recs, []byte(line))
+	}
+	return recs
+}
+
+// unblock turns a MODE B upload back into the stream a MODE S upload would
+// have sent: ASCII records end in CRLF, binary data is joined. It stops at the
+// EOF block or a truncated block.
+func unblock(data []byte, ascii bool) []byte {
+	var out []byte
+	for len(data) >= 3 {
+		desc, n := data[0], int(binary.BigEndian.Uint16(data[1:3]))
+		if len(data) < 3+n {
+			break
+		}
+		if desc&blockRestart == 0 {
+			out = append(out, data[3:3+n]...)
+			if ascii && desc&blockEOR != 0 {
+				out = append(out, '\r', '\n')
+			}
+		}
+		data = data[3+n:]
+		if desc&blockEOF != 0 {
+			break
+		}
+	}
+	return out
+}
+
+// checkpointInterval is the session's SITE CHKPTINT, 0 when unset.
+func (st *siteState) checkpointInterval() int {
+	n, _ := strconv.Atoi(st.other["CHKPTINT"])
+	return n
+}
@@ -10,7 +10,8 @@ import (
 // EnableTLS makes the server accept AUTH TLS and upgrade the control connection
 // to a TLS server session using cfg (which must carry a certificate). It lets
 // tests exercise the client's AuthTLS/PBSZ/PROT path over a real, in-process TLS
-// handshake. Call it before the client dials.
+// handshake; after PROT P the passive data connections are TLS as well. Call it
+// before the client dials.
 func (s *Server) EnableTLS(cfg *tls.Config) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -7,14 +7,27 @@
 // exercised end-to-end (dial, passive negotiation, data transfer, multiline
 // reply parsing) over loopback with no mainframe.
 //
-// It is a test helper and lives under internal/ so it never becomes part of the
-// public API and cannot create an import cycle with the library under test.
+// By default every reply is scripted (Script, DataFor, CompletionReply). With
+// EnableState, or any of the Add… seeding helpers, the server instead keeps an
+// in-memory catalog of datasets, PDS members and z/OS UNIX files that STOR,
+// RETR, LIST, DELE, RNFR/RNTO and MKD operate on. Under SITE FILETYPE=JES the
+// same commands drive a simulated JES2 spool: STOR submits JCL, LIST shows jobs
+// at JESINTERFACELEVEL 1 or 2, RETR fetches spool files and DELE purges.
+//
+// InjectFaults installs a seeded profile of network faults (latency, bandwidth
+// caps, data-connection resets, delayed or split replies, 421 disconnects) on
+// top of either mode.
+//
+// It is a test helper and lives under internal/ so it can change freely and
+// cannot create an import cycle with the library under test. The public
+// zftptest package wraps it with a stable API for applications built on zftp.
 package mockzos
 
 import (
 	"bufio"
 	"crypto/tls"
 	"fmt"
+	"math/rand/v2"
 	"net"
 	"strings"
 	"sync"
@@ -29,7 +42,7 @@ const dataTimeout = 10 * time.Second
 
 // Server is an in-process FTP server bound to a loopback ephemeral port.
 type Server struct {
-	tb     testing.TB
+	tb     reporter
 	ln     net.Listener
 	closed atomic.Bool
 	wg     sync.WaitGroup
@@ -49,6 +62,19 @@ type Server struct {
 	completionReply map[string][]string // transfer verb (upper) -> override the closing reply (default "250 ...")
 	tlsConfig       *tls.Config         // when set, AUTH TLS upgrades the control connection
 	received        []string            // every command line received, in order
+	cat             *catalog            // stateful catalog; nil until EnableState or an Add… seed
+	clock           func() time.Time    // stateful clock; nil means time.Now
+	faults          *faultProfile       // InjectFaults profile; nil when none
+	sessions        atomic.Uint64       // control connections accepted so far
+	conns           map[net.Conn]bool   // open control connections, closed by Close
+}
+
+// reporter is the slice of testing.TB the server reports through: seeding
+// mistakes fail the test, and connection trouble is logged.
+type reporter interface {
+	Helper()
+	Fatalf(format string, args ...any)
+	Logf(format string, args ...any)
 }
 
 // New starts a Server on 127.0.0.1:0 and registers cleanup with the test.
@@ -58,6 +84,33 @@ func New(tb testing.TB) *Server {
 	if err != nil {
 		tb.Fatalf("mockzos listen: %v", err)
 	}
+	s := start(ln, tb)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Listen starts a Server on addr outside of a test, for a standalone fake
+// mainframe. Seeding mistakes and connection trouble are reported through logf
+// (nil discards them) instead of failing a test. The caller must Close it.
+func Listen(addr string, logf func(format string, args ...any)) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	return start(ln, logReporter(logf)), nil
+}
+
+// logReporter reports through a log function; a Fatalf is logged, not fatal.
+type logReporter func(format string, args ...any)
+
+func (logReporter) Helper()                             {}
+func (l logReporter) Fatalf(format string, args ...any) { l(format, args...) }
+func (l logReporter) Logf(format string, args ...any)   { l(format, args...) }
+
+func start(ln net.Listener, tb reporter) *Server {
 	s := &Server{
 		tb:              tb,
 		ln:              ln,
@@ -73,22 +126,28 @@ func New(tb testing.TB) *Server {
 		hangData:        map[string]bool{},
 		withholdReply:   map[string]bool{},
 		completionReply: map[string][]string{},
+		conns:           map[net.Conn]bool{},
 	}
 	s.wg.Add(1)
 	go s.serve()
-	tb.Cleanup(s.Close)
 	return s
 }
 
 // Addr returns the host:port the server is listening on.
 func (s *Server) Addr() string { return s.ln.Addr().String() }
 
-// Close stops the server and waits for in-flight connections to drain.
+// Close stops the server, closes the control connections still open, and waits
+// for their handlers to return.
 func (s *Server) Close() {
 	if s.closed.Swap(true) {
 		return
 	}
 	_ = s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
 	s.wg.Wait()
 }
 
@@ -106,23 +165,50 @@ func (s *Server) serve() {
 }
 
 // session holds per-connection state: the (possibly TLS-upgraded) control
-// connection, its buffered reader, and the pending passive data listener.
+// connection, its buffered reader, the pending passive data listener, and the
+// login, working directory, representation type, transmission mode, pending
+// REST and SITE values the stateful mode honors, and the command count and
+// random source of the fault profile.
 type session struct {
-	conn net.Conn
-	r    *bufio.Reader
-	pasv net.Listener
+	id         uint64
+	conn       net.Conn
+	r          *bufio.Reader
+	pasv       net.Listener
+	user       string
+	cwd        string
+	ascii      bool
+	mode       string // "S", "B" or "C" (MODE)
+	restart    string // argument of the last REST, until a transfer takes it
+	site       *siteState
+	renameFrom *target
+	protected  *tls.Config // after AUTH TLS and PROT P, data connections use TLS too
+	commands   int
+	rng        *rand.Rand
+	rngFor     *faultProfile
 }
 
 func (s *Server) handle(conn net.Conn) {
-	defer conn.Close()
-	sess := &session{conn: conn, r: bufio.NewReader(conn)}
+	s.mu.Lock()
+	s.conns[conn] = true
+	s.mu.Unlock()
+	if s.closed.Load() {
+		_ = conn.Close() // accepted while Close was sweeping the open connections
+	}
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	sess := &session{id: s.sessions.Add(1), r: bufio.NewReader(conn), ascii: true, mode: "S", site: newSiteState()}
+	sess.conn = &replyConn{Conn: conn, s: s, sess: sess}
 	defer func() {
 		if sess.pasv != nil {
 			_ = sess.pasv.Close()
 		}
 	}()
 
-	writeLines(conn, []string{"220 mockzos FTP service ready"})
+	writeLines(sess.conn, []string{"220 mockzos FTP service ready"})
 
 	for {
 		// Read from sess.r, not a captured reader: AUTH TLS swaps both the
@@ -137,6 +223,10 @@ func (s *Server) handle(conn net.Conn) {
 		s.received = append(s.received, line)
 		s.mu.Unlock()
 		verb, arg := splitCommand(line)
+		sess.commands++
+		if s.commandFaults(sess, line, verb) {
+			return
+		}
 		if s.dispatch(sess, line, verb, arg) {
 			return // QUIT
 		}
@@ -162,6 +252,18 @@ func (s *Server) dispatch(sess *session, line, verb, arg string) bool {
 		writeLines(sess.conn, reply)
 		return verb == "QUIT"
 	}
+	if verb == "USER" {
+		sess.user = strings.ToUpper(arg)
+	}
+	// In stateful mode the catalog answers the commands it models; DataFor
+	// payloads still win for downloads so a test can override one listing.
+	if c := s.state(); c != nil {
+		if _, scripted := s.dataFor(line, verb); !scripted || !isDownload(verb) {
+			if s.stateCommand(c, sess, line, verb, arg) {
+				return false
+			}
+		}
+	}
 
 	switch verb {
 	case "AUTH":
@@ -173,8 +275,12 @@ func (s *Server) dispatch(sess *session, line, verb, arg string) bool {
 	case "SYST":
 		writeLines(sess.conn, []string{"215 MVS is the operating system of this server. FTP Server is running on z/OS."})
 	case "TYPE":
+		// Tracked even without EnableState, like the SITE values below, so
+		// a session that logs in before its datasets are added keeps them.
+		sess.ascii = !strings.HasPrefix(strings.ToUpper(arg), "I")
 		writeLines(sess.conn, []string{"200 representation type is " + arg})
 	case "SITE":
+		sess.site.apply(arg)
 		writeLines(sess.conn, []string{"200 SITE command was accepted"})
 	case "XSTA", "XSTAT":
 		// Default: report a parseable FileType so dataset/spool flows can
@@ -184,7 +290,10 @@ func (s *Server) dispatch(sess *session, line, verb, arg string) bool {
 		writeLines(sess.conn, []string{"211 mockzos status ok"})
 	case "FEAT":
 		writeLines(sess.conn, []string{"211-Extensions supported", "211 End"})
+	case "MODE":
+		s.handleMode(sess, arg)
 	case "REST":
+		sess.restart = strings.TrimSpace(arg)
 		writeLines(sess.conn, []string{"350 restarting at the requested offset, send transfer command"})
 	case "CWD":
 		writeLines(sess.conn, []string{"250 directory changed"})
@@ -204,6 +313,8 @@ func (s *Server) dispatch(sess *session, line, verb, arg string) bool {
 		s.handleDownload(sess, line, verb, arg)
 	case "STOR", "STOU", "APPE":
 		s.handleUpload(sess, verb, arg)
+	case "PROT":
+		s.handleProt(sess, arg)
 	case "QUIT":
 		writeLines(sess.conn, []string{"221 goodbye"})
 		return true
@@ -237,6 +348,20 @@ func (s *Server) handleAuth(sess *session, arg string) {
 	sess.r = bufio.NewReader(tconn)
 }
 
+// handleProt records the data channel protection level. PROT P on a session
+// upgraded by AUTH TLS makes every later data connection a TLS server session
+// with the same configuration; PROT C (or PROT P without AUTH TLS) leaves them
+// in the clear.
+func (s *Server) handleProt(sess *session, arg string) {
+	sess.protected = nil
+	if _, ok := sess.conn.(*tls.Conn); ok && strings.EqualFold(strings.TrimSpace(arg), "P") {
+		s.mu.Lock()
+		sess.protected = s.tlsConfig
+		s.mu.Unlock()
+	}
+	writeLines(sess.conn, []string{"200 command okay"})
+}
+
 // handlePasv opens a fresh loopback data listener and advertises it.
 func (s *Server) handlePasv(sess *session) {
 	if sess.pasv != nil {
@@ -249,22 +374,54 @@ func (s *Server) handlePasv(sess *session) {
 		return
 	}
 	sess.pasv = dl
+	if sess.protected != nil {
+		sess.pasv = s.protect(dl, sess.protected)
+	}
 	port := dl.Addr().(*net.TCPAddr).Port
 	writeLines(sess.conn, []string{fmt.Sprintf("227 Entering Passive Mode (127,0,0,1,%d,%d)", port>>8, port&0xff)})
 }
 
+func isDownload(verb string) bool {
+	return verb == "LIST" || verb == "NLST" || verb == "RETR"
+}
+
 // handleDownload streams a registered payload over the passive data connection.
 func (s *Server) handleDownload(sess *session, line, verb, arg string) {
 	payload, ok := s.dataFor(line, verb)
 	if !ok {
 		payload = "" // empty listing is valid
 	}
+	s.sendDownload(sess, verb, payload)
+}
+
+// sendDownload delivers payload over the passive data connection, framed for
+// the session's transmission mode, and sends the closing reply, applying the
+// per-verb data and reply fault hooks.
+func (s *Server) sendDownload(sess *session, verb, payload string) {
+	s.sendFramed(sess, verb, sess.frameStream(payload))
+}
+
+// sendFramed is sendDownload for a payload already in the session's
+// transmission mode.
+func (s *Server) sendFramed(sess *session, verb, payload string) {
+	s.sendDownloadReplies(sess, verb, payload, []string{"125 data connection already open; transfer starting"}, s.completionReplyFor(verb))
+}
+
+// sendDownloadReplies is sendDownload with explicit preliminary and closing
+// replies, for transfers whose replies carry information (a JES submit-and-wait).
+func (s *Server) sendDownloadReplies(sess *session, verb, payload string, start, done []string) {
 	dc := s.acceptData(sess)
 	if dc == nil {
 		writeLines(sess.conn, []string{"425 cannot open data connection"})
 		return
 	}
-	writeLines(sess.conn, []string{"125 data connection already open; transfer starting"})
+	writeLines(sess.conn, start)
+	if off, ok := s.resetOffset(sess, verb, len(payload)); ok {
+		_, _ = dc.Write([]byte(payload[:off]))
+		reset(dc)
+		writeLines(sess.conn, []string{"426 Connection closed; transfer aborted."})
+		return
+	}
 	_, _ = dc.Write([]byte(payload))
 
 	// HangData: hold the data connection open after sending the payload so the
@@ -280,10 +437,7 @@ func (s *Server) handleDownload(sess *session, line, verb, arg string) {
 	// clean FIN, modeling a failed/aborted z/OS transfer. The control reply still
 	// says 250, so only the data-stream error should fail the operation.
 	if s.isTruncateData(verb) {
-		if tcp, ok := dc.(*net.TCPConn); ok {
-			_ = tcp.SetLinger(0)
-		}
-		_ = dc.Close()
+		reset(dc)
 		writeLines(sess.conn, []string{"250 transfer completed successfully"})
 		return
 	}
@@ -301,24 +455,42 @@ func (s *Server) handleDownload(sess *session, line, verb, arg string) {
 	if s.isWithholdReplyAfterData(verb) {
 		return
 	}
-	writeLines(sess.conn, s.completionReplyFor(verb))
+	s.delayCompletion(sess, verb)
+	writeLines(sess.conn, done)
 }
 
 // handleUpload captures the payload the client sends over the data connection.
 func (s *Server) handleUpload(sess *session, verb, arg string) {
+	if _, ok := s.receiveUpload(sess, verb, arg); ok {
+		writeLines(sess.conn, s.completionReplyFor(verb))
+	}
+}
+
+// receiveUpload accepts the data connection, drains the upload and records it
+// for Stored. It reports false (after replying 425) when no data connection
+// could be accepted; the caller sends the closing reply otherwise.
+func (s *Server) receiveUpload(sess *session, verb, arg string) ([]byte, bool) {
 	dc := s.acceptData(sess)
 	if dc == nil {
 		writeLines(sess.conn, []string{"425 cannot open data connection"})
-		return
+		return nil, false
 	}
 	writeLines(sess.conn, []string{"125 data connection already open; transfer starting"})
 	buf := new(strings.Builder)
+	if off, ok := s.resetOffset(sess, verb, 0); ok {
+		_, _ = copyN(buf, dc, off)
+		reset(dc)
+		writeLines(sess.conn, []string{"426 Connection closed; transfer aborted."})
+		return nil, false
+	}
 	_, _ = copyAll(buf, dc)
 	_ = dc.Close()
+	data := []byte(buf.String())
 	s.mu.Lock()
-	s.stored[strings.ToUpper(strings.TrimSpace(arg))] = []byte(buf.String())
+	s.stored[strings.ToUpper(strings.TrimSpace(arg))] = data
 	s.mu.Unlock()
-	writeLines(sess.conn, s.completionReplyFor(verb))
+	s.delayCompletion(sess, verb)
+	return data, true
 }
 
 // acceptData accepts the pending passive data connection.
@@ -337,9 +509,60 @@ func (s *Server) acceptData(sess *session) net.Conn {
 	if err != nil {
 		return nil
 	}
+	if p, _ := s.faultsFor(sess); p != nil && p.bandwidth > 0 {
+		dc = &throttledConn{Conn: dc, bps: p.bandwidth}
+	}
 	return dc
 }
 
+// protectedListener is a passive listener under PROT P. The client negotiates
+// TLS as soon as its data connection is up, before it sends the transfer
+// command, so the listener accepts and completes the handshake right away and
+// hands the connection over when the transfer asks for it.
+type protectedListener struct {
+	net.Listener
+	ready chan net.Conn // nil when the accept or the handshake failed
+}
+
+func (s *Server) protect(dl net.Listener, cfg *tls.Config) *protectedListener {
+	pl := &protectedListener{Listener: dl, ready: make(chan net.Conn, 1)}
+	if tl, ok := dl.(*net.TCPListener); ok {
+		_ = tl.SetDeadline(time.Now().Add(dataTimeout))
+	}
+	go func() {
+		dc, err := dl.Accept()
+		if err != nil {
+			pl.ready <- nil
+			return
+		}
+		tconn := tls.Server(dc, cfg)
+		_ = tconn.SetDeadline(time.Now().Add(dataTimeout))
+		if err := tconn.Handshake(); err != nil {
+			s.tb.Logf("mockzos: data connection TLS handshake failed: %v", err)
+			_ = dc.Close()
+			pl.ready <- nil
+			return
+		}
+		_ = tconn.SetDeadline(time.Time{})
+		pl.ready <- &protectedConn{Conn: tconn, raw: dc}
+	}()
+	return pl
+}
+
+func (l *protectedListener) Accept() (net.Conn, error) {
+	if dc := <-l.ready; dc != nil {
+		return dc, nil
+	}
+	return nil, net.ErrClosed
+}
+
+// protectedConn is a TLS data connection that remembers the connection under
+// it, so reset can still abort the TCP stream.
+type protectedConn struct {
+	*tls.Conn
+	raw net.Conn
+}
+
 // splitCommand splits a request line into an uppercased verb and its argument.
 func splitCommand(line string) (verb, arg string) {
 	line = strings.TrimSpace(line)
@@ -358,6 +581,21 @@ func writeLines(conn net.Conn, lines []string) {
 	_, _ = conn.Write([]byte(b.String()))
 }
 
+// copyN reads up to n bytes of src into dst.
+func copyN(dst *strings.Builder, src net.Conn, n int) (int, error) {
+	buf := make([]byte, 4096)
+	total := 0
+	for total < n {
+		m, err := src.Read(buf[:min(len(buf), n-total)])
+		dst.Write(buf[:m])
+		total += m
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 // copyAll drains src into dst without importing io (keeps the helper explicit).
 func copyAll(dst *strings.Builder, src net.Conn) (int, error) {
 	buf := make([]byte, 4096)
@@ -133,3 +133,30 @@ func TestServer_ScriptOverride(t *testing.T) {
 	readReply(t, r, "211-")
 	readReply(t, r, "211 ")
 }
+
+// TestState_RntoSourceRemoved checks RNTO fails cleanly when another session
+// deletes the source between RNFR and RNTO, for a member and a dataset.
+func TestState_RntoSourceRemoved(t *testing.T) {
+	s := New(t)
+	s.AddPDS("ME.SRC", Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800})
+	s.AddMember("ME.SRC", "OLD", "LINE")
+	s.AddDataset("ME.DATA", Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}, "REC")
+	a, ra := dial(t, s)
+	b, rb := dial(t, s)
+	readReply(t, ra, "220")
+	readReply(t, rb, "220")
+
+	for _, tc := range []struct{ from, to, remove string }{
+		{"'ME.SRC(OLD)'", "'ME.SRC(NEW)'", "'ME.SRC(OLD)'"},
+		{"'ME.DATA'", "'ME.DATA2'", "'ME.DATA'"},
+	} {
+		send(t, a, "RNFR "+tc.from)
+		readReply(t, ra, "350")
+		send(t, b, "DELE "+tc.remove)
+		readReply(t, rb, "250")
+		send(t, a, "RNTO "+tc.to)
+		if line := readReply(t, ra, "550"); !strings.Contains(line, "does not exist") {
+			t.Errorf("RNTO %s: %q", tc.to, line)
+		}
+	}
+}
@@ -0,0 +1,641 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package mockzos
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// siteState is the per-connection SITE configuration the stateful mode honors.
+// z/OS keeps SITE values per session, so each control connection has its own.
+type siteState struct {
+	recfm    string
+	lrecl    int
+	blksize  int
+	filetype string
+	rdw      bool
+	wrap     bool
+	library  bool
+	eol      string
+	other    map[string]string // every other KEY[=VALUE] seen, uppercased
+}
+
+func newSiteState() *siteState {
+	return &siteState{
+		recfm:    defaultAttrs.Recfm,
+		lrecl:    defaultAttrs.Lrecl,
+		blksize:  defaultAttrs.BlkSize,
+		filetype: "SEQ",
+		eol:      "\r\n",
+		other:    map[string]string{},
+	}
+}
+
+// apply records the space-separated KEY[=VALUE] parameters of a SITE command.
+// It returns the first parameter it cannot honor, or "".
+func (st *siteState) apply(arg string) string {
+	for tok := range strings.FieldsSeq(arg) {
+		key, val, _ := strings.Cut(strings.ToUpper(tok), "=")
+		switch key {
+		case "RECFM":
+			st.recfm = val
+		case "LRECL", "BLKSIZE", "BLOCKSIZE":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 32760 {
+				return tok
+			}
+			if key == "LRECL" {
+				st.lrecl = n
+			} else {
+				st.blksize = n
+			}
+		case "FILETYPE":
+			st.filetype = val
+		case "RDW", "NORDW":
+			st.rdw = key == "RDW"
+		case "WRAPRECORD", "NOWRAPRECORD":
+			st.wrap = key == "WRAPRECORD"
+		case "DSNTYPE":
+			st.library = val == "LIBRARY"
+		case "SBSENDEOL":
+			switch val {
+			case "CRLF":
+				st.eol = "\r\n"
+			case "LF":
+				st.eol = "\n"
+			case "CR":
+				st.eol = "\r"
+			case "NONE":
+				st.eol = ""
+			default:
+				return tok
+			}
+		default:
+			st.other[key] = val
+		}
+	}
+	return ""
+}
+
+func (st *siteState) attrs() Attrs {
+	return Attrs{Recfm: st.recfm, Lrecl: st.lrecl, BlkSize: st.blksize}.withDefaults()
+}
+
+// newAttrs returns the attributes of a dataset the session creates: those of the
+// SITE DCBDSN model when one is set and cataloged, else the SITE values. The
+// caller holds c.mu.
+func (sess *session) newAttrs(c *catalog) Attrs {
+	if model := sess.site.other["DCBDSN"]; model != "" {
+		if ds, ok := c.datasets[sess.resolve(model).dsn]; ok {
+			return ds.attrs
+		}
+	}
+	return sess.site.attrs()
+}
+
+// target is a resolved transfer argument: either a z/OS UNIX path or a dataset
+// name with an optional member.
+type target struct {
+	uss    bool
+	path   string
+	dsn    string
+	member string
+}
+
+func (t target) String() string {
+	switch {
+	case t.uss:
+		return t.path
+	case t.member != "":
+		return t.dsn + "(" + t.member + ")"
+	default:
+		return t.dsn
+	}
+}
+
+// resolve maps a command argument onto the catalog the way z/OS does: a leading
+// '/' is an absolute UNIX path, a quoted name is a fully qualified dataset name,
+// and anything else is relative to the working directory (a UNIX directory or a
+// dataset-name prefix such as "USER.").
+func (sess *session) resolve(arg string) target {
+	arg = strings.TrimSpace(arg)
+	switch {
+	case strings.HasPrefix(arg, "/"):
+		return target{uss: true, path: path.Clean(arg)}
+	case strings.HasPrefix(arg, "'"):
+		dsn, mem := splitMember(normalizeDSN(arg))
+		return target{dsn: dsn, member: mem}
+	case strings.HasPrefix(sess.cwd, "/"):
+		return target{uss: true, path: path.Join(sess.cwd, arg)}
+	default:
+		dsn, mem := splitMember(normalizeDSN(sess.cwd + arg))
+		return target{dsn: dsn, member: mem}
+	}
+}
+
+// stateCommand handles a command against the stateful catalog. It reports false
+// when the command is not one the stateful mode answers, so dispatch falls back
+// to the scripted defaults.
+func (s *Server) stateCommand(c *catalog, sess *session, line, verb, arg string) bool {
+	switch verb {
+	case "USER":
+		sess.cwd = strings.ToUpper(arg) + "."
+		return false
+	case "SITE":
+		if bad := sess.site.apply(arg); bad != "" {
+			writeLines(sess.conn, []string{"200-Unrecognized parameter '" + bad + "' on SITE command.", "200 SITE command was accepted"})
+			return true
+		}
+		return false
+	case "XSTA", "XSTAT":
+		return s.stateXstat(sess, arg)
+	case "STAT":
+		s.stateStat(sess)
+	case "CWD":
+		s.stateCwd(c, sess, arg)
+	case "PWD", "XPWD":
+		if strings.HasPrefix(sess.cwd, "/") {
+			writeLines(sess.conn, []string{fmt.Sprintf("257 %q is the HFS working directory.", sess.cwd)})
+		} else {
+			writeLines(sess.conn, []string{fmt.Sprintf("257 \"'%s'\" is working directory.", sess.cwd)})
+		}
+	case "TYPE":
+		sess.ascii = !strings.HasPrefix(strings.ToUpper(arg), "I")
+		return false
+	case "MKD", "XMKD":
+		s.stateMkd(c, sess, arg)
+	case "DELE":
+		if sess.site.filetype == "JES" {
+			return s.jesCommand(c, sess, verb, arg)
+		}
+		s.stateDele(c, sess, arg)
+	case "RNFR":
+		t := sess.resolve(arg)
+		if !c.exists(t) {
+			writeLines(sess.conn, []string{"550 RNFR fails: " + t.String() + " does not exist."})
+			return true
+		}
+		sess.renameFrom = &t
+		writeLines(sess.conn, []string{"350 RNFR accepted. Please supply new name for RNTO."})
+	case "RNTO":
+		s.stateRnto(c, sess, arg)
+	case "LIST", "NLST":
+		switch sess.site.filetype {
+		case "SEQ":
+			s.stateList(c, sess, verb, arg)
+		case "JES":
+			return s.jesCommand(c, sess, verb, arg)
+		default:
+			return false
+		}
+	case "RETR":
+		switch sess.site.filetype {
+		case "SEQ":
+			s.stateRetr(c, sess, arg)
+		case "JES":
+			return s.jesCommand(c, sess, verb, arg)
+		default:
+			return false
+		}
+	case "STOR", "APPE":
+		switch sess.site.filetype {
+		case "SEQ":
+			s.stateStor(c, sess, verb, arg)
+		case "JES":
+			return s.jesCommand(c, sess, verb, arg)
+		default:
+			return false
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// stateXstat answers the XSTA queries whose values the stateful mode tracks.
+func (s *Server) stateXstat(sess *session, arg string) bool {
+	key := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(arg), "("))
+	switch key {
+	case "FILETYPE":
+		writeLines(sess.conn, []string{"211-FileType " + sess.site.filetype, "211 *** end of status ***"})
+	case "RECFM", "LRECL", "BLOCKSIZE":
+		writeLines(sess.conn, []string{
+			fmt.Sprintf("211-Record format %s, Lrecl: %d, Blocksize: %d", sess.site.recfm, sess.site.lrecl, sess.site.blksize),
+			"211 *** end of status ***"})
+	case "RDW":
+		word := "not "
+		if sess.site.rdw {
+			word = ""
+		}
+		writeLines(sess.conn, []string{"211-RDWs from variable format datasets are " + word + "retained as part of data.", "211 *** end of status ***"})
+	case "JESJOBNAME", "JESOWNER", "JESSTATUS":
+		name, owner, status := sess.jesFilter()
+		val := map[string]string{"JESJOBNAME": name, "JESOWNER": owner, "JESSTATUS": status}[key]
+		writeLines(sess.conn, []string{"211-" + key + " is " + val, "211 *** end of status ***"})
+	case "JESINTERFACELEVEL":
+		writeLines(sess.conn, []string{fmt.Sprintf("211-JESINTERFACELEVEL is %d", sess.jesLevel()), "211 *** end of status ***"})
+	case "CHKPTINT":
+		writeLines(sess.conn, []string{fmt.Sprintf("211-Checkpoint interval is %d", sess.site.checkpointInterval()), "211 *** end of status ***"})
+	case "ENCODING":
+		enc := cmp.Or(sess.site.other["ENCODING"], "SBCS")
+		writeLines(sess.conn, []string{"211-Data transfer encoding is " + enc, "211 *** end of status ***"})
+	case "MBDATACONN":
+		line := "211-No multibyte data conversion is set"
+		if conn := sess.site.other["MBDATACONN"]; conn != "" {
+			line = "211-Multibyte data conversion is " + conn
+		}
+		writeLines(sess.conn, []string{line, "211 *** end of status ***"})
+	default:
+		return false
+	}
+	return true
+}
+
+// stateStat answers STAT with the tracked values, in z/OS wording.
+func (s *Server) stateStat(sess *session) {
+	rdw := "discarded"
+	if sess.site.rdw {
+		rdw = "retained as part of data"
+	}
+	name, owner, status := sess.jesFilter()
+	writeLines(sess.conn, []string{
+		"211-Server FTP talking to host 127.0.0.1",
+		"211-User: " + sess.user + "  Working directory: " + sess.cwd,
+		"211-FileType " + sess.site.filetype,
+		fmt.Sprintf("211-Record format %s, Lrecl: %d, Blocksize: %d", sess.site.recfm, sess.site.lrecl, sess.site.blksize),
+		"211-RDWs from variable format data sets are " + rdw + ".",
+		"211-JESJOBNAME is " + name,
+		"211-JESOWNER is " + owner,
+		"211-JESSTATUS is " + status,
+		"211 *** end of status ***",
+	})
+}
+
+func (s *Server) stateCwd(c *catalog, sess *session, arg string) {
+	arg = strings.TrimSpace(arg)
+	switch {
+	case arg == "..":
+		if strings.HasPrefix(sess.cwd, "/") {
+			sess.cwd = path.Dir(sess.cwd)
+		} else if q := strings.Split(strings.TrimSuffix(sess.cwd, "."), "."); len(q) > 1 {
+			sess.cwd = strings.Join(q[:len(q)-1], ".") + "."
+		}
+	case strings.HasPrefix(arg, "/") || strings.HasPrefix(sess.cwd, "/") && !strings.HasPrefix(arg, "'"):
+		t := sess.resolve(arg)
+		c.mu.Lock()
+		n, ok := c.uss[t.path]
+		c.mu.Unlock()
+		if !ok || !n.dir {
+			writeLines(sess.conn, []string{"550 " + t.path + ": No such file or directory."})
+			return
+		}
+		sess.cwd = t.path
+		writeLines(sess.conn, []string{fmt.Sprintf("250 HFS directory %s is the current working directory", sess.cwd)})
+		return
+	default:
+		sess.cwd = sess.resolve(arg).dsn
+		if !strings.HasSuffix(sess.cwd, ".") {
+			sess.cwd += "."
+		}
+	}
+	writeLines(sess.conn, []string{fmt.Sprintf("250 \"'%s'\" is the working directory name prefix.", sess.cwd)})
+}
+
+func (s *Server) stateMkd(c *catalog, sess *session, arg string) {
+	t := sess.resolve(arg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.uss {
+		parent, ok := c.uss[path.Dir(t.path)]
+		if _, exists := c.uss[t.path]; exists || !ok || !parent.dir {
+			writeLines(sess.conn, []string{"550 MKD fails: " + t.path + " cannot be created."})
+			return
+		}
+		c.uss[t.path] = &ussNode{dir: true, mtime: s.now()}
+		writeLines(sess.conn, []string{fmt.Sprintf("257 %q created.", t.path)})
+		return
+	}
+	if _, exists := c.datasets[t.dsn]; exists || t.member != "" {
+		writeLines(sess.conn, []string{"550 MKD fails: data set " + t.dsn + " already exists or is not valid."})
+		return
+	}
+	c.datasets[t.dsn] = &dataset{name: t.dsn, attrs: sess.newAttrs(c), library: sess.site.library || sess.site.other["PDSTYPE"] == "PDSE", referred: s.now(), members: map[string]*member{}}
+	writeLines(sess.conn, []string{fmt.Sprintf("257 \"'%s'\" created.", t.dsn)})
+}
+
+func (s *Server) stateDele(c *catalog, sess *session, arg string) {
+	t := sess.resolve(arg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case t.uss:
+		n, ok := c.uss[t.path]
+		if !ok || n.dir {
+			writeLines(sess.conn, []string{"550 DELE fails: " + t.path + " does not exist."})
+			return
+		}
+		delete(c.uss, t.path)
+	case t.member != "":
+		ds, ok := c.datasets[t.dsn]
+		if !ok || ds.members[t.member] == nil {
+			writeLines(sess.conn, []string{"550 DELE fails: " + t.String() + " does not exist."})
+			return
+		}
+		delete(ds.members, t.member)
+	default:
+		if _, ok := c.datasets[t.dsn]; !ok {
+			writeLines(sess.conn, []string{"550 DELE fails: " + t.dsn + " does not exist."})
+			return
+		}
+		delete(c.datasets, t.dsn)
+	}
+	writeLines(sess.conn, []string{"250 " + t.String() + " deleted."})
+}
+
+func (s *Server) stateRnto(c *catalog, sess *session, arg string) {
+	from := sess.renameFrom
+	sess.renameFrom = nil
+	if from == nil {
+		writeLines(sess.conn, []string{"503 RNTO must be preceded by RNFR."})
+		return
+	}
+	to := sess.resolve(arg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// RNFR checked the source, but another session may have removed it since.
+	if !c.existsLocked(*from) {
+		writeLines(sess.conn, []string{"550 RNTO fails: " + from.String() + " does not exist."})
+		return
+	}
+	if c.existsLocked(to) || to.uss != from.uss || (to.member == "") != (from.member == "") {
+		writeLines(sess.conn, []string{"550 RNTO fails: " + to.String() + " cannot be used as the new name."})
+		return
+	}
+	switch {
+	case from.uss:
+		c.uss[to.path] = c.uss[from.path]
+		delete(c.uss, from.path)
+	case from.member != "":
+		src, dst := c.datasets[from.dsn], c.datasets[to.dsn]
+		if dst == nil || !dst.partitioned() {
+			writeLines(sess.conn, []string{"550 RNTO fails: " + to.dsn + " is not a partitioned data set."})
+			return
+		}
+		m := src.members[from.member]
+		delete(src.members, from.member)
+		m.name = to.member
+		dst.members[to.member] = m
+	default:
+		ds := c.datasets[from.dsn]
+		delete(c.datasets, from.dsn)
+		ds.name = to.dsn
+		c.datasets[to.dsn] = ds
+	}
+	writeLines(sess.conn, []string{"250 " + from.String() + " renamed to " + to.String()})
+}
+
+// exists reports whether the resolved target is present in the catalog.
+func (c *catalog) exists(t target) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.existsLocked(t)
+}
+
+func (c *catalog) existsLocked(t target) bool {
+	if t.uss {
+		_, ok := c.uss[t.path]
+		return ok
+	}
+	ds, ok := c.datasets[t.dsn]
+	if !ok || t.member == "" {
+		return ok
+	}
+	_, ok = ds.members[t.member]
+	return ok
+}
+
+// stateList renders a listing of datasets, PDS members or a UNIX directory. A
+// fully qualified PDS name (or "PDS(pattern)") lists members, anything else is a
+// dataset-name pattern. NLST emits names only.
+func (s *Server) stateList(c *catalog, sess *session, verb, arg string) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" || arg == "*" {
+		if strings.HasPrefix(sess.cwd, "/") {
+			arg = sess.cwd
+		} else {
+			arg = "'" + sess.cwd + "**'"
+		}
+	}
+	t := sess.resolve(arg)
+	names := verb == "NLST"
+
+	c.mu.Lock()
+	var lines []string
+	switch {
+	case t.uss:
+		if n, ok := c.uss[t.path]; ok && !n.dir {
+			lines = append(lines, pick(names, path.Base(t.path), ussLine(path.Base(t.path), n)))
+			break
+		}
+		for _, name := range c.ussChildren(t.path) {
+			lines = append(lines, pick(names, name, ussLine(name, c.uss[path.Join(t.path, name)])))
+		}
+		if len(lines) == 0 {
+			if _, ok := c.uss[t.path]; !ok {
+				c.mu.Unlock()
+				writeLines(sess.conn, []string{"550 " + t.path + ": No such file or directory."})
+				return
+			}
+		}
+	case t.member != "" || c.datasets[t.dsn] != nil && c.datasets[t.dsn].partitioned():
+		ds, ok := c.datasets[t.dsn]
+		if !ok || !ds.partitioned() {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 No members found."})
+			return
+		}
+		pattern := t.member
+		if pattern == "" {
+			pattern = "*"
+		}
+		for _, m := range ds.sortedMembers(dsnPattern(pattern)) {
+			lines = append(lines, pick(names, m.name, memberLine(m)))
+		}
+		if len(lines) == 0 {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 No members found."})
+			return
+		}
+		if !names {
+			lines = append([]string{memberHeader}, lines...)
+		}
+	default:
+		for _, ds := range c.sortedDatasets(dsnPattern(t.dsn)) {
+			lines = append(lines, pick(names, "'"+ds.name+"'", datasetLine(ds)))
+		}
+		if len(lines) == 0 {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 No data sets found."})
+			return
+		}
+		if !names {
+			lines = append([]string{datasetHeader}, lines...)
+		}
+	}
+	c.mu.Unlock()
+
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(l)
+		b.WriteString("\r\n")
+	}
+	s.sendDownload(sess, verb, b.String())
+}
+
+func pick(names bool, name, full string) string {
+	if names {
+		return name
+	}
+	return full
+}
+
+// stateRetr serves a dataset, member or UNIX file from the catalog. In block
+// and compressed modes each record (each line of a UNIX file in ASCII) is
+// framed as a record, with restart markers every SITE CHKPTINT records, and a
+// REST marker skips the records sent before it.
+func (s *Server) stateRetr(c *catalog, sess *session, arg string) {
+	t := sess.resolve(arg)
+	skip := 0
+	if sess.mode != "S" {
+		skip, _ = strconv.Atoi(sess.restart)
+	}
+	sess.restart = ""
+	c.mu.Lock()
+	var payload []byte
+	found := false
+	switch {
+	case t.uss:
+		if n, ok := c.uss[t.path]; ok && !n.dir {
+			found = true
+			payload = n.data
+			switch {
+			case sess.mode != "S" && sess.ascii:
+				payload = sess.frame(textRecords(n.data), skip)
+			case sess.mode != "S":
+				payload = sess.frame([][]byte{n.data}, 0)
+			case sess.ascii && sess.site.eol != "\n":
+				payload = []byte(strings.ReplaceAll(string(n.data), "\n", sess.site.eol))
+			}
+		}
+	default:
+		if ds, ok := c.datasets[t.dsn]; ok {
+			recs, ok := ds.records, !ds.partitioned()
+			if t.member != "" {
+				m, mok := ds.members[t.member]
+				ok = mok
+				if mok {
+					recs = m.records
+				}
+			}
+			if ok {
+				found = true
+				ds.referred = s.now()
+				if sess.mode != "S" {
+					framed := make([][]byte, len(recs))
+					for i, r := range recs {
+						framed[i] = encodeRecords(ds.attrs, [][]byte{r}, sess.ascii, sess.site.rdw, "")
+					}
+					payload = sess.frame(framed, skip)
+				} else {
+					payload = encodeRecords(ds.attrs, recs, sess.ascii, sess.site.rdw, sess.site.eol)
+				}
+			}
+		}
+	}
+	c.mu.Unlock()
+	if !found {
+		writeLines(sess.conn, []string{"550 Request nonexistent data set or member " + t.String()})
+		return
+	}
+	s.sendFramed(sess, "RETR", string(payload))
+}
+
+// stateStor receives an upload and files it in the catalog. A new sequential
+// dataset takes the session's SITE RECFM/LRECL/BLKSIZE; an existing dataset (or
+// a member, which inherits its PDS's attributes) keeps its DCB.
+func (s *Server) stateStor(c *catalog, sess *session, verb, arg string) {
+	t := sess.resolve(arg)
+	c.mu.Lock()
+	if t.uss {
+		parent, ok := c.uss[path.Dir(t.path)]
+		if !ok || !parent.dir {
+			c.mu.Unlock()
+			writeLines(sess.conn, []string{"550 " + t.path + ": No such file or directory."})
+			return
+		}
+	} else if ds, ok := c.datasets[t.dsn]; t.member != "" && (!ok || !ds.partitioned()) || t.member == "" && ok && ds.partitioned() {
+		c.mu.Unlock()
+		writeLines(sess.conn, []string{"550 STOR fails: " + t.String() + " is not a valid target."})
+		return
+	}
+	c.mu.Unlock()
+
+	data, ok := s.receiveUpload(sess, verb, arg)
+	if !ok {
+		return
+	}
+	data = sess.unframe(data)
+
+	c.mu.Lock()
+	truncated := false
+	switch {
+	case t.uss:
+		if sess.ascii {
+			data = []byte(strings.ReplaceAll(string(data), "\r\n", "\n"))
+		}
+		n := c.uss[t.path]
+		if n == nil || verb != "APPE" {
+			n = &ussNode{}
+			c.uss[t.path] = n
+		}
+		n.data = append(n.data, data...)
+		n.mtime = s.now()
+	default:
+		ds, ok := c.datasets[t.dsn]
+		if !ok {
+			ds = &dataset{name: t.dsn, attrs: sess.newAttrs(c), referred: s.now()}
+			c.datasets[t.dsn] = ds
+		}
+		var recs [][]byte
+		recs, truncated = decodeRecords(ds.attrs, data, sess.ascii, sess.site.rdw, sess.site.wrap)
+		if t.member != "" {
+			m := ds.members[t.member]
+			if m == nil {
+				m = &member{name: t.member}
+				ds.members[t.member] = m
+			}
+			if verb == "APPE" {
+				recs = append(m.records, recs...)
+			}
+			m.records = recs
+			m.touch(s.now(), sess.user)
+		} else {
+			if verb == "APPE" {
+				recs = append(ds.records, recs...)
+			}
+			ds.records = recs
+		}
+		ds.referred = s.now()
+	}
+	c.mu.Unlock()
+
+	if truncated {
+		writeLines(sess.conn, []string{"250 Transfer completed (data was truncated)"})
+		return
+	}
+	writeLines(sess.conn, s.completionReplyFor(verb))
+}
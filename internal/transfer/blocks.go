@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package transfer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Block descriptor flags (RFC 959, MODE B). Each block on the data connection
+// is a descriptor byte, a 16-bit big-endian byte count and that many bytes.
+const (
+	BlockEOR     byte = 0x80 // the block ends a record
+	BlockEOF     byte = 0x40 // the block ends the file
+	BlockErrors  byte = 0x20 // the block's data may be in error
+	BlockRestart byte = 0x10 // the block's data is a restart marker
+)
+
+// maxBlock is the largest count a block header can carry.
+const maxBlock = 1<<16 - 1
+
+// storeChunk is how much unstructured data StoreBlock puts in one block.
+const storeChunk = 32 << 10
+
+// BlockReader reads MODE B blocks.
+type BlockReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func NewBlockReader(r io.Reader) *BlockReader {
+	return &BlockReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next block's descriptor and data. The data is only valid
+// until the following call. It returns io.EOF when the stream ends between
+// blocks and io.ErrUnexpectedEOF when it ends inside one.
+func (b *BlockReader) Next() (desc byte, data []byte, err error) {
+	var hdr [3]byte
+	if _, err = io.ReadFull(b.r, hdr[:]); err != nil {
+		return 0, nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[1:]))
+	if cap(b.buf) < n {
+		b.buf = make([]byte, n)
+	}
+	data = b.buf[:n]
+	if _, err = io.ReadFull(b.r, data); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return hdr[0], data, nil
+}
+
+// BlockWriter writes MODE B blocks. Output is buffered; call Flush when done.
+type BlockWriter struct {
+	w *bufio.Writer
+}
+
+func NewBlockWriter(w io.Writer) *BlockWriter {
+	return &BlockWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteBlock writes data with descriptor desc. Data longer than a block can
+// hold is split, and only the last block carries desc.
+func (b *BlockWriter) WriteBlock(desc byte, data []byte) error {
+	for len(data) > maxBlock {
+		if err := b.write(0, data[:maxBlock]); err != nil {
+			return err
+		}
+		data = data[maxBlock:]
+	}
+	return b.write(desc, data)
+}
+
+func (b *BlockWriter) write(desc byte, data []byte) error {
+	hdr := [3]byte{desc}
+	binary.BigEndian.PutUint16(hdr[1:], uint16(len(data)))
+	if _, err := b.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	_, err := b.w.Write(data)
+	return err
+}
+
+func (b *BlockWriter) Flush() error {
+	return b.w.Flush()
+}
+
+/* ------------------------------------------------------------------------------------------------------------------ */
+
+// RetrieveBlock receives a MODE B download. Data goes to dest, followed by eor
+// at the end of each record; restart markers are passed to onMarker with the
+// number of bytes written to dest so far.
+type RetrieveBlock struct {
+	dest     io.Writer
+	eor      []byte
+	onMarker func(marker string, written int64)
+	wire     int64
+}
+
+func (r *RetrieveBlock) Transfer(conn net.Conn) (int64, error) {
+	cr := &counter{r: conn}
+	defer func() { r.wire = cr.n }()
+	br := NewBlockReader(cr)
+	var n int64
+	for {
+		desc, data, err := br.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF // the server must end with an EOF block
+			}
+			return n, fmt.Errorf("block mode: %w", err)
+		}
+		if desc&BlockRestart != 0 {
+			if r.onMarker != nil {
+				r.onMarker(string(data), n)
+			}
+			continue
+		}
+		if desc&BlockEOR != 0 {
+			data = append(data, r.eor...)
+		}
+		w, err := r.dest.Write(data)
+		n += int64(w)
+		if err != nil {
+			return n, err
+		}
+		if desc&BlockEOF != 0 {
+			// Nothing follows the EOF block; read to the server's close so the
+			// connection is not torn down with data unread.
+			_, _ = io.Copy(io.Discard, cr)
+			return n, nil
+		}
+	}
+}
+
+func (r *RetrieveBlock) Command() string {
+	return "RETR"
+}
+
+// WireBytes returns how many bytes the last transfer read from the connection.
+func (r *RetrieveBlock) WireBytes() int64 {
+	return r.wire
+}
+
+// NewRetrieveBlock returns a MODE B retrieve. eor is appended to each record
+// (a line end for text, nil for binary); onMarker may be nil.
+func NewRetrieveBlock(dest io.Writer, eor []byte, onMarker func(marker string, written int64)) *RetrieveBlock {
+	return &RetrieveBlock{dest: dest, eor: eor, onMarker: onMarker}
+}
+
+/* ------------------------------------------------------------------------------------------------------------------ */
+
+// StoreBlock sends a MODE B upload: each text line as a record, or binary data
+// in unstructured blocks, then an EOF block.
+type StoreBlock struct {
+	src   io.Reader
+	ascii bool
+	wire  int64
+}
+
+func (s *StoreBlock) Transfer(conn net.Conn) (int64, error) {
+	cw := &counter{w: conn}
+	defer func() { s.wire = cw.n }()
+	bw := NewBlockWriter(cw)
+	size := int64(0)
+	if s.ascii {
+		scanner := bufio.NewScanner(s.src)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if err := bw.WriteBlock(BlockEOR, line); err != nil {
+				return size, err
+			}
+			size += int64(len(line))
+		}
+		if err := scanner.Err(); err != nil {
+			return size, fmt.Errorf("scan error: %w", err)
+		}
+	} else {
+		buf := make([]byte, storeChunk)
+		for {
+			n, err := io.ReadFull(s.src, buf)
+			if n > 0 {
+				if werr := bw.WriteBlock(0, buf[:n]); werr != nil {
+					return size, werr
+				}
+				size += int64(n)
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			if err != nil {
+				return size, err
+			}
+		}
+	}
+	if err := bw.WriteBlock(BlockEOF, nil); err != nil {
+		return size, err
+	}
+	if err := bw.Flush(); err != nil {
+		return size, fmt.Errorf("flush error: %w", err)
+	}
+	return size, nil
+}
+
+func (s *StoreBlock) Command() string {
+	return "STOR"
+}
+
+// WireBytes returns how many bytes the last transfer wrote to the connection.
+func (s *StoreBlock) WireBytes() int64 {
+	return s.wire
+}
+
+// NewStoreBlock returns a MODE B store; ascii sends src line by line, as
+// records, instead of as unstructured data.
+func NewStoreBlock(src io.Reader, ascii bool) *StoreBlock {
+	return &StoreBlock{src: src, ascii: ascii}
+}
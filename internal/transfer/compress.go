@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package transfer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// MODE C (RFC 959) encodes the data as a sequence of strings, each introduced by
+// a header byte: 0nnnnnnn is n bytes of data, 10nnnnnn one byte repeated n times,
+// and 11nnnnnn n filler bytes (a space for text, zero for binary). A zero
+// header is an escape: the byte after it is a block descriptor (BlockEOR,
+// BlockEOF, BlockRestart), and a restart marker is the data string that follows.
+const (
+	maxLiteral = 0x7F
+	maxRun     = 0x3F
+	minRun     = 3 // shorter runs stay in the data string
+)
+
+// CompressWriter writes MODE C data. Output is buffered; call Flush when done.
+type CompressWriter struct {
+	w      *bufio.Writer
+	filler byte
+	lit    []byte
+}
+
+func NewCompressWriter(w io.Writer, filler byte) *CompressWriter {
+	return &CompressWriter{w: bufio.NewWriter(w), filler: filler, lit: make([]byte, 0, maxLiteral)}
+}
+
+// Write encodes p, turning runs of a byte into replicated-byte or filler
+// strings. Runs are not joined across calls.
+func (c *CompressWriter) Write(p []byte) (int, error) {
+	for i := 0; i < len(p); {
+		b, run := p[i], 1
+		for i+run < len(p) && p[i+run] == b && run < maxRun {
+			run++
+		}
+		if run < minRun {
+			c.lit = append(c.lit, b)
+			i++
+			if len(c.lit) == maxLiteral {
+				if err := c.flushLiteral(); err != nil {
+					return 0, err
+				}
+			}
+			continue
+		}
+		if err := c.flushLiteral(); err != nil {
+			return 0, err
+		}
+		var err error
+		if b == c.filler {
+			err = c.w.WriteByte(0xC0 | byte(run))
+		} else {
+			_, err = c.w.Write([]byte{0x80 | byte(run), b})
+		}
+		if err != nil {
+			return 0, err
+		}
+		i += run
+	}
+	return len(p), nil
+}
+
+func (c *CompressWriter) flushLiteral() error {
+	if len(c.lit) == 0 {
+		return nil
+	}
+	if err := c.w.WriteByte(byte(len(c.lit))); err != nil {
+		return err
+	}
+	_, err := c.w.Write(c.lit)
+	c.lit = c.lit[:0]
+	return err
+}
+
+// Escape writes an escape with descriptor desc.
+func (c *CompressWriter) Escape(desc byte) error {
+	if err := c.flushLiteral(); err != nil {
+		return err
+	}
+	_, err := c.w.Write([]byte{0, desc})
+	return err
+}
+
+func (c *CompressWriter) Flush() error {
+	if err := c.flushLiteral(); err != nil {
+		return err
+	}
+	return c.w.Flush()
+}
+
+/* ------------------------------------------------------------------------------------------------------------------ */
+
+// RetrieveCompressed receives a MODE C download. Data goes to dest, followed by
+// eor at the end of each record; restart markers are passed to onMarker with
+// the number of bytes written to dest so far.
+type RetrieveCompressed struct {
+	dest     io.Writer
+	eor      []byte
+	filler   byte
+	onMarker func(marker string, written int64)
+	wire     int64
+}
+
+func (r *RetrieveCompressed) Transfer(conn net.Conn) (n int64, err error) {
+	cr := &counter{r: conn}
+	br := bufio.NewReader(cr)
+	out := bufio.NewWriter(r.dest)
+	defer func() {
+		r.wire = cr.n
+		if ferr := out.Flush(); err == nil {
+			err = ferr
+		}
+	}()
+	write := func(p []byte) error {
+		w, err := out.Write(p)
+		n += int64(w)
+		return err
+	}
+	buf := make([]byte, maxLiteral)
+	for {
+		h, err := br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF // the server must end with an EOF escape
+			}
+			return n, fmt.Errorf("compressed mode: %w", err)
+		}
+		switch {
+		case h == 0:
+			desc, err := br.ReadByte()
+			if err != nil {
+				return n, fmt.Errorf("compressed mode: %w", io.ErrUnexpectedEOF)
+			}
+			if desc&BlockRestart != 0 {
+				l, err := br.ReadByte()
+				if err == nil && (l == 0 || l&0x80 != 0) {
+					err = errors.New("restart marker is not a data string")
+				}
+				if err == nil {
+					_, err = io.ReadFull(br, buf[:l])
+				}
+				if err != nil {
+					return n, fmt.Errorf("compressed mode: %w", err)
+				}
+				if err := out.Flush(); err != nil {
+					return n, err
+				}
+				if r.onMarker != nil {
+					r.onMarker(string(buf[:l]), n)
+				}
+			}
+			if desc&BlockEOR != 0 {
+				if err := write(r.eor); err != nil {
+					return n, err
+				}
+			}
+			if desc&BlockEOF != 0 {
+				// Read to the server's close so the connection is not torn down
+				// with data unread.
+				_, _ = io.Copy(io.Discard, br)
+				return n, nil
+			}
+		case h&0x80 == 0:
+			if _, err := io.ReadFull(br, buf[:h]); err != nil {
+				return n, fmt.Errorf("compressed mode: %w", io.ErrUnexpectedEOF)
+			}
+			if err := write(buf[:h]); err != nil {
+				return n, err
+			}
+		default:
+			b := r.filler
+			if h&0x40 == 0 {
+				if b, err = br.ReadByte(); err != nil {
+					return n, fmt.Errorf("compressed mode: %w", io.ErrUnexpectedEOF)
+				}
+			}
+			run := buf[:h&maxRun]
+			for i := range run {
+				run[i] = b
+			}
+			if err := write(run); err != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+func (r *RetrieveCompressed) Command() string {
+	return "RETR"
+}
+
+// WireBytes returns how many bytes the last transfer read from the connection.
+func (r *RetrieveCompressed) WireBytes() int64 {
+	return r.wire
+}
+
+// NewRetrieveCompressed returns a MODE C retrieve. eor is appended to each
+// record (a line end for text, nil for binary), filler is the byte filler
+// strings expand to, and onMarker may be nil.
+func NewRetrieveCompressed(dest io.Writer, eor []byte, filler byte, onMarker func(marker string, written int64)) *RetrieveCompressed {
+	return &RetrieveCompressed{dest: dest, eor: eor, filler: filler, onMarker: onMarker}
+}
+
+/* ------------------------------------------------------------------------------------------------------------------ */
+
+// StoreCompressed sends a MODE C upload: each text line as a record, or binary
+// data unstructured, then an EOF escape.
+type StoreCompressed struct {
+	src    io.Reader
+	ascii  bool
+	filler byte
+	wire   int64
+}
+
+func (s *StoreCompressed) Transfer(conn net.Conn) (int64, error) {
+	cw := &counter{w: conn}
+	defer func() { s.wire = cw.n }()
+	w := NewCompressWriter(cw, s.filler)
+	size := int64(0)
+	if s.ascii {
+		scanner := bufio.NewScanner(s.src)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if _, err := w.Write(line); err != nil {
+				return size, err
+			}
+			if err := w.Escape(BlockEOR); err != nil {
+				return size, err
+			}
+			size += int64(len(line))
+		}
+		if err := scanner.Err(); err != nil {
+			return size, fmt.Errorf("scan error: %w", err)
+		}
+	} else {
+		n, err := io.Copy(w, s.src)
+		size += n
+		if err != nil {
+			return size, err
+		}
+	}
+	if err := w.Escape(BlockEOF); err != nil {
+		return size, err
+	}
+	if err := w.Flush(); err != nil {
+		return size, fmt.Errorf("flush error: %w", err)
+	}
+	return size, nil
+}
+
+func (s *StoreCompressed) Command() string {
+	return "STOR"
+}
+
+// WireBytes returns how many bytes the last transfer wrote to the connection.
+func (s *StoreCompressed) WireBytes() int64 {
+	return s.wire
+}
+
+// NewStoreCompressed returns a MODE C store; ascii sends src line by line, as
+// records, and filler is the byte filler strings stand for.
+func NewStoreCompressed(src io.Reader, ascii bool, filler byte) *StoreCompressed {
+	return &StoreCompressed{src: src, ascii: ascii, filler: filler}
+}
@@ -20,8 +20,31 @@ var (
 	_ DataTransfer = (*Store)(nil)
 	_ DataTransfer = (*Retrieve)(nil)
 	_ DataTransfer = (*StoreAscii)(nil)
+	_ DataTransfer = (*RetrieveBlock)(nil)
+	_ DataTransfer = (*StoreBlock)(nil)
+	_ DataTransfer = (*RetrieveCompressed)(nil)
+	_ DataTransfer = (*StoreCompressed)(nil)
 )
 
+// counter counts the bytes read from or written to the data connection.
+type counter struct {
+	r io.Reader
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
 type Store struct {
 	src io.Reader
 }
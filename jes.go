@@ -3,6 +3,7 @@
 package zftp
 
 import (
+	"context"
 	"fmt"
 	"gopkg.in/ro-ag/zftp.v2/hfs"
 	"gopkg.in/ro-ag/zftp.v2/internal/utils"
@@ -36,16 +37,16 @@ func (s *FTPSession) SubmitIO(jr io.Reader, options ...JesSpec) (*JesJob, error)
 		}
 	}
 
-	curr, err := utils.SetValueAndGetCurrent(s.log, "JES", s.SetStatusOf().FileType, s.StatusOf().FileType)
+	var msg string
+	err := s.WithSite(context.Background(), SiteParams{"FILETYPE": "JES"}, func() (err error) {
+		if _, msg, err = s.storeIO(job.DSN, jr, TypeAscii, transferOptions{}); err != nil {
+			return fmt.Errorf("failed to write JCL to FTP server: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer curr.Restore()
-
-	_, msg, err := s.storeIO(job.DSN, jr, TypeAscii)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write JCL to FTP server: %w", err)
-	}
 
 	match := s.jobPrefix.FindStringSubmatch(msg)
 	if len(match) != 2 {
@@ -100,48 +101,38 @@ var (
 // it returns the whole Spool output as a string
 // this function waits for the job to complete
 //
-// NOTE: the RECFM/LRECL/BLKSIZE SITE attributes set here for the JCL upload are
-// NOT restored afterwards (only FILETYPE and the JES job-name filter are), so they
-// persist on the session for subsequent commands. Re-set them, or use a separate
-// session, if a later transfer needs different allocation attributes.
+// NOTE: the RECFM/LRECL/BLKSIZE SITE attributes set here for the JCL upload, and
+// NOJESGETBYDSN, are NOT restored afterwards (only FILETYPE and the JES job-name
+// filter are, through WithSite), so they persist on the session for subsequent
+// commands. Re-set them, or use a separate session, if a later transfer needs
+// different allocation attributes.
 func (s *FTPSession) SubmitJesGetByDSN(jcl string) (*JobResult, error) {
-	currSeq, err := utils.SetValueAndGetCurrent(s.log, "SEQ", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
-		return nil, err
-	}
-	defer currSeq.Restore()
-
-	_, err = s.Site("RECFM=FB LRECL=80 BLKSIZE=27920")
-	if err != nil {
-		return nil, fmt.Errorf("failed to set site parameters: %w", err)
-	}
-
 	job := &JobResult{}
 
 	job.DSN = generateJobFileName()
 
-	_, err = s.StoreIO(job.DSN, strings.NewReader(jcl), TypeAscii)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write JCL to FTP server: %w", err)
-	}
-
-	currJes, err := utils.SetValueAndGetCurrent(s.log, "JES NOJESGETBYDSN", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
-		return nil, err
-	}
-	defer currJes.Restore()
-
-	currName, err := utils.SetValueAndGetCurrent(s.log, "*", s.SetStatusOf().JesJobName, s.StatusOf().JesJobName)
-	if err != nil {
-		return nil, err
-	}
-	defer currName.Restore()
-
 	jobOutput := &strings.Builder{}
-
-	_, msg, err := s.retrieveIO(job.DSN, jobOutput, TypeAscii)
+	var msg string
+	ctx := context.Background()
+	err := s.WithSite(ctx, SiteParams{"FILETYPE": "SEQ"}, func() error {
+		if _, err := s.setSite("RECFM=FB LRECL=80 BLKSIZE=27920"); err != nil {
+			return fmt.Errorf("failed to set site parameters: %w", err)
+		}
+		if _, err := s.StoreIO(job.DSN, strings.NewReader(jcl), TypeAscii); err != nil {
+			return fmt.Errorf("failed to write JCL to FTP server: %w", err)
+		}
+		return s.WithSite(ctx, SiteParams{"FILETYPE": "JES", "JESJOBNAME": "*"}, func() (err error) {
+			if _, err = s.setSite("NOJESGETBYDSN"); err != nil {
+				return fmt.Errorf("failed to set site parameters: %w", err)
+			}
+			if _, msg, err = s.retrieveIO(job.DSN, jobOutput, TypeAscii, transferOptions{}); err != nil {
+				return fmt.Errorf("failed to retrieve job output: %w", err)
+			}
+			return nil
+		})
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve job output: %w", err)
+		return nil, err
 	}
 
 	/* get job-id from response */
@@ -270,28 +261,49 @@ func (s *FTPSession) GetJobStatus(jobID string) (*hfs.InfoJobDetail, error) {
 		return nil, fmt.Errorf("invalid job-id: %s", jobID)
 	}
 
-	// set JES parameters and restore them after the function returns
-	FileType, err := utils.SetValueAndGetCurrent(s.log, "JES", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
-		return nil, err
-	}
-	defer FileType.Restore()
+	// list for job details under FILETYPE=JES and JESJOBNAME=*, restored afterwards
+	var jr *hfs.InfoJobDetail
+	err := s.WithSite(context.Background(), jesListParams, func() error {
+		records, err := s.List(jobID)
+		if err != nil {
+			return err
+		}
+		jr, err = hfs.ParseInfoJobDetail(records)
+		return err
+	})
+	return jr, err
+}
 
-	// set jes job name to * and restore it after the function returns
-	JesJobName, err := utils.SetValueAndGetCurrent(s.log, "*", s.SetStatusOf().JesJobName, s.StatusOf().JesJobName)
-	if err != nil {
-		return nil, err
-	}
-	defer JesJobName.Restore()
+// jesListParams select every job in JES, for commands that name a job by ID.
+var jesListParams = SiteParams{"FILETYPE": "JES", "JESJOBNAME": "*"}
 
-	// list for job details
-	records, err := s.List(jobID)
-	if err != nil {
-		return nil, err
+// RetrieveSpool downloads spool file n of a job (RETR JOBnnnnn.n), or every
+// spool file when n is 0 (RETR JOBnnnnn.x, each file followed by the
+// " !! END OF JES SPOOL FILE !!" line), to dest in ASCII, and returns the number
+// of bytes transferred. Like GetJobStatus it sets FILETYPE=JES and JESJOBNAME=*
+// and restores both afterwards.
+//
+// The transfer options apply as for RetrieveIO; WithCarriageControl(carriage.ASA)
+// turns the carriage control of SYSOUT (the JES2 job log among it) into blank
+// lines and form feeds.
+func (s *FTPSession) RetrieveSpool(jobID string, n int, dest io.Writer, opts ...TransferOption) (int64, error) {
+	if utils.RegexSearchPattern.MatchString(jobID) || jobID == "" {
+		return 0, fmt.Errorf("invalid job-id: %s", jobID)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid spool file number: %d", n)
+	}
+	remote := jobID + ".x"
+	if n > 0 {
+		remote = fmt.Sprintf("%s.%d", jobID, n)
 	}
 
-	jr, err := hfs.ParseInfoJobDetail(records)
-	return jr, err
+	var size int64
+	err := s.WithSite(context.Background(), jesListParams, func() (err error) {
+		size, err = s.RetrieveIO(remote, dest, TypeAscii, opts...)
+		return err
+	})
+	return size, err
 }
 
 // WithJesEntryLimit sets the maximum number of entries to retrieve from JES
@@ -360,11 +372,8 @@ func (f jesOptionFunc) Apply(s *FTPSession) error {
 // The session's file type is set to JES for the call and restored afterward. A
 // 550 (unknown job / not owner) is returned as a *ReturnError.
 func (s *FTPSession) PurgeJob(jobID string) error {
-	ft, err := utils.SetValueAndGetCurrent(s.log, "JES", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
+	return s.WithSite(context.Background(), SiteParams{"FILETYPE": "JES"}, func() error {
+		_, err := s.send(CodeFileActionOK, "DELE", jobID)
 		return err
-	}
-	defer ft.Restore()
-	_, err = s.SendCommand(CodeFileActionOK, "DELE", jobID)
-	return err
+	})
 }
@@ -0,0 +1,220 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// knownState is what the session knows of the server's SITE parameters and
+// transfer type because the library set or read them, so commands that would not
+// change them can be skipped. Raw Site and SendCommand calls may change
+// anything, so they forget it all. It is seeded from one STAT per login, read
+// lazily on first use rather than by Login; see Login.
+type knownState struct {
+	mu     sync.Mutex
+	typ    TransferType      // 0 when unknown
+	site   map[string]string // parameter name -> value, as siteToken splits it
+	seeded bool              // a STAT has been read since login
+}
+
+// knownToggles are the switches tracked by their bare form (RDW / NORDW). Other
+// bare parameters, such as TRACKS or CYLINDERS, clear or replace settings under
+// other names and are never skipped.
+var knownToggles = map[string]bool{
+	"RDW": true, "JESGETBYDSN": true, "TRUNCATE": true, "WRAPRECORD": true,
+	"TRAILINGBLANKS": true, "ISPFSTATS": true, "AUTORECALL": true, "AUTOMOUNT": true,
+	"QUOTESOVERRIDE": true, "ASATRANS": true, "LISTSUBDIR": true, "MBREQUIRELASTEOL": true,
+	"SBSUB": true, "SPREAD": true, "UCSSUB": true, "UCSTRUNC": true,
+}
+
+// siteToken splits a SITE parameter into the name the session tracks it by and
+// its value: "LRECL=80" is LRECL and 80, "NORDW" is RDW and NORDW. ok is false
+// for a parameter that is not tracked.
+func siteToken(tok string) (name, value string, ok bool) {
+	tok = strings.ToUpper(tok)
+	if name, value, ok = strings.Cut(tok, "="); ok {
+		if name == "BLOCKSIZE" {
+			name = "BLKSIZE"
+		}
+		return name, value, name != ""
+	}
+	name = strings.TrimPrefix(tok, "NO")
+	return name, tok, knownToggles[name]
+}
+
+func (k *knownState) forget() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.typ = 0
+	clear(k.site)
+}
+
+// reset forgets everything, and that a STAT was read, for a new login.
+func (k *knownState) reset() {
+	k.forget()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.seeded = false
+}
+
+// claimSeed reports whether no STAT has been read since login, marking one as
+// read so that only the first caller sends it.
+func (k *knownState) claimSeed() bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	first := !k.seeded
+	k.seeded = true
+	return first
+}
+
+// isType reports whether the server is known to be in transfer type t.
+func (k *knownState) isType(t TransferType) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.typ != 0 && k.typ == t
+}
+
+// setType records the transfer type; 0 forgets it.
+func (k *knownState) setType(t TransferType) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.typ = t
+}
+
+// value returns the known value of the SITE parameter name.
+func (k *knownState) value(name string) (string, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	v, ok := k.site[name]
+	return v, ok
+}
+
+// covers reports whether every parameter of a SITE command is tracked and
+// known to have the value it sets, so sending it would change nothing.
+func (k *knownState) covers(params string) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	toks := strings.Fields(params)
+	for _, tok := range toks {
+		name, value, ok := siteToken(tok)
+		if known, found := k.site[name]; !ok || !found || known != value {
+			return false
+		}
+	}
+	return len(toks) > 0
+}
+
+// remember records the tracked parameters of a SITE command the server accepted.
+// A bare parameter that is not a toggle, such as DCBDSN, clears its value. A
+// DCBDSN model replaces the RECFM, LRECL and BLKSIZE of new datasets, so setting
+// one forgets those.
+func (k *knownState) remember(params string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for tok := range strings.FieldsSeq(params) {
+		name, value, ok := siteToken(tok)
+		switch {
+		case ok:
+			if k.site == nil {
+				k.site = map[string]string{}
+			}
+			k.site[name] = value
+			if name == "DCBDSN" {
+				delete(k.site, "RECFM")
+				delete(k.site, "LRECL")
+				delete(k.site, "BLKSIZE")
+			}
+		case !strings.Contains(tok, "="):
+			delete(k.site, name)
+		}
+	}
+}
+
+// forgetParams drops the parameters of a SITE command that failed, which the
+// server may have applied in part.
+func (k *knownState) forgetParams(params string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for tok := range strings.FieldsSeq(params) {
+		if name, _, ok := siteToken(tok); ok {
+			delete(k.site, name)
+		}
+	}
+}
+
+// knownOr returns get wrapped to answer with the known value of the SITE
+// parameter name instead of querying the server, and to remember what a query
+// returns. get must return the value in the form the parameter's setter sends:
+// the bare form (RDW / NORDW) for a toggle. The first value needed after login
+// reads one STAT, which may answer this and later ones.
+func (s *FTPSession) knownOr(name string, get func() (string, error)) func() (string, error) {
+	return s.knownVia(s.StatusOf(), name, get)
+}
+
+// knownVia is knownOr reading the STAT through st, such as one bound to a
+// context.
+func (s *FTPSession) knownVia(st *ServerStatus, name string, get func() (string, error)) func() (string, error) {
+	return func() (string, error) {
+		if v, ok := s.known.value(name); ok {
+			return v, nil
+		}
+		if s.seedKnown(st) {
+			if v, ok := s.known.value(name); ok {
+				return v, nil
+			}
+		}
+		v, err := get()
+		if err == nil && v != "" && !strings.ContainsAny(v, " =") {
+			tok := name + "=" + v
+			if knownToggles[name] {
+				tok = v
+			}
+			s.known.remember(tok)
+		}
+		return v, err
+	}
+}
+
+// seedKnown reads a STAT through st into the known values, once per login; it
+// reports whether it did.
+func (s *FTPSession) seedKnown(st *ServerStatus) bool {
+	if !s.known.claimSeed() {
+		return false
+	}
+	snap, err := st.Snapshot()
+	if err != nil {
+		s.log.Debugf("STAT for the known settings: %v", err)
+		return false
+	}
+	s.known.seed(snap)
+	return true
+}
+
+var fileTypeLine = regexp.MustCompile(`^FileType\s+(\w+)`)
+
+// seed records what a STAT reply says of the parameters the library changes.
+func (k *knownState) seed(snap StatusSnapshot) {
+	var params []string
+	for _, ln := range snap.Lines() {
+		if m := fileTypeLine.FindStringSubmatch(ln); m != nil {
+			params = append(params, "FILETYPE="+m[1])
+		}
+		if m := recFmt.FindStringSubmatch(ln); m != nil {
+			params = append(params, "RECFM="+m[1], "LRECL="+m[2], "BLKSIZE="+m[3])
+		}
+		if strings.HasPrefix(ln, "RDWs ") {
+			if v, err := rdwValue(ln); err == nil {
+				params = append(params, v)
+			}
+		}
+	}
+	for _, name := range []string{"JESJOBNAME", "JESOWNER", "JESSTATUS", "JESLRECL", "JESENTRYLIMIT", "LISTLEVEL"} {
+		if v, ok := snap.Get(name); ok && v != "" && !strings.ContainsAny(v, " =") {
+			params = append(params, name+"="+v)
+		}
+	}
+	k.remember(strings.Join(params, " "))
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// countPrefix counts the commands that start with prefix.
+func countPrefix(cmds []string, prefix string) int {
+	n := 0
+	for _, c := range cmds {
+		if strings.HasPrefix(c, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+// TestKnownState_SeededOnFirstUse checks login sends no STAT, as Login
+// documents, and the one STAT read when a value is first needed tells the session the FILETYPE, so listing
+// many datasets sends no XSTA or SITE FILETYPE at all.
+func TestKnownState_SeededOnFirstUse(t *testing.T) {
+	srv := mockzos.New(t)
+	srv.AddDataset("ME.DATA", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800}, "ONE")
+	s, err := zftp.Open(srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	if err := s.Login("ME", "PW"); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCmd(srv.Commands(), "STAT"); n != 0 {
+		t.Errorf("login sent %d STAT, want 0", n)
+	}
+
+	before := len(srv.Commands())
+	for range 20 {
+		if _, err := s.ListDatasets("'ME.*'"); err != nil {
+			t.Fatalf("ListDatasets: %v", err)
+		}
+	}
+	cmds := srv.Commands()[before:]
+	if n := countCmd(cmds, "STAT"); n != 1 {
+		t.Errorf("sent %d STAT, want 1", n)
+	}
+	if n := countPrefix(cmds, "XSTA"); n != 0 {
+		t.Errorf("sent %d XSTA queries, want 0", n)
+	}
+	if n := countPrefix(cmds, "SITE"); n != 0 {
+		t.Errorf("sent %d SITE commands, want 0: %v", n, cmds)
+	}
+	if len(cmds) > 20*4+1 {
+		t.Errorf("20 listings took %d commands", len(cmds))
+	}
+}
+
+// TestKnownState_SkipsAndForgets checks values learned from a query are reused,
+// a transfer in the current type sends no TYPE, and raw Site and SendCommand
+// calls make the session query again.
+func TestKnownState_SkipsAndForgets(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.DataFor("LIST", "", "")
+	srv.DataFor("RETR", "MY.BIN", "\x00\x01")
+
+	list := func() []string {
+		before := len(srv.Commands())
+		if _, err := s.ListDatasets("'ME.*'"); err != nil {
+			t.Fatalf("ListDatasets: %v", err)
+		}
+		return srv.Commands()[before:]
+	}
+	if cmds := list(); countPrefix(cmds, "XSTA") != 1 || countPrefix(cmds, "SITE") != 0 {
+		t.Errorf("first listing: %v", cmds)
+	}
+	if cmds := list(); countPrefix(cmds, "XSTA") != 0 {
+		t.Errorf("second listing queried again: %v", cmds)
+	}
+
+	before := len(srv.Commands())
+	if _, err := s.RetrieveIO("MY.BIN", &bytes.Buffer{}, zftp.TypeBinary); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := srv.Commands()[before:]; countPrefix(cmds, "TYPE") != 0 {
+		t.Errorf("binary transfer in TYPE I sent %v", cmds)
+	}
+
+	if _, err := s.Site("FILETYPE=SEQ"); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := list(); countPrefix(cmds, "XSTA") != 1 {
+		t.Errorf("listing after a raw Site did not query: %v", cmds)
+	}
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); err != nil {
+		t.Fatal(err)
+	}
+	before = len(srv.Commands())
+	if _, err := s.RetrieveIO("MY.BIN", &bytes.Buffer{}, zftp.TypeBinary); err != nil {
+		t.Fatal(err)
+	}
+	if cmds := srv.Commands()[before:]; countPrefix(cmds, "TYPE I") == 0 {
+		t.Errorf("transfer after SendCommand trusted the old TYPE: %v", cmds)
+	}
+}
+
+// TestKnownState_SetterSkips checks a setter that would not change a known
+// value sends nothing, and that SITE DCBDSN and a failed SITE make the values
+// they affect unknown.
+func TestKnownState_SetterSkips(t *testing.T) {
+	s, srv := dialMock(t)
+	set := s.SetStatusOf()
+	for range 3 {
+		if err := set.Lrecl(133); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countCmd(srv.Commands(), "SITE LRECL=133"); n != 1 {
+		t.Errorf("SITE LRECL=133 sent %d times, want 1", n)
+	}
+
+	if err := set.DCBDSN("'ME.MODEL'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Lrecl(133); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCmd(srv.Commands(), "SITE LRECL=133"); n != 2 {
+		t.Errorf("after SITE DCBDSN, SITE LRECL=133 sent %d times, want 2", n)
+	}
+
+	srv.Script("SITE LRECL=80", "501 Invalid LRECL.")
+	if err := set.Lrecl(80); err == nil {
+		t.Fatal("Lrecl(80): want the scripted rejection")
+	}
+	if err := set.Lrecl(133); err != nil {
+		t.Fatal(err)
+	}
+	if n := countCmd(srv.Commands(), "SITE LRECL=133"); n != 3 {
+		t.Errorf("after a failed SITE, SITE LRECL=133 sent %d times, want 3", n)
+	}
+}
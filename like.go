@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+)
+
+// ErrNoModel is returned by AllocateLike when the model dataset is not found.
+var ErrNoModel = errors.New("zftp: model dataset not found")
+
+// like is the DataSpec returned by Like.
+type like struct {
+	ds        hfs.InfoDataset
+	overrides []DataSpec
+}
+
+// Like specs for a new dataset with the attributes of ds, an entry returned by
+// ListDatasets: its RECFM, LRECL and BLKSIZE, and its space in tracks, with the
+// tracks in use as primary (at least 1) and the average extent as secondary.
+// A partitioned (DSORG PO) model makes Allocate create a PDS; pass
+// DSNTypeLibrary as an override for a PDSE.
+//
+// overrides replace the attribute of the same kind derived from ds, so
+// Like(ds, WithLrecl(133), SpaceCylinders) keeps the model's RECFM and BLKSIZE.
+// Apply fails for a model without listed attributes, such as a migrated or VSAM
+// dataset; WithDCBDSN copies the DCB of those.
+func Like(ds hfs.InfoDataset, overrides ...DataSpec) DataSpec {
+	return like{ds: ds, overrides: overrides}
+}
+
+// derived returns the specs Like takes from the model.
+func (l like) derived() ([]DataSpec, error) {
+	ds := l.ds
+	if !ds.Active() || ds.IsVSAM() {
+		return nil, fmt.Errorf("dataset %s has no attributes to copy", ds.Name())
+	}
+	var a []DataSpec
+	if r := ds.Recfm.String(); r != "" && r != "?" {
+		a = append(a, Recfm(r))
+	}
+	if n := ds.Lrecl.Value(); n > 0 && n <= math.MaxUint16 {
+		a = append(a, lrecl(n))
+	}
+	if n := ds.BlkSz.Value(); n > 0 && n <= math.MaxUint16 {
+		a = append(a, blksz(n))
+	}
+	if !ds.Used.IsOverflow() && !ds.Tracks.IsOverflow() {
+		used := max(ds.Tracks.Value(), ds.Used.Value(), 1)
+		a = append(a, SpaceTracks, primary(used), secondary(max(used/max(ds.Ext.Value(), 1), 1)))
+	}
+	return a, nil
+}
+
+// partitioned reports whether Allocate should create the dataset with MKD, and
+// whether as a PDSE.
+func (l like) partitioned() (partitioned, pdse bool) {
+	partitioned, pdse = allocKind(l.overrides)
+	return partitioned || l.ds.IsPartitioned(), pdse
+}
+
+// Apply renders the model's attributes, then the overrides in place of the
+// attributes of the same kind.
+func (l like) Apply() (string, error) {
+	a, err := l.derived()
+	if err != nil {
+		return "", err
+	}
+	var params []string
+	index := map[string]int{}
+	for _, spec := range append(a, l.overrides...) {
+		p, err := spec.Apply()
+		if err != nil {
+			return "", err
+		}
+		for tok := range strings.FieldsSeq(p) {
+			kind, _, _ := strings.Cut(tok, "=")
+			if SpaceUnit(kind).isUnit() {
+				kind = "SPACE"
+			}
+			if i, ok := index[kind]; ok {
+				params[i] = tok
+				continue
+			}
+			index[kind] = len(params)
+			params = append(params, tok)
+		}
+	}
+	return strings.Join(params, " "), nil
+}
+
+// dcbdsn is the DataSpec returned by WithDCBDSN.
+type dcbdsn string
+
+func (d dcbdsn) Apply() (string, error) {
+	if d == "" {
+		return "", fmt.Errorf("no DCBDSN model specified")
+	}
+	return fmt.Sprintf("DCBDSN=%s", string(d)), nil
+}
+
+// Compile-time checks that the DataSpec implementations satisfy the interface.
+var (
+	_ DataSpec = like{}
+	_ DataSpec = dcbdsn("")
+)
+
+// WithDCBDSN specs for a new dataset with the RECFM, LRECL and BLKSIZE of the
+// model dataset, copied by the server (SITE DCBDSN). model is quoted or relative
+// to the working directory. Space is not copied.
+func WithDCBDSN(model string) DataSpec {
+	return dcbdsn(model)
+}
+
+// AllocateLike creates the empty dataset dsn with the attributes of the dataset
+// model, as Allocate with Like. When the listing of model carries no attributes,
+// as for a migrated dataset, the DCB is copied by the server with SITE DCBDSN
+// instead, and cleared again afterwards; space then comes from the overrides or
+// the server's defaults.
+func (s *FTPSession) AllocateLike(dsn, model string, overrides ...DataSpec) error {
+	ds, found, err := s.findDataset(model)
+	switch {
+	case err != nil:
+		return err
+	case !found:
+		return fmt.Errorf("%w: %s", ErrNoModel, model)
+	case ds.Active() && !ds.IsVSAM():
+		return s.Allocate(dsn, Like(ds, overrides...))
+	}
+	defer func() {
+		if _, err := s.setSite("DCBDSN"); err != nil {
+			s.log.Errorf("failed to clear DCBDSN: %s", err)
+		}
+	}()
+	return s.Allocate(dsn, append([]DataSpec{WithDCBDSN(model)}, overrides...)...)
+}
@@ -0,0 +1,262 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LinePolicy is what WithLinePolicy does with a line longer than the records of
+// the target dataset.
+type LinePolicy int
+
+const (
+	LineError    LinePolicy = iota + 1 // Fail before STOR with a *LongLinesError
+	LineTruncate                       // Store the first width characters
+	LineWrap                           // Store the line as several records of width characters
+	LineSpill                          // Leave the line out and write it to the WithSpill writer
+)
+
+// String returns the policy name, such as "truncate".
+func (p LinePolicy) String() string {
+	switch p {
+	case LineError:
+		return "error"
+	case LineTruncate:
+		return "truncate"
+	case LineWrap:
+		return "wrap"
+	case LineSpill:
+		return "spill"
+	}
+	return fmt.Sprintf("LinePolicy(%d)", int(p))
+}
+
+// linePolicy is the policy and line width of WithLinePolicy.
+type linePolicy struct {
+	policy LinePolicy
+	width  int
+}
+
+// WithLinePolicy has StoreIO enforce p on the client for text lines longer
+// than width characters, instead of leaving them to the server's SITE
+// WRAPRECORD and TRUNCATE handling. It affects ASCII transfers and those with
+// WithLocalConversion; with any other type StoreIO fails.
+//
+// width 0 is discovered from a listing of the target dataset: its LRECL, less
+// the 4-byte record descriptor word for variable-length records. A dataset that
+// does not exist yet takes the session's SITE RECFM and LRECL. z/OS UNIX files
+// have no record length, so they need an explicit width.
+//
+// LineError reads the whole source before STOR: a source that is not an
+// io.Seeker is buffered in memory. The other policies apply as the data is sent,
+// and list the lines they changed in TransferStats.LongLines.
+func WithLinePolicy(p LinePolicy, width int) TransferOption {
+	return func(o *transferOptions) {
+		o.lines = &linePolicy{policy: p, width: width}
+	}
+}
+
+// WithSpill sets where LineSpill writes the lines it leaves out, each followed
+// by a newline.
+func WithSpill(w io.Writer) TransferOption {
+	return func(o *transferOptions) {
+		o.spill = w
+	}
+}
+
+// enforceLines returns src with o's line policy applied, and the limiter that
+// records the lines it changed; src unchanged and nil without a policy.
+func (s *FTPSession) enforceLines(remote string, src io.Reader, o transferOptions) (io.Reader, *lineLimiter, error) {
+	lp := o.lines
+	if lp == nil {
+		return src, nil, nil
+	}
+	switch lp.policy {
+	case LineError, LineTruncate, LineWrap:
+	case LineSpill:
+		if o.spill == nil {
+			return nil, nil, errors.New("zftp: LineSpill needs a WithSpill writer")
+		}
+	default:
+		return nil, nil, fmt.Errorf("zftp: invalid line policy %s", lp.policy)
+	}
+	width := lp.width
+	if width <= 0 {
+		var err error
+		if width, err = s.lineWidth(remote); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if lp.policy == LineError {
+		src, long, err := scanLongLines(src, width)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(long) > 0 {
+			return nil, nil, &LongLinesError{Lrecl: width, Lines: long}
+		}
+		return src, nil, nil
+	}
+	l := &lineLimiter{br: bufio.NewReader(src), width: width, policy: lp.policy, spill: o.spill}
+	return l, l, nil
+}
+
+// lineWidth returns the longest line a record of the dataset remote holds.
+func (s *FTPSession) lineWidth(remote string) (int, error) {
+	if strings.HasPrefix(remote, "/") {
+		return 0, fmt.Errorf("zftp: WithLinePolicy needs a width for z/OS UNIX file %s", remote)
+	}
+	ds, found, err := s.findDataset(remote)
+	if err != nil {
+		return 0, err
+	}
+	recfm, lrecl := ds.Recfm.String(), int(ds.Lrecl.Value())
+	if !found || lrecl == 0 {
+		if recfm, err = s.knownOr("RECFM", s.StatusOf().Recfm)(); err != nil {
+			return 0, err
+		}
+		v, err := s.knownOr("LRECL", func() (string, error) {
+			n, err := s.StatusOf().Lrecl()
+			return strconv.Itoa(n), err
+		})()
+		if err != nil {
+			return 0, err
+		}
+		if lrecl, err = strconv.Atoi(v); err != nil {
+			return 0, fmt.Errorf("zftp: unexpected LRECL %q", v)
+		}
+	}
+	if strings.HasPrefix(strings.ToUpper(recfm), "V") {
+		lrecl -= 4
+	}
+	if lrecl <= 0 {
+		return 0, fmt.Errorf("zftp: no record length for %s", remote)
+	}
+	return lrecl, nil
+}
+
+// scanLongLines returns the numbers of the lines of src longer than width, and
+// a reader that yields src again from the start.
+func scanLongLines(src io.Reader, width int) (io.Reader, []int, error) {
+	var buf bytes.Buffer
+	seeker, seekable := src.(io.Seeker)
+	var start int64
+	if seekable {
+		var err error
+		if start, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+	scan := src
+	if !seekable {
+		scan = io.TeeReader(src, &buf)
+	}
+	var long []int
+	br := bufio.NewReader(scan)
+	for n := 1; ; n++ {
+		line, err := br.ReadString('\n')
+		if line != "" && lineLength(line) > width {
+			long = append(long, n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if !seekable {
+		return &buf, long, nil
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	return src, long, nil
+}
+
+// lineLength returns the length of line in characters, without its line end.
+func lineLength(line string) int {
+	return utf8.RuneCountInString(trimEOL(line))
+}
+
+// trimEOL returns line without its LF or CRLF.
+func trimEOL(line string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+}
+
+// lineLimiter is a reader that truncates, wraps or spills the lines of br
+// longer than width characters.
+type lineLimiter struct {
+	br     *bufio.Reader
+	width  int
+	policy LinePolicy
+	spill  io.Writer
+	line   int
+	long   []int
+	buf    []byte
+	err    error
+}
+
+// Read yields the lines of br, each changed by add.
+func (l *lineLimiter) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		line, err := l.br.ReadString('\n')
+		if err != nil {
+			l.err = err
+		}
+		if line != "" {
+			l.add(line)
+		}
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+// add queues line, changed by the policy when it is too long.
+func (l *lineLimiter) add(line string) {
+	l.line++
+	body := trimEOL(line)
+	if utf8.RuneCountInString(body) <= l.width {
+		l.buf = append(l.buf, line...)
+		return
+	}
+	l.long = append(l.long, l.line)
+	switch l.policy {
+	case LineTruncate:
+		l.buf = append(append(l.buf, firstRunes(body, l.width)...), '\n')
+	case LineWrap:
+		for body != "" {
+			chunk := firstRunes(body, l.width)
+			l.buf = append(append(l.buf, chunk...), '\n')
+			body = body[len(chunk):]
+		}
+	case LineSpill:
+		if _, err := io.WriteString(l.spill, body+"\n"); err != nil {
+			l.err = fmt.Errorf("zftp: spill line %d: %w", l.line, err)
+		}
+	}
+}
+
+// firstRunes returns the first n characters of s.
+func firstRunes(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// TestStoreIO_LinePolicy checks each policy against the LRECL listed for an
+// existing dataset: a VB 14 dataset holds lines of 10 characters.
+func TestStoreIO_LinePolicy(t *testing.T) {
+	const text = "short\nexactly 10\nthis is far too long\nok\n"
+	cases := []struct {
+		policy  zftp.LinePolicy
+		records []string
+		spilled string
+	}{
+		{zftp.LineTruncate, []string{"short", "exactly 10", "this is fa", "ok"}, ""},
+		{zftp.LineWrap, []string{"short", "exactly 10", "this is fa", "r too long", "ok"}, ""},
+		{zftp.LineSpill, []string{"short", "exactly 10", "ok"}, "this is far too long\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.policy.String(), func(t *testing.T) {
+			s, srv := dialMock(t)
+			srv.AddDataset("ME.TEXT", mockzos.Attrs{Recfm: "VB", Lrecl: 14, BlkSize: 6233})
+			var spill bytes.Buffer
+			var st zftp.TransferStats
+			_, err := s.StoreIO("'ME.TEXT'", strings.NewReader(text), zftp.TypeAscii,
+				zftp.WithLinePolicy(c.policy, 0), zftp.WithSpill(&spill), zftp.WithTransferStats(&st))
+			if err != nil {
+				t.Fatalf("StoreIO: %v", err)
+			}
+			if got, _ := srv.Records("ME.TEXT"); !reflect.DeepEqual(got, c.records) {
+				t.Errorf("records = %q, want %q", got, c.records)
+			}
+			if spill.String() != c.spilled {
+				t.Errorf("spilled %q, want %q", spill.String(), c.spilled)
+			}
+			if !reflect.DeepEqual(st.LongLines, []int{3}) {
+				t.Errorf("LongLines = %v, want [3]", st.LongLines)
+			}
+		})
+	}
+}
+
+// TestStoreIO_LinePolicyError checks LineError reports every long line before
+// anything is stored, for seekable and streamed sources alike, and lets a
+// source that fits through unchanged.
+func TestStoreIO_LinePolicyError(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("ME.CARDS", mockzos.Attrs{Recfm: "FB", Lrecl: 8, BlkSize: 800})
+	const text = "12345678\n123456789\nabc\nabcdefghij\n"
+
+	for name, src := range map[string]io.Reader{
+		"seeker": strings.NewReader(text),
+		"stream": io.MultiReader(strings.NewReader(text)),
+	} {
+		before := len(srv.Commands())
+		_, err := s.StoreIO("'ME.CARDS'", src, zftp.TypeAscii, zftp.WithLinePolicy(zftp.LineError, 0))
+		var long *zftp.LongLinesError
+		if !errors.As(err, &long) || long.Lrecl != 8 || !reflect.DeepEqual(long.Lines, []int{2, 4}) {
+			t.Errorf("%s: got %v, want lines [2 4] longer than 8", name, err)
+		}
+		if !errors.Is(err, zftp.ErrLineTooLong) {
+			t.Errorf("%s: %v is not ErrLineTooLong", name, err)
+		}
+		if cmds := srv.Commands()[before:]; countPrefix(cmds, "STOR") != 0 {
+			t.Errorf("%s: commands = %v", name, cmds)
+		}
+	}
+
+	src := io.MultiReader(strings.NewReader("A\nBB\n"))
+	if _, err := s.StoreIO("'ME.CARDS'", src, zftp.TypeAscii, zftp.WithLinePolicy(zftp.LineError, 0)); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	if got, _ := srv.Records("ME.CARDS"); !reflect.DeepEqual(got, []string{"A", "BB"}) {
+		t.Errorf("records = %q", got)
+	}
+}
+
+// TestStoreIO_LinePolicyWidth checks the width of a new dataset comes from the
+// session's SITE values, that an explicit width wins, and the invalid setups.
+func TestStoreIO_LinePolicyWidth(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	if err := s.SetDataSpecs(zftp.RecfmFB, zftp.WithLrecl(5)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.StoreIO("'ME.NEW'", strings.NewReader("123456\n"), zftp.TypeAscii,
+		zftp.WithLinePolicy(zftp.LineTruncate, 0)); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	if got, _ := srv.Records("ME.NEW"); !reflect.DeepEqual(got, []string{"12345"}) {
+		t.Errorf("records = %q", got)
+	}
+
+	if _, err := s.StoreIO("'ME.NEW'", strings.NewReader("123456\n"), zftp.TypeAscii,
+		zftp.WithLinePolicy(zftp.LineWrap, 4)); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	if got, _ := srv.Records("ME.NEW"); !reflect.DeepEqual(got, []string{"1234", "56"}) {
+		t.Errorf("records = %q", got)
+	}
+
+	if _, err := s.StoreIO("'ME.NEW'", strings.NewReader("1234567\n"), zftp.TypeAsciiASA,
+		zftp.WithLinePolicy(zftp.LineTruncate, 3)); err != nil {
+		t.Fatalf("StoreIO(ASA): %v", err)
+	}
+	if got, _ := srv.Records("ME.NEW"); !reflect.DeepEqual(got, []string{"123"}) {
+		t.Errorf("ASA records = %q", got)
+	}
+
+	before := len(srv.Commands())
+	if _, err := s.StoreIO("'ME.NEW'", strings.NewReader("x\n"), zftp.TypeImage,
+		zftp.WithLinePolicy(zftp.LineTruncate, 10)); err == nil {
+		t.Error("expected an error for a binary transfer")
+	}
+	for _, opt := range []zftp.TransferOption{
+		zftp.WithLinePolicy(zftp.LineSpill, 10),
+		zftp.WithLinePolicy(zftp.LinePolicy(42), 10),
+	} {
+		if _, err := s.StoreIO("'ME.NEW'", strings.NewReader("x\n"), zftp.TypeAscii, opt); err == nil {
+			t.Error("expected an error")
+		}
+	}
+	if _, err := s.StoreIO("/u/me/a.txt", strings.NewReader("x\n"), zftp.TypeAscii,
+		zftp.WithLinePolicy(zftp.LineError, 0)); err == nil {
+		t.Error("expected an error for a z/OS UNIX file without a width")
+	}
+	if cmds := srv.Commands()[before:]; countPrefix(cmds, "STOR") != 0 {
+		t.Errorf("commands = %v", cmds)
+	}
+}
@@ -3,6 +3,7 @@
 package zftp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"gopkg.in/ro-ag/zftp.v2/hfs"
@@ -53,7 +54,7 @@ func (s *FTPSession) anyList(cmd, expression string) ([]string, string, error) {
 		}
 	}(child)
 
-	resp, err := s.SendCommand(CodeListOK, cmd, expression)
+	resp, err := s.send(CodeListOK, cmd, expression)
 	if err != nil {
 		return nil, resp, fmt.Errorf("error while sending list command: %w", err)
 	}
@@ -106,14 +107,11 @@ func (s *FTPSession) NList(expression string) ([]string, error) {
 
 // ListDatasets returns a list of files matching the given expression, including file attributes.
 func (s *FTPSession) ListDatasets(expression string) ([]hfs.InfoDataset, error) {
-
-	curr, err := utils.SetValueAndGetCurrent(s.log, "SEQ", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
-		return nil, err
-	}
-	defer curr.Restore()
-
-	lines, err := s.List(expression)
+	var lines []string
+	err := s.WithSite(context.Background(), SiteParams{"FILETYPE": "SEQ"}, func() (err error) {
+		lines, err = s.List(expression)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -138,15 +136,19 @@ func (s *FTPSession) ListDatasets(expression string) ([]hfs.InfoDataset, error)
 }
 
 // ListPds returns a list of files matching the given expression, including file attributes.
+// A quoted expression without wildcards must be a valid DSN; see ParseDSN.
 func (s *FTPSession) ListPds(expression string) ([]hfs.InfoPdsMember, error) {
-
-	curr, err := utils.SetValueAndGetCurrent(s.log, "SEQ", s.SetStatusOf().FileType, s.StatusOf().FileType)
-	if err != nil {
-		return nil, err
+	if !strings.ContainsAny(expression, "*%") {
+		if err := checkDSN(expression); err != nil {
+			return nil, err
+		}
 	}
-	defer curr.Restore()
 
-	lines, err := s.List(expression)
+	var lines []string
+	err := s.WithSite(context.Background(), SiteParams{"FILETYPE": "SEQ"}, func() (err error) {
+		lines, err = s.List(expression)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -175,16 +177,16 @@ func (s *FTPSession) ListSpool(expression string) ([]hfs.InfoJob, error) {
 		return nil, fmt.Errorf("invalid search pattern: %s", expression)
 	}
 
-	curr, err := utils.SetValueAndGetCurrent(s.log, "JES", s.SetStatusOf().FileType, s.StatusOf().FileType)
+	var lines []string
+	err := s.WithSite(context.Background(), SiteParams{"FILETYPE": "JES"}, func() (err error) {
+		if lines, err = s.List(expression); err != nil {
+			return fmt.Errorf("failed to list spool jobs: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer curr.Restore()
-
-	lines, err := s.List(expression)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list spool jobs: %w", err)
-	}
 
 	jobs, err := hfs.ParseInfoJob(lines)
 	if err != nil {
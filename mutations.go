@@ -7,8 +7,12 @@ import "context"
 // Delete removes a file or dataset on the server with a DELE command. name is the
 // HFS path or a quoted dataset name ('USER.DATA'). A 550 (not found / not
 // permitted) is returned as a *ReturnError; match it with errors.Is(err, CodeError(550)).
+// A malformed quoted name fails with ErrInvalidDSN before DELE is sent.
 func (s *FTPSession) Delete(name string) error {
-	_, err := s.SendCommand(CodeFileActionOK, "DELE", name)
+	if err := checkDSN(name); err != nil {
+		return err
+	}
+	_, err := s.send(CodeFileActionOK, "DELE", name)
 	return err
 }
 
@@ -16,14 +20,16 @@ func (s *FTPSession) Delete(name string) error {
 // under SITE DIRECTORYMODE, a dataset qualifier). A 550 is returned as a
 // *ReturnError.
 func (s *FTPSession) Mkdir(path string) error {
-	_, err := s.SendCommand(CodeDirCreated, "MKD", path)
+	_, err := s.send(CodeDirCreated, "MKD", path)
 	return err
 }
 
 // Chmod changes HFS file permissions via SITE CHMOD <mode> <path>. mode is an
 // octal string ("750"). It is meaningful only for z/OS UNIX (HFS) files.
 func (s *FTPSession) Chmod(mode, path string) error {
-	_, err := s.Site("CHMOD", mode, path)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.siteLocked("CHMOD", mode, path)
 	return err
 }
 
@@ -31,8 +37,13 @@ func (s *FTPSession) Chmod(mode, path string) error {
 // two round-trips are issued under a single hold of the session mutex so no other
 // goroutine's command can interleave between them, keeping *FTPSession safe to
 // share across goroutines. A failing RNFR (e.g. 550) is returned without sending
-// RNTO.
+// RNTO, and a malformed quoted name fails with ErrInvalidDSN before RNFR.
 func (s *FTPSession) Rename(from, to string) error {
+	for _, name := range []string{from, to} {
+		if err := checkDSN(name); err != nil {
+			return err
+		}
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if _, err := s.sendLocked(context.Background(), CodeNeedInfo, "RNFR", from); err != nil {
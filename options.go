@@ -18,6 +18,7 @@ type dialOptions struct {
 	dialer          Dialer
 	signalHandler   bool
 	logger          *slog.Logger
+	transferMode    TransferMode
 }
 
 // defaultReplyTimeout bounds the wait for a post-transfer control reply. It is
@@ -90,3 +91,14 @@ func WithReplyTimeout(d time.Duration) Option {
 func WithLogger(l *slog.Logger) Option {
 	return func(o *dialOptions) { o.logger = l }
 }
+
+// WithTransferMode sets the transmission mode RetrieveIO and StoreIO use, and
+// so Get, Put and the other transfers built on them, when no per-transfer option
+// chooses one. Listings and job submission always use stream mode. With
+// ModeCompressed, a server that rejects MODE C turns the default back to stream
+// mode.
+//
+//	s, err := zftp.Open("host:21", zftp.WithTransferMode(zftp.ModeCompressed))
+func WithTransferMode(m TransferMode) Option {
+	return func(o *dialOptions) { o.transferMode = m }
+}
@@ -27,7 +27,7 @@ func (s *FTPSession) SetPassiveMode() (int, error) {
 	defer cancel()
 
 	// Send PASV command
-	response, err := s.SendCommandWithContext(ctx, CodeEnteringPassiveMode, "PASV")
+	response, err := s.sendContext(ctx, CodeEnteringPassiveMode, "PASV")
 	if err != nil {
 		return 0, err
 	}
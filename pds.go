@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/internal/dsn"
+)
+
+// PdsOptions tune GetPds and PutPds.
+type PdsOptions struct {
+	// Mode is the transfer type of every member. The zero value is TypeImage,
+	// binary, as for the CLI; use TypeAscii for source and JCL.
+	Mode TransferType
+	// Include limits the transfer to the members matching one of these
+	// path.Match patterns; none means every member. A pattern matches the member
+	// name or its local file name, case-insensitively: "PAY*" and "*.cbl" both
+	// work.
+	Include []string
+	// Exclude skips the members matching one of these patterns, as Include.
+	Exclude []string
+	// Ext is appended to the member name to make the local file name in
+	// GetPds, such as ".cbl".
+	Ext string
+}
+
+// mode returns the transfer type, TypeImage by default.
+func (o PdsOptions) mode() TransferType {
+	if o.Mode == 0 {
+		return TypeImage
+	}
+	return o.Mode
+}
+
+// selects reports whether the member, with the local file name file, passes
+// Include and Exclude.
+func (o PdsOptions) selects(member, file string) bool {
+	match := func(patterns []string) bool {
+		for _, p := range patterns {
+			p = strings.ToUpper(p)
+			for _, name := range []string{member, file} {
+				if ok, _ := path.Match(p, strings.ToUpper(name)); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return (len(o.Include) == 0 || match(o.Include)) && !match(o.Exclude)
+}
+
+// MemberTransfer is the outcome of one member of GetPds or PutPds.
+type MemberTransfer struct {
+	// Member is the member name; it is empty when PutPds could not map File to
+	// one.
+	Member string
+	// File is the local path.
+	File string
+	// Err is why the member failed, or nil.
+	Err error
+}
+
+// PdsError reports the members of GetPds or PutPds that failed.
+type PdsError struct {
+	// Failed are the failed members, in transfer order.
+	Failed []MemberTransfer
+}
+
+// Error counts the failed members and shows the first.
+func (e *PdsError) Error() string {
+	first := e.Failed[0]
+	name := first.Member
+	if name == "" {
+		name = first.File
+	}
+	return fmt.Sprintf("zftp: %d member(s) failed, first %s: %s", len(e.Failed), name, first.Err)
+}
+
+// Unwrap returns the errors of the failed members, for errors.Is and errors.As.
+func (e *PdsError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, m := range e.Failed {
+		errs[i] = m.Err
+	}
+	return errs
+}
+
+// pdsResult returns the error of a bulk transfer: a *PdsError when a member
+// failed, nil otherwise.
+func pdsResult(transfers []MemberTransfer) error {
+	var failed []MemberTransfer
+	for _, m := range transfers {
+		if m.Err != nil {
+			failed = append(failed, m)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return &PdsError{Failed: failed}
+}
+
+// MemberName maps a local file name to a member name: the directory and the
+// extension are dropped and the rest is uppercased, so "src/payroll.cbl" is
+// PAYROLL. A name that is not a valid member name returns ErrInvalidDSN.
+func MemberName(file string) (string, error) {
+	base := filepath.Base(file)
+	name := strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base)))
+	if err := dsn.CheckMember(name); err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrInvalidDSN, file, err)
+	}
+	return name, nil
+}
+
+// memberDSN returns the name of member in the PDS pds, quoted when pds is.
+func memberDSN(pds DSN, member string) DSN {
+	pds.Member = member
+	return pds
+}
+
+// pdsName parses pds as the name of a partitioned dataset.
+func pdsName(pds string) (DSN, error) {
+	d, err := ParseDSN(pds)
+	if err != nil {
+		return DSN{}, err
+	}
+	if d.Member != "" || d.GDG {
+		return DSN{}, fmt.Errorf("%w: %s is not a PDS name", ErrInvalidDSN, pds)
+	}
+	return d, nil
+}
+
+// GetPds downloads the members of the PDS pds selected by opts to localDir,
+// one file per member named after it plus opts.Ext. localDir is created when
+// missing and existing files are overwritten. A member that fails does not stop
+// the others: every member tried is returned, and the failures also as a
+// *PdsError. Errors that stop the whole transfer are returned without
+// transfers, among them ErrDatasetNotFound when pds is not cataloged; an
+// existing PDS without members returns none.
+func (s *FTPSession) GetPds(pds, localDir string, opts PdsOptions) ([]MemberTransfer, error) {
+	d, err := pdsName(pds)
+	if err != nil {
+		return nil, err
+	}
+	if _, found, err := s.findDataset(d.String()); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, fmt.Errorf("%w: %s", ErrDatasetNotFound, pds)
+	}
+	// The PDS exists, so a 550 from the member listing means it has no members.
+	members, err := s.ListPdsDSN(d)
+	if err != nil && !errors.Is(err, CodeError(550)) {
+		return nil, err
+	}
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return nil, err
+	}
+	var transfers []MemberTransfer
+	for _, m := range members {
+		name := m.Name.String()
+		file := name + opts.Ext
+		if !opts.selects(name, file) {
+			continue
+		}
+		t := MemberTransfer{Member: name, File: filepath.Join(localDir, file)}
+		t.Err = s.GetDSN(memberDSN(d, name), t.File, opts.mode())
+		if t.Err != nil {
+			s.log.Debugf("failed to get member %s: %s", name, t.Err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, pdsResult(transfers)
+}
+
+// PutPds uploads the files of localDir selected by opts as members of the
+// existing PDS pds, each named by MemberName. Subdirectories are skipped. A
+// file without a valid member name, a second file mapping to the same member,
+// or a failed upload does not stop the others: every file tried is returned,
+// and the failures also as a *PdsError.
+func (s *FTPSession) PutPds(localDir, pds string, opts PdsOptions) ([]MemberTransfer, error) {
+	d, err := pdsName(pds)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		return nil, err
+	}
+	var transfers []MemberTransfer
+	seen := map[string]string{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		t := MemberTransfer{File: filepath.Join(localDir, e.Name())}
+		t.Member, t.Err = MemberName(e.Name())
+		if !opts.selects(t.Member, e.Name()) {
+			continue
+		}
+		if prev, dup := seen[t.Member]; t.Err == nil && dup {
+			t.Err = fmt.Errorf("member %s is also uploaded from %s", t.Member, prev)
+		}
+		if t.Err == nil {
+			seen[t.Member] = e.Name()
+			t.Err = s.PutDSN(t.File, memberDSN(d, t.Member), opts.mode())
+		}
+		if t.Err != nil {
+			s.log.Debugf("failed to put %s: %s", t.File, t.Err)
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, pdsResult(transfers)
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+func TestMemberName(t *testing.T) {
+	for file, want := range map[string]string{
+		"payroll.cbl":     "PAYROLL",
+		"src/Tax$1.jcl":   "TAX$1",
+		"#INIT":           "#INIT",
+		"archive.tar.gz":  "",
+		"toolongname.cbl": "",
+		"1st.cbl":         "",
+		"my-job.jcl":      "",
+	} {
+		got, err := zftp.MemberName(file)
+		if want == "" {
+			if !errors.Is(err, zftp.ErrInvalidDSN) {
+				t.Errorf("MemberName(%q) = %q, %v; want ErrInvalidDSN", file, got, err)
+			}
+			continue
+		}
+		if err != nil || got != want {
+			t.Errorf("MemberName(%q) = %q, %v; want %q", file, got, err, want)
+		}
+	}
+}
+
+// TestGetPds checks the selected members are downloaded, one file each.
+func TestGetPds(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddPDS("ME.SRC", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800})
+	srv.AddMember("ME.SRC", "PAYROLL", "PAY LINE")
+	srv.AddMember("ME.SRC", "PAYTAX", "TAX LINE")
+	srv.AddMember("ME.SRC", "README", "DOCS")
+	dir := filepath.Join(t.TempDir(), "src")
+
+	got, err := s.GetPds("'ME.SRC'", dir, zftp.PdsOptions{
+		Mode: zftp.TypeAscii, Include: []string{"pay*"}, Exclude: []string{"*TAX.cbl"}, Ext: ".cbl",
+	})
+	if err != nil {
+		t.Fatalf("GetPds: %v", err)
+	}
+	want := []zftp.MemberTransfer{{Member: "PAYROLL", File: filepath.Join(dir, "PAYROLL.cbl")}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transfers = %+v, want %+v", got, want)
+	}
+	if b, _ := os.ReadFile(want[0].File); string(b) != "PAY LINE\n" {
+		t.Errorf("PAYROLL.cbl = %q", b)
+	}
+	if !hasCmd(srv.Commands(), "RETR 'ME.SRC(PAYROLL)'") {
+		t.Errorf("commands = %v", srv.Commands())
+	}
+
+	srv.AddPDS("ME.EMPTY", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800})
+	if got, err := s.GetPds("'ME.EMPTY'", dir, zftp.PdsOptions{}); err != nil || len(got) != 0 {
+		t.Errorf("GetPds(empty) = %+v, %v", got, err)
+	}
+	if _, err := s.GetPds("'ME.SRCX'", dir, zftp.PdsOptions{}); !errors.Is(err, zftp.ErrDatasetNotFound) {
+		t.Errorf("GetPds(missing): got %v, want ErrDatasetNotFound", err)
+	}
+}
+
+// TestPutPds checks files become members, and that unmappable and duplicate
+// names are collected without stopping the rest.
+func TestPutPds(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddPDS("ME.JCL", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 800})
+	dir := t.TempDir()
+	for name, text := range map[string]string{
+		"build.jcl":       "//BUILD JOB\n",
+		"build.txt":       "//DUP JOB\n",
+		"deploy.jcl":      "//DEPLOY JOB\n",
+		"much-too-long.x": "x\n",
+		"notes.md":        "skip\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.PutPds(dir, "'ME.JCL'", zftp.PdsOptions{Mode: zftp.TypeAscii, Exclude: []string{"*.md"}})
+	var pe *zftp.PdsError
+	if !errors.As(err, &pe) || len(pe.Failed) != 2 {
+		t.Fatalf("PutPds: got %v, want two failed members", err)
+	}
+	if pe.Failed[0].File != filepath.Join(dir, "build.txt") || pe.Failed[1].Member != "" ||
+		!errors.Is(pe.Failed[1].Err, zftp.ErrInvalidDSN) || !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("failed = %+v", pe.Failed)
+	}
+	if len(got) != 4 {
+		t.Errorf("transfers = %+v", got)
+	}
+	for member, want := range map[string][]string{"BUILD": {"//BUILD JOB"}, "DEPLOY": {"//DEPLOY JOB"}} {
+		if recs, _ := srv.Records("ME.JCL(" + member + ")"); !reflect.DeepEqual(recs, want) {
+			t.Errorf("%s = %q, want %q", member, recs, want)
+		}
+	}
+
+	if _, err := s.PutPds(dir, "'ME.JCL(X)'", zftp.PdsOptions{}); !errors.Is(err, zftp.ErrInvalidDSN) {
+		t.Errorf("PutPds(member): got %v, want ErrInvalidDSN", err)
+	}
+}
@@ -21,7 +21,15 @@ const (
 //   - mode is the transfer mode, either ASCII or binary.
 //
 // Supports dataset specification as variadic arguments (the same as SetDataSpecs(a ...DataSpec))
+//
+// A malformed quoted dataset name fails with ErrInvalidDSN before anything is
+// sent; PutDSN takes a parsed DSN. A relative GDG reference such as BASE(+1)
+// that the server rejects as already existing is retried with the absolute
+// generation name; see ResolveGeneration.
 func (s *FTPSession) Put(srcLocal string, destRemote string, mode TransferType, a ...DataSpec) error {
+	if err := checkDSN(destRemote); err != nil {
+		return err
+	}
 
 	if len(a) > 0 {
 		s.log.Debug("dataset attributes passed to Put()")
@@ -56,7 +64,14 @@ func (s *FTPSession) Put(srcLocal string, destRemote string, mode TransferType,
 
 	s.log.Debugf("starting transfer to: %s", destRemote)
 
-	bytesTransferred, err := s.StoreIO(destRemote, file, mode)
+	var bytesTransferred int64
+	err = s.retryGeneration(destRemote, storRejections, func(remote string) error {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		bytesTransferred, err = s.StoreIO(remote, file, mode)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("failed to store file: %w", err)
 	}
@@ -229,6 +244,12 @@ func WithBlkSize(size uint16) DataSpec {
 //   - WithLrecl(length uint16) - record length
 //   - WithBlkSize(size uint16) - block size
 //   - a Recfm constant (e.g. RecfmFB) - record format
+//   - a SpaceUnit constant (e.g. SpaceCylinders) - unit of the space quantities
+//   - WithPrimary, WithSecondary, WithDirectory - space and directory blocks
+//   - a DSNType constant (e.g. DSNTypeLibrary) - dataset type
+//   - WithVolume, WithUnit - where the dataset is allocated
+//   - WithDataClass, WithMgmtClass, WithStorageClass - SMS classes
+//   - WithRetPD, WithEATTR - retention period and extended attributes
 //
 // this function sends a SITE command to the server to set the attributes.
 func (s *FTPSession) SetDataSpecs(attributes ...DataSpec) error {
@@ -248,11 +269,11 @@ func (s *FTPSession) SetDataSpecs(attributes ...DataSpec) error {
 		cmd.WriteString(" ")
 	}
 
-	msg, err := s.Site(cmd.String())
+	msg, err := s.setSite(cmd.String())
 	if err != nil {
 		return err
 	}
-	if msg != "SITE command was accepted" {
+	if msg != "" && msg != "SITE command was accepted" {
 		s.log.Warning(utils.WrapText(msg))
 	}
 	return nil
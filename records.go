@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+
+	"gopkg.in/ro-ag/zftp.v2/internal/utils"
+	"gopkg.in/ro-ag/zftp.v2/records"
+)
+
+// RetrieveRecords downloads a variable-length dataset (RECFM=V, VB, VS or VBS)
+// in binary with SITE RDW in effect and calls fn with each record, without its
+// RDW, as it arrives. Spanned records are reassembled. The RDW setting is
+// restored after the transfer.
+//
+// Returning an error from fn stops the calls and RetrieveRecords returns that
+// error, but only once the whole dataset has arrived: the rest is still
+// downloaded and discarded, so the transfer ends with its normal reply and the
+// session stays usable. Aborting it instead would leave a 426 that closes the
+// session. The record slice is not reused and may be retained by fn.
+func (s *FTPSession) RetrieveRecords(remote string, fn func(record []byte) error) error {
+	curr, err := utils.SetValueAndGetCurrent(s.log, "RDW", s.setRDW, s.knownOr("RDW", s.currentRDW))
+	if err != nil {
+		return err
+	}
+	defer curr.Restore()
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		rd := records.NewReader(pr)
+		var rerr error
+		for {
+			var rec []byte
+			if rec, rerr = rd.Read(); rerr != nil {
+				break
+			}
+			if rerr = fn(rec); rerr != nil {
+				break
+			}
+		}
+		if rerr == io.EOF {
+			rerr = nil
+		}
+		// Drain what is left so the transfer ends normally: failing it mid-stream
+		// would close the session. This is what stopping early costs.
+		_, _ = io.Copy(io.Discard, pr)
+		done <- rerr
+	}()
+
+	_, err = s.RetrieveIO(remote, pw, TypeImage)
+	_ = pw.CloseWithError(err)
+	if rerr := <-done; rerr != nil {
+		return rerr
+	}
+	return err
+}
+
+// StoreRecords uploads recs to a variable-length dataset in binary with SITE
+// RDW in effect, so every record keeps its boundary whatever bytes it holds. The
+// RDW setting is restored after the transfer. A new dataset takes the SITE
+// attributes in effect (see SetDataSpecs); records longer than
+// records.MaxRecord are rejected with records.ErrRecordTooLong.
+func (s *FTPSession) StoreRecords(remote string, recs [][]byte) (int64, error) {
+	curr, err := utils.SetValueAndGetCurrent(s.log, "RDW", s.setRDW, s.knownOr("RDW", s.currentRDW))
+	if err != nil {
+		return 0, err
+	}
+	defer curr.Restore()
+
+	pr, pw := io.Pipe()
+	go func() {
+		_ = pw.CloseWithError(records.NewWriter(pw).WriteAll(recs))
+	}()
+	sz, err := s.StoreIO(remote, pr, TypeImage)
+	_ = pr.CloseWithError(err) // unblock the writer if the transfer stopped early
+	return sz, err
+}
+
+// ErrNotFixedRecords is returned by RetrieveFixed when it looks up a dataset
+// whose record format is not fixed-length (RECFM=F, FB, FBA, …), and by
+// RetrieveDatasetRecords for one that is neither fixed nor variable-length.
+var ErrNotFixedRecords = errors.New("zftp: dataset does not have fixed-length records")
+
+// RetrieveFixed downloads a fixed-length dataset (RECFM=F or FB) in binary and
+// yields its records of lrecl bytes as they arrive, so packed and binary fields
+// come through untouched. With lrecl 0 the record format and length are looked
+// up with ListDatasets (a PDS member takes its library's); a dataset that is not
+// fixed-length yields ErrNotFixedRecords.
+//
+// A download that is not a whole number of records yields an error wrapping
+// records.ErrPartialRecord after the last whole record. Transfer and lookup
+// errors are yielded as the final element. Breaking out of the loop still costs
+// the rest of the download, which is read and discarded before the loop ends so
+// the session stays usable, as in RetrieveRecords.
+//
+//	for rec, err := range s.RetrieveFixed("'HLQ.MASTER'", 0) {
+//		if err != nil {
+//			return err
+//		}
+//		process(rec)
+//	}
+func (s *FTPSession) RetrieveFixed(remote string, lrecl int) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		if lrecl <= 0 {
+			var err error
+			if lrecl, err = s.fixedLrecl(remote); err != nil {
+				yield(nil, err)
+				return
+			}
+		}
+
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.RetrieveIO(remote, pw, TypeImage)
+			_ = pw.CloseWithError(err)
+			done <- err
+		}()
+
+		rd := records.NewFixedReader(pr, lrecl)
+		for {
+			rec, err := rd.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// Either the transfer's own error, passed through the pipe, or a
+				// trailing partial record after a complete transfer.
+				_ = pr.CloseWithError(err)
+				<-done
+				yield(nil, err)
+				return
+			}
+			if !yield(rec, nil) {
+				_, _ = io.Copy(io.Discard, pr)
+				<-done
+				return
+			}
+		}
+		if err := <-done; err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// fixedLrecl looks up the LRECL of the dataset holding remote and checks its
+// record format is fixed-length.
+func (s *FTPSession) fixedLrecl(remote string) (int, error) {
+	recfm, lrecl, err := s.recordFormat(remote)
+	if err != nil {
+		return 0, err
+	}
+	if !strings.HasPrefix(recfm, "F") {
+		return 0, fmt.Errorf("%w: %s is RECFM=%s", ErrNotFixedRecords, remote, recfm)
+	}
+	if lrecl == 0 {
+		return 0, fmt.Errorf("%s lists no LRECL", remote)
+	}
+	return lrecl, nil
+}
+
+// recordFormat looks up the RECFM and LRECL of the dataset holding remote with
+// a listing; a PDS member takes its library's.
+func (s *FTPSession) recordFormat(remote string) (string, int, error) {
+	ds, found, err := s.findDataset(remote)
+	if err != nil {
+		return "", 0, fmt.Errorf("looking up %s: %w", remote, err)
+	}
+	if !found {
+		return "", 0, fmt.Errorf("looking up %s: %w", remote, ErrDatasetNotFound)
+	}
+	return ds.Recfm.String(), int(ds.Lrecl.Value()), nil
+}
+
+// errStopRecords ends RetrieveRecords when a RetrieveDatasetRecords loop breaks.
+var errStopRecords = errors.New("zftp: record loop stopped")
+
+// RetrieveDatasetRecords downloads a dataset in binary and yields its records,
+// choosing RetrieveFixed or RetrieveRecords from the record format ListDatasets
+// reports: fixed-length records are yielded LRECL bytes at a time, and
+// variable-length ones without their RDWs. Other record formats (such as
+// RECFM=U) yield ErrNotFixedRecords. This is the input a copybook.Layout
+// decodes.
+//
+// Errors are yielded as the final element; breaking out of the loop still
+// downloads and discards the rest of the dataset, as in RetrieveRecords.
+func (s *FTPSession) RetrieveDatasetRecords(remote string) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		recfm, lrecl, err := s.recordFormat(remote)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		switch {
+		case strings.HasPrefix(recfm, "F"):
+			for rec, err := range s.RetrieveFixed(remote, lrecl) {
+				if !yield(rec, err) {
+					return
+				}
+			}
+		case strings.HasPrefix(recfm, "V"):
+			err := s.RetrieveRecords(remote, func(rec []byte) error {
+				if !yield(rec, nil) {
+					return errStopRecords
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errStopRecords) {
+				yield(nil, err)
+			}
+		default:
+			yield(nil, fmt.Errorf("%w: %s is RECFM=%s", ErrNotFixedRecords, remote, recfm))
+		}
+	}
+}
+
+// setRDW and currentRDW adapt the RDW setter and getter to the "RDW"/"NORDW"
+// values utils.SetValueAndGetCurrent saves and restores.
+func (s *FTPSession) setRDW(value string) error {
+	return s.SetStatusOf().RDW(value == "RDW")
+}
+
+func (s *FTPSession) currentRDW() (string, error) {
+	return statusRDW(s.StatusOf())
+}
+
+// statusRDW reads the RDW setting through st as "RDW" or "NORDW".
+func statusRDW(st *ServerStatus) (string, error) {
+	resp, err := st.RDW()
+	if err != nil {
+		return "", err
+	}
+	return rdwValue(resp)
+}
+
+// rdwValue turns an RDW status line into "RDW" or "NORDW".
+func rdwValue(resp string) (string, error) {
+	// "RDWs from variable format datasets are retained as part of data." or
+	// "... are discarded." (some levels say "are not retained").
+	switch {
+	case strings.Contains(resp, "not retained"), strings.Contains(resp, "discarded"):
+		return "NORDW", nil
+	case strings.Contains(resp, "retained"):
+		return "RDW", nil
+	default:
+		return "", fmt.Errorf("could not parse RDW status %q", resp)
+	}
+}
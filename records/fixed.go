@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package records
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrPartialRecord is returned by a FixedReader when the stream ends part way
+// through a record, so its length is not a whole number of records. It usually
+// means the LRECL is wrong or the transfer was not binary.
+var ErrPartialRecord = errors.New("records: stream is not a whole number of fixed-length records")
+
+// FixedReader splits the undelimited byte stream of a binary RECFM=F or FB
+// download into records of LRECL bytes.
+type FixedReader struct {
+	r     io.Reader
+	lrecl int
+	n     int64 // records read so far
+}
+
+// NewFixedReader returns a FixedReader cutting r into records of lrecl bytes.
+// It panics if lrecl is not positive.
+func NewFixedReader(r io.Reader, lrecl int) *FixedReader {
+	if lrecl <= 0 {
+		panic(fmt.Sprintf("records: invalid LRECL %d", lrecl))
+	}
+	return &FixedReader{r: r, lrecl: lrecl}
+}
+
+// Read returns the next record. It returns io.EOF after the last whole record
+// and ErrPartialRecord when the stream ends inside one. The returned slice is
+// newly allocated on every call.
+func (r *FixedReader) Read() ([]byte, error) {
+	rec := make([]byte, r.lrecl)
+	n, err := io.ReadFull(r.r, rec)
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		return nil, fmt.Errorf("%w: %d trailing bytes after %d records of %d", ErrPartialRecord, n, r.n, r.lrecl)
+	case err != nil:
+		return nil, err
+	}
+	r.n++
+	return rec, nil
+}
+
+// ReadAll reads every remaining record.
+func (r *FixedReader) ReadAll() ([][]byte, error) {
+	var recs [][]byte
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package records splits binary z/OS FTP transfers into records.
+//
+// A FixedReader cuts the undelimited stream of a RECFM=F or FB dataset at LRECL.
+// A Reader and Writer handle the stream z/OS FTP sends for a variable-length
+// dataset (RECFM=V, VB, VS or VBS) when SITE RDW is in effect and the transfer
+// is binary. Each record is preceded by a 4-byte record descriptor word (RDW): a
+// big-endian halfword holding the record length plus the four RDW bytes,
+// followed by two bytes of zeros.
+//
+// In a spanned dataset (VS/VBS) a logical record may be split into segments,
+// each preceded by a segment descriptor word (SDW) whose third byte tells
+// whether the segment is complete, the first, a middle or the last piece of a
+// record. A Reader reassembles segments into whole records, and a Writer made
+// with NewSpannedWriter cuts long records into segments.
+package records
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DescriptorSize is the length of an RDW or SDW.
+const DescriptorSize = 4
+
+// MaxRecord is the longest record a non-spanned variable dataset holds: the
+// largest LRECL, 32756, less the RDW.
+const MaxRecord = 32752
+
+// Segment control codes carried in the third byte of an SDW.
+const (
+	segComplete = 0x00
+	segFirst    = 0x01
+	segLast     = 0x02
+	segMiddle   = 0x03
+)
+
+// ErrRecordTooLong is returned by a Writer for a record that does not fit in
+// one RDW (MaxRecord bytes) and the Writer does not span records.
+var ErrRecordTooLong = errors.New("records: record longer than a variable record can hold")
+
+// ErrBadDescriptor is returned by a Reader when the stream does not hold a
+// valid RDW or SDW where one is expected, or when spanned segments arrive out
+// of order. It usually means the transfer ran without SITE RDW, in ASCII mode,
+// or against a dataset that is not variable-length.
+var ErrBadDescriptor = errors.New("records: invalid record descriptor word")
+
+// Reader reads RDW-prefixed records from an io.Reader.
+type Reader struct {
+	r      *bufio.Reader
+	hdr    [DescriptorSize]byte
+	offset int64 // stream offset of the next descriptor, for error messages
+}
+
+// NewReader returns a Reader reading from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Read returns the next record, without its descriptor, reassembling spanned
+// segments. It returns io.EOF at the end of a well-formed stream and
+// io.ErrUnexpectedEOF when the stream ends inside a record. The returned slice
+// is newly allocated on every call.
+func (r *Reader) Read() ([]byte, error) {
+	var rec []byte
+	spanning := false
+	for {
+		code, data, err := r.segment()
+		switch {
+		case err == io.EOF && spanning:
+			return nil, io.ErrUnexpectedEOF
+		case err != nil:
+			return nil, err
+		}
+		switch {
+		case code == segComplete && !spanning:
+			return data, nil
+		case code == segFirst && !spanning:
+			rec, spanning = data, true
+		case code == segMiddle && spanning:
+			rec = append(rec, data...)
+		case code == segLast && spanning:
+			return append(rec, data...), nil
+		default:
+			return nil, fmt.Errorf("%w: segment code %#02x out of sequence at offset %d", ErrBadDescriptor, code, r.offset-int64(len(data))-DescriptorSize)
+		}
+	}
+}
+
+// ReadAll reads every remaining record.
+func (r *Reader) ReadAll() ([][]byte, error) {
+	var recs [][]byte
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return recs, nil
+		}
+		if err != nil {
+			return recs, err
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// segment reads one descriptor and the data it covers.
+func (r *Reader) segment() (code byte, data []byte, err error) {
+	if _, err := io.ReadFull(r.r, r.hdr[:]); err != nil {
+		return 0, nil, err // io.EOF between records, io.ErrUnexpectedEOF inside one
+	}
+	length := int(binary.BigEndian.Uint16(r.hdr[:2]))
+	code = r.hdr[2] &^ 0x03
+	if length < DescriptorSize || code != 0 || r.hdr[3] != 0 {
+		return 0, nil, fmt.Errorf("%w: % X at offset %d", ErrBadDescriptor, r.hdr, r.offset)
+	}
+	data = make([]byte, length-DescriptorSize)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	r.offset += int64(length)
+	return r.hdr[2] & 0x03, data, nil
+}
+
+// Writer writes records to an io.Writer, each preceded by its RDW.
+type Writer struct {
+	w       io.Writer
+	segment int // longest segment data; 0 writes every record whole
+	buf     []byte
+}
+
+// NewWriter returns a Writer for a non-spanned variable dataset (V or VB).
+// Records longer than MaxRecord are rejected with ErrRecordTooLong.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// NewSpannedWriter returns a Writer for a spanned dataset (VS or VBS). A record
+// longer than segment bytes is written as several segments of at most segment
+// bytes each; segment is clamped to MaxRecord. A dataset's segments are bounded
+// by its BLKSIZE less the block and segment descriptors, so BLKSIZE-8 is the
+// usual choice.
+func NewSpannedWriter(w io.Writer, segment int) *Writer {
+	if segment <= 0 || segment > MaxRecord {
+		segment = MaxRecord
+	}
+	return &Writer{w: w, segment: segment}
+}
+
+// Write writes one record.
+func (w *Writer) Write(record []byte) error {
+	if w.segment == 0 || len(record) <= w.segment {
+		if len(record) > MaxRecord {
+			return fmt.Errorf("%w: %d bytes", ErrRecordTooLong, len(record))
+		}
+		return w.put(segComplete, record)
+	}
+	for i := 0; i < len(record); i += w.segment {
+		end := min(i+w.segment, len(record))
+		code := byte(segMiddle)
+		switch {
+		case i == 0:
+			code = segFirst
+		case end == len(record):
+			code = segLast
+		}
+		if err := w.put(code, record[i:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAll writes every record in recs.
+func (w *Writer) WriteAll(recs [][]byte) error {
+	for _, rec := range recs {
+		if err := w.Write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Writer) put(code byte, data []byte) error {
+	w.buf = binary.BigEndian.AppendUint16(w.buf[:0], uint16(len(data)+DescriptorSize))
+	w.buf = append(w.buf, code, 0)
+	w.buf = append(w.buf, data...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package records_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/ro-ag/zftp.v2/records"
+)
+
+// TestRoundTrip writes records with a plain Writer and reads them back,
+// including an empty record, and checks the RDW bytes on the wire.
+func TestRoundTrip(t *testing.T) {
+	in := [][]byte{[]byte("HI"), {}, {0x00, 0x0C, 0xFF}}
+	var buf bytes.Buffer
+	if err := records.NewWriter(&buf).WriteAll(in); err != nil {
+		t.Fatalf("WriteAll: %v", err)
+	}
+	want := []byte{0, 6, 0, 0, 'H', 'I', 0, 4, 0, 0, 0, 7, 0, 0, 0x00, 0x0C, 0xFF}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("wire = % X, want % X", buf.Bytes(), want)
+	}
+	out, err := records.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !slices.EqualFunc(in, out, bytes.Equal) {
+		t.Errorf("read %q, want %q", out, in)
+	}
+}
+
+// TestSpanned cuts a long record into first/middle/last segments and checks the
+// Reader reassembles it between two unspanned records.
+func TestSpanned(t *testing.T) {
+	long := []byte(strings.Repeat("ABCDEFGHIJ", 3)) // 30 bytes in 12-byte segments
+	var buf bytes.Buffer
+	w := records.NewSpannedWriter(&buf, 12)
+	if err := w.WriteAll([][]byte{[]byte("A"), long, []byte("Z")}); err != nil {
+		t.Fatalf("WriteAll: %v", err)
+	}
+	codes := []byte{}
+	for b := buf.Bytes(); len(b) > 0; {
+		n := int(b[0])<<8 | int(b[1])
+		codes = append(codes, b[2])
+		b = b[n:]
+	}
+	if !bytes.Equal(codes, []byte{0, 1, 3, 2, 0}) {
+		t.Errorf("segment codes = %v", codes)
+	}
+	out, err := records.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if len(out) != 3 || !bytes.Equal(out[1], long) || string(out[2]) != "Z" {
+		t.Errorf("read %q", out)
+	}
+}
+
+// TestErrors covers a record too long for an unspanned Writer, garbage where an
+// RDW belongs, an orphaned middle segment and a stream cut inside a record.
+func TestErrors(t *testing.T) {
+	if err := records.NewWriter(io.Discard).Write(make([]byte, records.MaxRecord+1)); !errors.Is(err, records.ErrRecordTooLong) {
+		t.Errorf("long record: err = %v", err)
+	}
+	for name, tc := range map[string]struct {
+		in   []byte
+		want error
+	}{
+		"text":      {[]byte("HELLO WORLD\n"), records.ErrBadDescriptor},
+		"short rdw": {[]byte{0, 2, 0, 0}, records.ErrBadDescriptor},
+		"orphan":    {[]byte{0, 5, 3, 0, 'X'}, records.ErrBadDescriptor},
+		"cut data":  {[]byte{0, 9, 0, 0, 'A', 'B'}, io.ErrUnexpectedEOF},
+		"cut rdw":   {[]byte{0, 5, 0, 0, 'A', 0, 9}, io.ErrUnexpectedEOF},
+		"cut span":  {[]byte{0, 5, 1, 0, 'A'}, io.ErrUnexpectedEOF},
+	} {
+		_, err := records.NewReader(bytes.NewReader(tc.in)).ReadAll()
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
+
+// TestFixedReader cuts a stream at LRECL and reports trailing bytes that do not
+// make a whole record.
+func TestFixedReader(t *testing.T) {
+	recs, err := records.NewFixedReader(strings.NewReader("AAAABBBBCCCC"), 4).ReadAll()
+	if err != nil || len(recs) != 3 || string(recs[2]) != "CCCC" {
+		t.Errorf("ReadAll = %q, %v", recs, err)
+	}
+	recs, err = records.NewFixedReader(strings.NewReader("AAAABBBBCC"), 4).ReadAll()
+	if !errors.Is(err, records.ErrPartialRecord) || len(recs) != 2 {
+		t.Errorf("partial: %q, %v", recs, err)
+	}
+	if recs, err := records.NewFixedReader(strings.NewReader(""), 80).ReadAll(); err != nil || len(recs) != 0 {
+		t.Errorf("empty: %q, %v", recs, err)
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+	"gopkg.in/ro-ag/zftp.v2/records"
+)
+
+// TestRecords_StoreAndRetrieve uploads records holding line-end and zero bytes
+// to a VB dataset with StoreRecords, reads them back with RetrieveRecords, and
+// checks SITE RDW is set for each transfer and put back to NORDW afterwards.
+func TestRecords_StoreAndRetrieve(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	if err := s.SetDataSpecs(zftp.RecfmVB, zftp.WithLrecl(84), zftp.WithBlkSize(27998)); err != nil {
+		t.Fatal(err)
+	}
+
+	in := []string{"FIRST", "LINE\nBREAK", "\x00\x00\x0c", "LAST"}
+	recs := make([][]byte, len(in))
+	for i, r := range in {
+		recs[i] = []byte(r)
+	}
+	if _, err := s.StoreRecords("'HLQ.VB.DATA'", recs); err != nil {
+		t.Fatalf("StoreRecords: %v", err)
+	}
+	if got, ok := srv.Records("HLQ.VB.DATA"); !ok || !slices.Equal(got, in) {
+		t.Fatalf("stored records = %q (ok=%v)", got, ok)
+	}
+
+	var got []string
+	if err := s.RetrieveRecords("'HLQ.VB.DATA'", func(r []byte) error {
+		got = append(got, string(r))
+		return nil
+	}); err != nil {
+		t.Fatalf("RetrieveRecords: %v", err)
+	}
+	if !slices.Equal(got, in) {
+		t.Errorf("retrieved %q, want %q", got, in)
+	}
+	if rdw, err := s.StatusOf().RDW(); err != nil || rdw != "RDWs from variable format datasets are not retained as part of data." {
+		t.Errorf("RDW after transfers = %q, %v", rdw, err)
+	}
+	if n := countCmd(srv.Commands(), "SITE RDW"); n != 2 {
+		t.Errorf("SITE RDW sent %d times, want 2; commands=%v", n, srv.Commands())
+	}
+}
+
+// TestRecords_ConsumerError stops calling back after the callback fails,
+// returns its error, and leaves the session usable.
+func TestRecords_ConsumerError(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("HLQ.VB", mockzos.Attrs{Recfm: "VB", Lrecl: 84}, "ONE", "TWO", "THREE")
+
+	stop := errors.New("enough")
+	n := 0
+	err := s.RetrieveRecords("'HLQ.VB'", func([]byte) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("err = %v after %d records, want %v after 1", err, n, stop)
+	}
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); err != nil {
+		t.Errorf("session unusable after the callback failed: %v", err)
+	}
+}
+
+// TestRecords_RetrieveFixed looks up a FB dataset's LRECL with ListDatasets and
+// iterates its padded binary records, then covers an explicit LRECL that does
+// not divide the download, a variable dataset, and stopping early.
+func TestRecords_RetrieveFixed(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("HLQ.FB", mockzos.Attrs{Recfm: "FB", Lrecl: 10, BlkSize: 100}, "ALPHA", "\x00\x01\x2c", "GAMMA")
+	srv.AddDataset("HLQ.VB", mockzos.Attrs{Recfm: "VB", Lrecl: 84}, "ONE")
+
+	var got []string
+	for rec, err := range s.RetrieveFixed("'HLQ.FB'", 0) {
+		if err != nil {
+			t.Fatalf("RetrieveFixed: %v", err)
+		}
+		got = append(got, string(rec))
+	}
+	want := []string{"ALPHA     ", "\x00\x01\x2c       ", "GAMMA     "}
+	if !slices.Equal(got, want) {
+		t.Errorf("records = %q, want %q", got, want)
+	}
+
+	var last error
+	n := 0
+	for _, err := range s.RetrieveFixed("'HLQ.FB'", 7) {
+		if err != nil {
+			last = err
+			break
+		}
+		n++
+	}
+	if !errors.Is(last, records.ErrPartialRecord) || n != 4 {
+		t.Errorf("LRECL 7: %d records, err = %v; want 4 and ErrPartialRecord", n, last)
+	}
+
+	for _, err := range s.RetrieveFixed("'HLQ.VB'", 0) {
+		if !errors.Is(err, zftp.ErrNotFixedRecords) {
+			t.Errorf("VB dataset: err = %v, want ErrNotFixedRecords", err)
+		}
+	}
+
+	for range s.RetrieveFixed("'HLQ.FB'", 10) {
+		break
+	}
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); err != nil {
+		t.Errorf("session unusable after stopping early: %v", err)
+	}
+}
+
+// TestRecords_RetrieveDatasetRecords picks fixed or RDW records from the
+// record format, and stops cleanly when the loop breaks.
+func TestRecords_RetrieveDatasetRecords(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("HLQ.FB", mockzos.Attrs{Recfm: "FB", Lrecl: 4, BlkSize: 40}, "AB", "CDEF")
+	srv.AddDataset("HLQ.VB", mockzos.Attrs{Recfm: "VB", Lrecl: 84}, "ONE", "", "THREE")
+	srv.AddDataset("HLQ.U", mockzos.Attrs{Recfm: "U", Lrecl: 0, BlkSize: 6144}, "LOAD")
+	srv.AddDataset("HLQ.FBX", mockzos.Attrs{Recfm: "VB", Lrecl: 84}, "NOT THIS ONE")
+	srv.AddPDS("HLQ.LIB", mockzos.Attrs{Recfm: "FB", Lrecl: 4, BlkSize: 40})
+	srv.AddMember("HLQ.LIB", "MEM", "WXYZ")
+
+	collect := func(dsn string) ([]string, error) {
+		var got []string
+		for rec, err := range s.RetrieveDatasetRecords(dsn) {
+			if err != nil {
+				return got, err
+			}
+			got = append(got, string(rec))
+		}
+		return got, nil
+	}
+	if got, err := collect("'HLQ.FB'"); err != nil || !slices.Equal(got, []string{"AB  ", "CDEF"}) {
+		t.Errorf("FB records = %q, %v", got, err)
+	}
+	if got, err := collect("'HLQ.VB'"); err != nil || !slices.Equal(got, []string{"ONE", "", "THREE"}) {
+		t.Errorf("VB records = %q, %v", got, err)
+	}
+	if got, err := collect("'HLQ.LIB(MEM)'"); err != nil || !slices.Equal(got, []string{"WXYZ"}) {
+		t.Errorf("member records = %q, %v", got, err)
+	}
+	if _, err := collect("'HLQ.NONE'"); !errors.Is(err, zftp.ErrDatasetNotFound) {
+		t.Errorf("missing dataset: err = %v, want ErrDatasetNotFound", err)
+	}
+	if _, err := collect("'HLQ.U'"); !errors.Is(err, zftp.ErrNotFixedRecords) {
+		t.Errorf("RECFM=U: err = %v, want ErrNotFixedRecords", err)
+	}
+
+	for range s.RetrieveDatasetRecords("'HLQ.VB'") {
+		break
+	}
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); err != nil {
+		t.Errorf("session unusable after stopping early: %v", err)
+	}
+}
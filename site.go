@@ -4,26 +4,74 @@ package zftp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"strings"
 )
 
 // Site sends the SITE command to the FTP server and returns the raw response,
 // translating z/OS "Unrecognized parameter" / "Parameter ignored" replies into
 // errors.
+//
+// The session keeps track of the SITE and TYPE values the library sets and
+// reads, to skip commands that would not change them. A raw Site command may
+// change any of them, so it makes the session forget them all; the
+// SetStatusOf setters keep track instead.
 func (s *FTPSession) Site(subCommand string, a ...string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.known.forget()
 	return s.siteLocked(subCommand, a...)
 }
 
+// setSite is Site for the settings the library makes: a command that would not
+// change what the session knows is skipped, returning "", and the parameters
+// the server accepts are remembered.
+func (s *FTPSession) setSite(subCommand string, a ...string) (string, error) {
+	return s.setSiteContext(context.Background(), subCommand, a...)
+}
+
+// setSiteContext is setSite bounded by ctx.
+func (s *FTPSession) setSiteContext(ctx context.Context, subCommand string, a ...string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setSiteLockedContext(ctx, subCommand, a...)
+}
+
+// setSiteLocked is setSite for a caller that holds s.mu.
+func (s *FTPSession) setSiteLocked(subCommand string, a ...string) (string, error) {
+	return s.setSiteLockedContext(context.Background(), subCommand, a...)
+}
+
+func (s *FTPSession) setSiteLockedContext(ctx context.Context, subCommand string, a ...string) (string, error) {
+	params := strings.Join(append([]string{subCommand}, a...), " ")
+	if s.known.covers(params) {
+		return "", nil
+	}
+	msg, err := s.siteLockedContext(ctx, subCommand, a...)
+	if err != nil {
+		s.known.forgetParams(params)
+		return "", err
+	}
+	s.known.remember(params)
+	return msg, nil
+}
+
 // siteLocked issues a single SITE subcommand and interprets z/OS rejection
 // replies. The caller must hold s.mu.
 func (s *FTPSession) siteLocked(subCommand string, a ...string) (string, error) {
+	return s.siteLockedContext(context.Background(), subCommand, a...)
+}
+
+// siteLockedContext is siteLocked bounded by ctx.
+func (s *FTPSession) siteLockedContext(ctx context.Context, subCommand string, a ...string) (string, error) {
 	args := strings.Join(a, " ")
 	subCommand = strings.TrimSpace(strings.ToUpper(subCommand))
 	subCommandWithArgs := fmt.Sprintf("%s %s", subCommand, args)
-	str, err := s.sendLocked(context.Background(), CodeCmdOK, "SITE", subCommandWithArgs)
+	str, err := s.sendLocked(ctx, CodeCmdOK, "SITE", subCommandWithArgs)
 	lines := strings.Split(str, "\n")
 	switch {
 	case err != nil:
@@ -39,13 +87,156 @@ func (s *FTPSession) siteLocked(subCommand string, a ...string) (string, error)
 
 // SetStatusOf returns a *StatusSetter for changing z/OS session attributes (via
 // SITE) on the current session. See StatusSetter for the available setters.
+//
+// Setting a value the session already knows the server has sends nothing.
 func (s *FTPSession) SetStatusOf() *StatusSetter {
-	return &StatusSetter{site: s.Site}
+	return &StatusSetter{site: s.setSite}
 }
 
 // setStatusOfLocked is like SetStatusOf but its setters assume s.mu is already
 // held. It is used by methods that run a whole sequence under the lock, such as
 // Login, where calling the public (locking) Site would deadlock.
 func (s *FTPSession) setStatusOfLocked() *StatusSetter {
-	return &StatusSetter{site: s.siteLocked}
+	return &StatusSetter{site: s.setSiteLocked}
+}
+
+// ErrSiteParam is returned by WithSite for a parameter it cannot save and
+// restore.
+var ErrSiteParam = errors.New("zftp: SITE parameter not supported by WithSite")
+
+// ErrSiteRestore is returned by WithSite when the SITE command that puts the
+// previous values back fails; the session keeps the values fn ran with.
+var ErrSiteRestore = errors.New("zftp: SITE parameters not restored")
+
+// SiteParams are the SITE parameters of WithSite, keyed by name, such as
+// SiteParams{"FILETYPE": "JES", "JESJOBNAME": "*"}. Parameters that are
+// switched on and off (RDW / NORDW) take "true" or "false".
+type SiteParams map[string]string
+
+// siteParam is a parameter WithSite can save and restore: current reads its
+// value as SITE sends it, the bare form (RDW / NORDW) for a toggle.
+type siteParam struct {
+	toggle  bool
+	current func(st *ServerStatus) (string, error)
+}
+
+var siteParams = map[string]siteParam{
+	"FILETYPE":      {current: (*ServerStatus).FileType},
+	"JESJOBNAME":    {current: (*ServerStatus).JesJobName},
+	"JESOWNER":      {current: (*ServerStatus).JesOwner},
+	"JESSTATUS":     {current: (*ServerStatus).JesStatus},
+	"JESRECFM":      {current: (*ServerStatus).JesRecfm},
+	"JESENTRYLIMIT": {current: statusInt((*ServerStatus).JesEntryLimit)},
+	"JESLRECL":      {current: statusInt((*ServerStatus).JesLrecl)},
+	"JESGETBYDSN":   {toggle: true, current: statusToggle("JESGETBYDSN", (*ServerStatus).JesGetByDSN)},
+	"LISTLEVEL":     {current: statusInt((*ServerStatus).ListLevel)},
+	"LISTSUBDIR":    {toggle: true, current: statusToggle("LISTSUBDIR", (*ServerStatus).ListSubDir)},
+	"RECFM":         {current: (*ServerStatus).Recfm},
+	"LRECL":         {current: statusInt((*ServerStatus).Lrecl)},
+	"BLKSIZE":       {current: statusInt((*ServerStatus).BlockSize)},
+	"CHKPTINT":      {current: statusInt((*ServerStatus).CheckpointInterval)},
+	"ENCODING":      {current: (*ServerStatus).Encoding},
+	"SBSENDEOL":     {current: (*ServerStatus).SBSendEol},
+	"ISPFSTATS":     {toggle: true, current: statusToggle("ISPFSTATS", (*ServerStatus).ISPFStats)},
+	"SBSUB":         {toggle: true, current: statusToggle("SBSUB", (*ServerStatus).SBSub)},
+	"RDW":           {toggle: true, current: statusRDW},
+}
+
+func statusInt(get func(*ServerStatus) (int, error)) func(*ServerStatus) (string, error) {
+	return func(st *ServerStatus) (string, error) {
+		n, err := get(st)
+		return strconv.Itoa(n), err
+	}
+}
+
+func statusToggle(name string, get func(*ServerStatus) (bool, error)) func(*ServerStatus) (string, error) {
+	return func(st *ServerStatus) (string, error) {
+		on, err := get(st)
+		v := name
+		if !on {
+			v = "NO" + name
+		}
+		return v, err
+	}
+}
+
+// token renders name and value as a SITE parameter.
+func (p siteParam) token(name, value string) (string, error) {
+	if !p.toggle {
+		return name + "=" + value, nil
+	}
+	on, err := strconv.ParseBool(value)
+	if err != nil {
+		return "", fmt.Errorf("error : '%s=%s', %s", name, value, "Unrecognized parameter")
+	}
+	if !on {
+		name = "NO" + name
+	}
+	return name, nil
+}
+
+// WithSite runs fn with params in effect: it reads their current values (one
+// XSTA query each, unless the session already knows them), applies them all in
+// one SITE command, runs fn, and puts the previous values back in one SITE
+// command however fn returns. A failed restore is returned, wrapping
+// ErrSiteRestore and joined with fn's error, rather than logged. ctx bounds the
+// queries and the SITE commands; the restore runs even when ctx is done. With
+// no params fn just runs.
+//
+// The parameters it can restore are FILETYPE, JESJOBNAME, JESOWNER, JESSTATUS,
+// JESRECFM, JESENTRYLIMIT, JESLRECL, JESGETBYDSN, LISTLEVEL, LISTSUBDIR, RECFM,
+// LRECL, BLKSIZE, CHKPTINT, ENCODING, SBSENDEOL, ISPFSTATS, SBSUB and RDW;
+// any other name fails with ErrSiteParam before a command is sent.
+//
+//	err := s.WithSite(ctx, zftp.SiteParams{"FILETYPE": "JES", "JESJOBNAME": "*"}, func() error {
+//		_, err := s.List("JOB12345")
+//		return err
+//	})
+func (s *FTPSession) WithSite(ctx context.Context, params SiteParams, fn func() error) error {
+	names := slices.Sorted(maps.Keys(params))
+	apply := make([]string, 0, len(names))
+	for _, name := range names {
+		p, ok := siteParams[strings.ToUpper(name)]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrSiteParam, name)
+		}
+		tok, err := p.token(strings.ToUpper(name), params[name])
+		if err != nil {
+			return err
+		}
+		apply = append(apply, tok)
+	}
+
+	if len(apply) == 0 {
+		return fn()
+	}
+
+	st := s.statusOfContext(ctx)
+	restore := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(name)
+		p := siteParams[name]
+		curr, err := s.knownVia(st, name, func() (string, error) { return p.current(st) })()
+		if err != nil {
+			return fmt.Errorf("failed to get current value of %s: %w", name, err)
+		}
+		if !p.toggle {
+			curr = name + "=" + curr
+		}
+		restore = append(restore, curr)
+	}
+
+	if _, err := s.setSiteContext(ctx, strings.Join(apply, " ")); err != nil {
+		// z/OS keeps the parameters before the one it rejects, so put them back.
+		return errors.Join(fmt.Errorf("failed to set site parameters: %w", err), s.restoreSite(ctx, restore))
+	}
+	return errors.Join(fn(), s.restoreSite(ctx, restore))
+}
+
+// restoreSite sends the saved parameters of WithSite back in one SITE command.
+func (s *FTPSession) restoreSite(ctx context.Context, params []string) error {
+	if _, err := s.setSiteContext(context.WithoutCancel(ctx), strings.Join(params, " ")); err != nil {
+		return fmt.Errorf("%w: %w", ErrSiteRestore, err)
+	}
+	return nil
 }
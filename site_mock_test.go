@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+)
+
+// TestWithSite_AppliesAndRestores checks the parameters go out in one SITE
+// command, are in effect for the callback, and come back in one SITE command.
+func TestWithSite_AppliesAndRestores(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+
+	params := zftp.SiteParams{"FILETYPE": "JES", "JESJOBNAME": "*", "RDW": "true"}
+	var during string
+	err := s.WithSite(context.Background(), params, func() error {
+		var err error
+		during, err = s.StatusOf().FileType()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithSite: %v", err)
+	}
+	if during != "JES" {
+		t.Errorf("FILETYPE in callback = %q, want JES", during)
+	}
+	cmds := srv.Commands()
+	set, restore := cmdIndex(cmds, "SITE FILETYPE=JES JESJOBNAME=* RDW"), cmdIndex(cmds, "SITE FILETYPE=SEQ JESJOBNAME=ME* NORDW")
+	if set < 0 || restore < set {
+		t.Errorf("commands = %v", cmds)
+	}
+	if n := len(slices.DeleteFunc(slices.Clone(cmds[set:]), func(c string) bool { return !strings.HasPrefix(c, "SITE ") })); n != 2 {
+		t.Errorf("sent %d SITE commands, want 2: %v", n, cmds)
+	}
+	if ft, err := s.StatusOf().FileType(); err != nil || ft != "SEQ" {
+		t.Errorf("FILETYPE after WithSite = %q, %v", ft, err)
+	}
+}
+
+// TestWithSite_Errors checks the callback's error is returned after the
+// restore, a failed restore is reported, and unknown parameters send nothing.
+func TestWithSite_Errors(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	err := s.WithSite(ctx, zftp.SiteParams{"FILETYPE": "JES"}, func() error { return boom })
+	if !errors.Is(err, boom) || errors.Is(err, zftp.ErrSiteRestore) {
+		t.Errorf("callback error: got %v", err)
+	}
+	if !hasCmd(srv.Commands(), "SITE FILETYPE=SEQ") {
+		t.Errorf("not restored after a failing callback: %v", srv.Commands())
+	}
+
+	srv.Script("SITE FILETYPE=SEQ", "501 Invalid SITE parameter.")
+	err = s.WithSite(ctx, zftp.SiteParams{"FILETYPE": "JES"}, func() error { return nil })
+	if !errors.Is(err, zftp.ErrSiteRestore) {
+		t.Errorf("failed restore: got %v", err)
+	}
+	srv.Script("SITE FILETYPE=SEQ", "200 SITE command was accepted")
+	_, _ = s.Site("FILETYPE=SEQ")
+
+	before := len(srv.Commands())
+	called := false
+	for _, params := range []zftp.SiteParams{{"PRIMARY": "10"}, {"RDW": "maybe"}} {
+		err = s.WithSite(ctx, params, func() error { called = true; return nil })
+		if err == nil {
+			t.Errorf("WithSite(%v): want error", params)
+		}
+	}
+	if !errors.Is(s.WithSite(ctx, zftp.SiteParams{"PRIMARY": "10"}, nil), zftp.ErrSiteParam) {
+		t.Error("unknown parameter: want ErrSiteParam")
+	}
+	if called || len(srv.Commands()) != before {
+		t.Errorf("rejected parameters ran the callback or sent %v", srv.Commands()[before:])
+	}
+}
+
+// TestWithSite_ToggleRestoreIsStable checks a toggle that reads as off is
+// restored with the same NO form in every session, one after the other.
+func TestWithSite_ToggleRestoreIsStable(t *testing.T) {
+	for i := range 2 {
+		s, srv := dialMock(t)
+		srv.Script("XSTA (JESGETBYDSN", "211-JESGETBYDSN is FALSE", "211 *** end of status ***")
+		err := s.WithSite(context.Background(), zftp.SiteParams{"JESGETBYDSN": "true"}, func() error { return nil })
+		if err != nil {
+			t.Fatalf("session %d: WithSite: %v", i, err)
+		}
+		if cmds := srv.Commands(); !hasCmd(cmds, "SITE JESGETBYDSN") || !hasCmd(cmds, "SITE NOJESGETBYDSN") {
+			t.Errorf("session %d: commands = %v", i, cmds)
+		}
+	}
+}
+
+// TestWithSite_EmptyAndCanceled checks no params run the callback without a
+// command, and a done context stops the reads before anything is set.
+func TestWithSite_EmptyAndCanceled(t *testing.T) {
+	s, srv := dialMock(t)
+
+	before := len(srv.Commands())
+	called := false
+	if err := s.WithSite(context.Background(), zftp.SiteParams{}, func() error { called = true; return nil }); err != nil || !called {
+		t.Errorf("WithSite(empty) = %v, called %t", err, called)
+	}
+	if cmds := srv.Commands()[before:]; len(cmds) != 0 {
+		t.Errorf("WithSite(empty) sent %v", cmds)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called = false
+	err := s.WithSite(ctx, zftp.SiteParams{"JESOWNER": "ME"}, func() error { called = true; return nil })
+	if !errors.Is(err, context.Canceled) || called {
+		t.Errorf("WithSite(canceled) = %v, called %t", err, called)
+	}
+	if cmds := srv.Commands()[before:]; len(cmds) != 0 {
+		t.Errorf("WithSite(canceled) sent %v", cmds)
+	}
+}
+
+// TestWithSite_CallersReportRestore checks the spool listing and JCL submit
+// return a failed restore of FILETYPE instead of logging it.
+func TestWithSite_CallersReportRestore(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	srv.Script("SITE FILETYPE=SEQ", "501 Invalid SITE parameter.")
+
+	for name, call := range map[string]func() error{
+		"ListSpool": func() error { _, err := s.ListSpool("*"); return err },
+		"SubmitJCL": func() error { _, err := s.SubmitJCL("//MYJOB JOB (ACCT)\n//S1 EXEC PGM=IEFBR14\n"); return err },
+	} {
+		if err := call(); !errors.Is(err, zftp.ErrSiteRestore) {
+			t.Errorf("%s: got %v, want ErrSiteRestore", name, err)
+		}
+		// A line other than the scripted one reaches the catalog's state.
+		if _, err := s.Site("FILETYPE=SEQ NORDW"); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
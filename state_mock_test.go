@@ -0,0 +1,265 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftp_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/hfs"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// TestStateful_PutListGet drives the client against the stateful mock with no
+// scripted replies: an ASCII Put creates a dataset with the SITE attributes set
+// beforehand, ListDatasets parses the mock's real-format listing, and Get reads
+// the records back.
+func TestStateful_PutListGet(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	dir := t.TempDir()
+
+	src := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(src, []byte("HELLO\nMAINFRAME\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(src, "'HLQ.NEW.DATA'", zftp.TypeAscii, zftp.RecfmFB, zftp.WithLrecl(80), zftp.WithBlkSize(27920)); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if recs, ok := srv.Records("HLQ.NEW.DATA"); !ok || !slices.Equal(recs, []string{"HELLO", "MAINFRAME"}) {
+		t.Fatalf("records = %q (ok=%v)", recs, ok)
+	}
+
+	ds, err := s.ListDatasets("'HLQ.**'")
+	if err != nil {
+		t.Fatalf("ListDatasets: %v", err)
+	}
+	if len(ds) != 1 {
+		t.Fatalf("got %d datasets, want 1: %v", len(ds), ds)
+	}
+	if ds[0].Name() != "HLQ.NEW.DATA" || ds[0].Recfm.String() != "FB" || ds[0].Lrecl.Value() != 80 || ds[0].BlkSz.Value() != 27920 || !ds[0].IsSequential() {
+		t.Errorf("listed %v", ds[0])
+	}
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("'HLQ.NEW.DATA'", &out, zftp.TypeAscii); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if !strings.Contains(out.String(), "HELLO") || !strings.Contains(out.String(), "MAINFRAME") {
+		t.Errorf("retrieved %q", out.String())
+	}
+}
+
+// TestStateful_PdsMembers seeds a PDS, uploads a member, and verifies ListPds
+// parses the member rows (with ISPF statistics) the mock renders.
+func TestStateful_PdsMembers(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddPDS("HLQ.SRC", mockzos.Attrs{Recfm: "FB", Lrecl: 80, BlkSize: 3120})
+	srv.AddMember("HLQ.SRC", "ALPHA", "LINE 1", "LINE 2")
+
+	if _, err := s.StoreIO("'HLQ.SRC(BETA)'", strings.NewReader("ONE\nTWO\nTHREE\n"), zftp.TypeAscii); err != nil {
+		t.Fatalf("StoreIO member: %v", err)
+	}
+
+	members, err := s.ListPds("'HLQ.SRC'")
+	if err != nil {
+		t.Fatalf("ListPds: %v", err)
+	}
+	if len(members) != 2 {
+		t.Fatalf("got %d members, want 2: %v", len(members), members)
+	}
+	if members[0].Name.String() != "ALPHA" || members[1].Name.String() != "BETA" {
+		t.Errorf("member names = %s, %s", members[0].Name.String(), members[1].Name.String())
+	}
+	if members[1].Size.Value() != 3 || members[1].Id.String() != "ME" {
+		t.Errorf("BETA stats = %v", members[1])
+	}
+
+	ds, err := s.ListDatasets("'HLQ.*'")
+	if err != nil {
+		t.Fatalf("ListDatasets: %v", err)
+	}
+	if len(ds) != 1 || !ds[0].IsPartitioned() {
+		t.Errorf("listed %v, want one PO dataset", ds)
+	}
+}
+
+// TestStateful_Mutations covers DELE, RNFR/RNTO and MKD against the catalog,
+// including the 550 a missing entry draws.
+func TestStateful_Mutations(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.AddDataset("HLQ.OLD", mockzos.Attrs{}, "X")
+	srv.AddFile("/u/me/notes.txt", []byte("hi\n"))
+
+	if err := s.Rename("'HLQ.OLD'", "'HLQ.NEW'"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if srv.Exists("HLQ.OLD") || !srv.Exists("HLQ.NEW") {
+		t.Errorf("after rename: old=%v new=%v", srv.Exists("HLQ.OLD"), srv.Exists("HLQ.NEW"))
+	}
+	if err := s.Delete("'HLQ.NEW'"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete("'HLQ.NEW'"); !errors.Is(err, zftp.CodeError(zftp.CodeFileActionNotTakenPerm)) {
+		t.Errorf("second Delete err = %v, want 550", err)
+	}
+
+	if err := s.Mkdir("'HLQ.NEW.PDS'"); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if _, err := s.StoreIO("'HLQ.NEW.PDS(MEM1)'", strings.NewReader("DATA\n"), zftp.TypeAscii); err != nil {
+		t.Fatalf("StoreIO into new PDS: %v", err)
+	}
+
+	if err := s.Mkdir("/u/me/sub"); err != nil {
+		t.Fatalf("Mkdir uss: %v", err)
+	}
+	if err := s.Rename("/u/me/notes.txt", "/u/me/sub/notes.txt"); err != nil {
+		t.Fatalf("Rename uss: %v", err)
+	}
+	if got, ok := srv.File("/u/me/sub/notes.txt"); !ok || string(got) != "hi\n" {
+		t.Errorf("moved file = %q (ok=%v)", got, ok)
+	}
+	lines, err := s.List("/u/me/sub")
+	if err != nil {
+		t.Fatalf("List uss: %v", err)
+	}
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], " notes.txt") {
+		t.Errorf("uss listing = %q", lines)
+	}
+}
+
+// TestStateful_JesLifecycle submits JCL through the internal reader, follows
+// the job through INPUT, ACTIVE and OUTPUT on the mock clock, reads a spool
+// file and purges the job.
+func TestStateful_JesLifecycle(t *testing.T) {
+	s, srv := dialMock(t)
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	srv.SetClock(func() time.Time { return now })
+	srv.JESTiming(time.Minute, time.Minute)
+	srv.JobCompletion("MEJOB", "RC=0004")
+
+	job, err := s.SubmitJCL("//MEJOB JOB (ACCT),CLASS=A\n//S1 EXEC PGM=IEFBR14\n")
+	if err != nil {
+		t.Fatalf("SubmitJCL: %v", err)
+	}
+	if job.ID != "JOB00001" {
+		t.Fatalf("job.ID = %q", job.ID)
+	}
+
+	jobs, err := s.ListSpool("*")
+	if err != nil {
+		t.Fatalf("ListSpool: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Status.String() != "INPUT" || jobs[0].Owner.String() != "ME" {
+		t.Fatalf("spool = %v", jobs)
+	}
+
+	now = now.Add(90 * time.Second)
+	if _, err := s.GetJobStatus(job.ID); !errors.Is(err, hfs.ErrActiveJob) {
+		t.Errorf("GetJobStatus while running: err = %v, want ErrActiveJob", err)
+	}
+
+	now = now.Add(time.Minute)
+	jd, err := s.GetJobStatus(job.ID)
+	if err != nil {
+		t.Fatalf("GetJobStatus: %v", err)
+	}
+	if rc, err := jd.ReturnCode(); err != nil || rc != 4 {
+		t.Errorf("ReturnCode = %d, %v; want 4", rc, err)
+	}
+	if len(jd.Detail()) != 3 || jd.Detail()[0].DDName.String() != "JESMSGLG" {
+		t.Errorf("detail = %v", jd.Detail())
+	}
+
+	var log strings.Builder
+	if _, err := s.RetrieveIO(job.ID+".1", &log, zftp.TypeAscii); err == nil {
+		t.Error("RETR of a spool file under FILETYPE=SEQ succeeded")
+	}
+	if err := s.SetStatusOf().FileType("JES"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RetrieveIO(job.ID+".1", &log, zftp.TypeAscii); err != nil {
+		t.Fatalf("RetrieveIO spool: %v", err)
+	}
+	if !strings.Contains(log.String(), "$HASP395 MEJOB    ENDED - RC=0004") {
+		t.Errorf("JESMSGLG = %q", log.String())
+	}
+
+	if err := s.PurgeJob(job.ID); err != nil {
+		t.Fatalf("PurgeJob: %v", err)
+	}
+	if _, ok := srv.JobStatus(job.ID); ok {
+		t.Error("job still on the spool after purge")
+	}
+	if err := s.PurgeJob(job.ID); !errors.Is(err, zftp.CodeError(zftp.CodeFileActionNotTakenPerm)) {
+		t.Errorf("second PurgeJob err = %v, want 550", err)
+	}
+}
+
+// TestStateful_JesFiltersAndLevel1 checks the default JESJOBNAME/JESOWNER
+// filters and the level 1 listing format.
+func TestStateful_JesFiltersAndLevel1(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.SubmitJob("ME", "//MEA JOB\n//S1 EXEC PGM=IEFBR14\n")
+	srv.SubmitJob("OTHER", "//OTHERA JOB\n//S1 EXEC PGM=IEFBR14\n")
+
+	jobs, err := s.ListSpool("*")
+	if err != nil {
+		t.Fatalf("ListSpool: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].Name.String() != "MEA" {
+		t.Errorf("default filters listed %v", jobs)
+	}
+
+	if err := s.SetStatusOf().JesOwner("*"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetStatusOf().JesJobName("*"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Site("JESINTERFACELEVEL=1"); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err = s.ListSpool("*")
+	if err != nil {
+		t.Fatalf("ListSpool level 1: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].SpoolFiles != 3 || jobs[0].Owner.String() != "" {
+		t.Errorf("level 1 listed %v", jobs)
+	}
+}
+
+// TestStateful_SubmitJesGetByDSN runs the submit-and-wait flow end to end: the
+// JCL is stored as a dataset, retrieved under FILETYPE=JES, and the job's spool
+// comes back with its completion classified.
+func TestStateful_SubmitJesGetByDSN(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+
+	job, err := s.SubmitJesGetByDSN("//MYJOB JOB (ACCT)\n//S1 EXEC PGM=IEBGENER\n//SYSUT1 DD *\nPAYLOAD\n/*\n//SYSUT2 DD SYSOUT=*\n")
+	if err != nil {
+		t.Fatalf("SubmitJesGetByDSN: %v", err)
+	}
+	if job.ID == "" || job.DisplayName != "MYJOB" || job.ReturnCode != 0 {
+		t.Errorf("job = %+v", job)
+	}
+	if len(job.Spool) != 4 || job.Spool[3] != "PAYLOAD" {
+		t.Errorf("spool = %q", job.Spool)
+	}
+	if cmds := srv.Commands(); !hasCmd(cmds, "SITE FILETYPE=JES JESJOBNAME=*") || !hasCmd(cmds, "SITE FILETYPE=SEQ JESJOBNAME=ME*") {
+		t.Errorf("commands = %v", cmds)
+	}
+
+	srv.JobCompletion("MYJOB", "ABEND=S0C4")
+	job, err = s.SubmitJesGetByDSN("//MYJOB JOB (ACCT)\n//S1 EXEC PGM=IEFBR14\n")
+	if !errors.Is(err, zftp.ErrAbend) || job.ReturnCode != -1 {
+		t.Errorf("abend: err = %v, rc = %d", err, job.ReturnCode)
+	}
+}
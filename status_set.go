@@ -5,6 +5,7 @@ package zftp
 import (
 	"fmt"
 	"gopkg.in/ro-ag/zftp.v2/eol"
+	"regexp"
 	"strings"
 )
 
@@ -128,6 +129,18 @@ func (s *StatusSetter) ListLevel(level int) error {
 	return err
 }
 
+// RDW sets whether the record descriptor words of variable-length records are
+// transferred as part of the data (SITE RDW / NORDW). It only affects binary
+// transfers of RECFM=V datasets.
+func (s *StatusSetter) RDW(option bool) error {
+	cmd := "NORDW"
+	if option {
+		cmd = "RDW"
+	}
+	_, err := s.site(cmd)
+	return err
+}
+
 // SBSendEol sets the end-of-line sequence appended to outbound single-byte data
 // (SITE SBSENDEOL).
 func (s *StatusSetter) SBSendEol(eol eol.LineBreaker) error {
@@ -141,3 +154,544 @@ func (s *StatusSetter) MBSendEol(eol eol.LineBreaker) error {
 	_, err := s.site(fmt.Sprintf("MBSENDEOL=%s", eol.String()))
 	return err
 }
+
+// CheckpointInterval sets how many records the server sends between restart
+// markers in block-mode transfers (SITE CHKPTINT); 0 sends none.
+func (s *StatusSetter) CheckpointInterval(records int) error {
+	if records < 0 {
+		return fmt.Errorf("CheckpointInterval must not be negative")
+	}
+	_, err := s.site(fmt.Sprintf("CHKPTINT=%d", records))
+	return err
+}
+
+// Encoding sets how the server converts data connection text (SITE ENCODING):
+// SBCS with the single-byte table of SBDATACONN, or MBCS with the multibyte
+// code pages of MBDATACONN, which must be set first.
+func (s *StatusSetter) Encoding(encoding string) error {
+	switch encoding {
+	case "SBCS", "MBCS":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", encoding, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("ENCODING=%s", encoding))
+	return err
+}
+
+// MBDataConn sets the code pages of multibyte conversion (SITE
+// MBDATACONN=(fileSystem,network)): fileSystem is the host's, such as IBM-1047,
+// and network the data connection's, such as UTF-8.
+func (s *StatusSetter) MBDataConn(fileSystem, network string) error {
+	if fileSystem == "" || network == "" {
+		return fmt.Errorf("MBDataConn needs both the file system and the network code page")
+	}
+	_, err := s.site(fmt.Sprintf("MBDATACONN=(%s,%s)", fileSystem, network))
+	return err
+}
+
+// SBDataConn sets the single-byte conversion of the data connection (SITE
+// SBDATACONN). With a network code page it is the pair (fileSystem,network);
+// without one, fileSystem names a translate table dataset, or is * for the
+// table in effect at login or FTP_STANDARD_TABLE for the default.
+func (s *StatusSetter) SBDataConn(fileSystem, network string) error {
+	switch {
+	case fileSystem == "":
+		return fmt.Errorf("SBDataConn needs a code page or table")
+	case network == "":
+		_, err := s.site(fmt.Sprintf("SBDATACONN=%s", fileSystem))
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("SBDATACONN=(%s,%s)", fileSystem, network))
+	return err
+}
+
+// UCSSub sets whether characters without an EBCDIC equivalent are replaced by
+// the substitution character on Unicode conversion (SITE UCSSUB / NOUCSSUB);
+// otherwise the transfer fails.
+func (s *StatusSetter) UCSSub(option bool) error {
+	cmd := "NOUCSSUB"
+	if option {
+		cmd = "UCSSUB"
+	}
+	_, err := s.site(cmd)
+	return err
+}
+
+// UCSTrunc sets whether a record that grows past LRECL on Unicode conversion is
+// truncated (SITE UCSTRUNC / NOUCSTRUNC); otherwise the transfer fails.
+func (s *StatusSetter) UCSTrunc(option bool) error {
+	cmd := "NOUCSTRUNC"
+	if option {
+		cmd = "UCSTRUNC"
+	}
+	_, err := s.site(cmd)
+	return err
+}
+
+// UnicodeFileSystemBOM sets what happens to the byte-order mark of Unicode
+// files stored in z/OS UNIX (SITE UNICODEFILESYSTEMBOM). Valid values are
+// ASIS, ADD, and REMOVE.
+func (s *StatusSetter) UnicodeFileSystemBOM(option string) error {
+	switch option {
+	case "ASIS", "ADD", "REMOVE":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", option, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("UNICODEFILESYSTEMBOM=%s", option))
+	return err
+}
+
+// toggle sends the SITE parameter name when option is true and its NO form
+// otherwise.
+func (s *StatusSetter) toggle(name string, option bool) error {
+	if !option {
+		name = "NO" + name
+	}
+	_, err := s.site(name)
+	return err
+}
+
+// hostName matches a one-to-eight character z/OS name: an SMS class, a unit, a
+// volume serial or a translate table.
+var hostName = regexp.MustCompile(`^[A-Z0-9@#$]{1,8}$`)
+
+// setName sends name=value after checking value is a z/OS name; an empty value
+// sends the bare parameter, which clears it.
+func (s *StatusSetter) setName(name, value string) error {
+	if value == "" {
+		_, err := s.site(name)
+		return err
+	}
+	if !hostName.MatchString(value) {
+		return fmt.Errorf("error : '%s', %s", value, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("%s=%s", name, value))
+	return err
+}
+
+// Primary sets the primary space of new datasets, in the unit chosen by Tracks,
+// Cylinders or Blocks (SITE PRIMARY). The valid range is 1 to 16777215.
+func (s *StatusSetter) Primary(amount int) error {
+	if err := checkPrimary(amount); err != nil {
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("PRIMARY=%d", amount))
+	return err
+}
+
+// Secondary sets the secondary space of new datasets (SITE SECONDARY). The
+// valid range is 0 to 16777215.
+func (s *StatusSetter) Secondary(amount int) error {
+	if err := checkSecondary(amount); err != nil {
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("SECONDARY=%d", amount))
+	return err
+}
+
+// Tracks allocates the space of new datasets in tracks (SITE TRACKS).
+func (s *StatusSetter) Tracks() error {
+	_, err := s.site("TRACKS")
+	return err
+}
+
+// Cylinders allocates the space of new datasets in cylinders (SITE CYLINDERS).
+func (s *StatusSetter) Cylinders() error {
+	_, err := s.site("CYLINDERS")
+	return err
+}
+
+// Blocks allocates the space of new datasets in blocks of BLKSIZE (SITE
+// BLOCKS).
+func (s *StatusSetter) Blocks() error {
+	_, err := s.site("BLOCKS")
+	return err
+}
+
+// Directory sets the number of directory blocks of new partitioned datasets
+// (SITE DIRECTORY). The valid range is 1 to 16777215.
+func (s *StatusSetter) Directory(blocks int) error {
+	if err := checkDirectory(blocks); err != nil {
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("DIRECTORY=%d", blocks))
+	return err
+}
+
+// Recfm sets the record format of new datasets (SITE RECFM), such as FB, VB,
+// VBS or U, optionally followed by A (ASA) or M (machine) control characters.
+func (s *StatusSetter) Recfm(format string) error {
+	if !recfmPattern.MatchString(format) {
+		return fmt.Errorf("error : '%s', %s", format, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("RECFM=%s", format))
+	return err
+}
+
+var recfmPattern = regexp.MustCompile(`^([FV]B?S?|U)[AM]?$`)
+
+// Lrecl sets the logical record length of new datasets (SITE LRECL). The valid
+// range is 0 to 32760; 0 leaves it to the system (LRECL=X is not supported).
+func (s *StatusSetter) Lrecl(length int) error {
+	if length < 0 || length > 32760 {
+		return fmt.Errorf("Lrecl must be between 0 and 32760")
+	}
+	_, err := s.site(fmt.Sprintf("LRECL=%d", length))
+	return err
+}
+
+// BlockSize sets the block size of new datasets (SITE BLKSIZE). The valid range
+// is 0 to 32760; 0 lets the system determine it.
+func (s *StatusSetter) BlockSize(size int) error {
+	if size < 0 || size > 32760 {
+		return fmt.Errorf("BlockSize must be between 0 and 32760")
+	}
+	_, err := s.site(fmt.Sprintf("BLKSIZE=%d", size))
+	return err
+}
+
+// BufNo sets the number of access method buffers used to read and write data
+// (SITE BUFNO). The valid range is 1 to 35.
+func (s *StatusSetter) BufNo(buffers int) error {
+	if buffers < 1 || buffers > 35 {
+		return fmt.Errorf("BufNo must be between 1 and 35")
+	}
+	_, err := s.site(fmt.Sprintf("BUFNO=%d", buffers))
+	return err
+}
+
+// Volume sets the volume serials new datasets are allocated on (SITE VOLUME);
+// no volumes clears it.
+func (s *StatusSetter) Volume(volumes ...string) error {
+	if len(volumes) == 0 {
+		_, err := s.site("VOLUME")
+		return err
+	}
+	if err := checkVolumes(volumes); err != nil {
+		return err
+	}
+	if len(volumes) == 1 {
+		_, err := s.site(fmt.Sprintf("VOLUME=%s", volumes[0]))
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("VOLUME=(%s)", strings.Join(volumes, ",")))
+	return err
+}
+
+// Unit sets the unit type new datasets are allocated on, such as SYSDA (SITE
+// UNIT); "" clears it.
+func (s *StatusSetter) Unit(unit string) error {
+	return s.setName("UNIT", unit)
+}
+
+// UCount sets the number of devices allocated for a new dataset (SITE UCOUNT).
+// The valid range is 1 to 59; 0 requests parallel mounts (UCOUNT=P).
+func (s *StatusSetter) UCount(count int) error {
+	switch {
+	case count == 0:
+		_, err := s.site("UCOUNT=P")
+		return err
+	case count < 0 || count > 59:
+		return fmt.Errorf("UCount must be between 0 and 59")
+	}
+	_, err := s.site(fmt.Sprintf("UCOUNT=%d", count))
+	return err
+}
+
+// VCount sets the number of tape volumes a new dataset may span (SITE VCOUNT).
+// The valid range is 1 to 255.
+func (s *StatusSetter) VCount(count int) error {
+	if count < 1 || count > 255 {
+		return fmt.Errorf("VCount must be between 1 and 255")
+	}
+	_, err := s.site(fmt.Sprintf("VCOUNT=%d", count))
+	return err
+}
+
+// DataClass sets the SMS data class of new datasets (SITE DATACLASS); "" clears
+// it.
+func (s *StatusSetter) DataClass(class string) error {
+	return s.setName("DATACLASS", class)
+}
+
+// MgmtClass sets the SMS management class of new datasets (SITE MGMTCLASS); ""
+// clears it.
+func (s *StatusSetter) MgmtClass(class string) error {
+	return s.setName("MGMTCLASS", class)
+}
+
+// StorageClass sets the SMS storage class of new datasets (SITE STORCLASS); ""
+// clears it.
+func (s *StatusSetter) StorageClass(class string) error {
+	return s.setName("STORCLASS", class)
+}
+
+// RetPD sets the retention period, in days, of new datasets (SITE RETPD). The
+// valid range is 0 to 93000; a negative value clears it.
+func (s *StatusSetter) RetPD(days int) error {
+	if days < 0 {
+		_, err := s.site("RETPD=")
+		return err
+	}
+	if err := checkRetPD(days); err != nil {
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("RETPD=%d", days))
+	return err
+}
+
+// DSNType sets the type of new datasets (SITE DSNTYPE). Valid values are
+// SYSTEM, BASIC, LARGE, PDS, LIBRARY, EXTREQ and EXTPREF.
+func (s *StatusSetter) DSNType(Type string) error {
+	switch Type {
+	case "SYSTEM", "BASIC", "LARGE", "PDS", "LIBRARY", "EXTREQ", "EXTPREF":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", Type, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("DSNTYPE=%s", Type))
+	return err
+}
+
+// PDSType sets whether partitioned datasets created by MKD are PDS or PDSE
+// (SITE PDSTYPE); "" lets the system decide.
+func (s *StatusSetter) PDSType(Type string) error {
+	switch Type {
+	case "":
+		_, err := s.site("PDSTYPE")
+		return err
+	case "PDS", "PDSE":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", Type, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("PDSTYPE=%s", Type))
+	return err
+}
+
+// EATTR sets whether new datasets may have extended attributes (SITE EATTR).
+// Valid values are SYSTEM, NO and OPT.
+func (s *StatusSetter) EATTR(option string) error {
+	if err := checkEATTR(option); err != nil {
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("EATTR=%s", option))
+	return err
+}
+
+// DCBDSN sets the dataset whose attributes model new datasets (SITE DCBDSN);
+// "" clears it.
+func (s *StatusSetter) DCBDSN(dsn string) error {
+	if dsn == "" {
+		_, err := s.site("DCBDSN")
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("DCBDSN=%s", dsn))
+	return err
+}
+
+// MigrateVol sets the volume serial that identifies migrated datasets (SITE
+// MIGRATEVOL).
+func (s *StatusSetter) MigrateVol(volume string) error {
+	if len(volume) > 6 || !hostName.MatchString(volume) {
+		return fmt.Errorf("error : '%s', %s", volume, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("MIGRATEVOL=%s", volume))
+	return err
+}
+
+// ConditionDisposition sets what happens to a new dataset when a store fails
+// (SITE CONDDISP). Valid values are CATLG and DELETE.
+func (s *StatusSetter) ConditionDisposition(disposition string) error {
+	switch disposition {
+	case "CATLG", "DELETE":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", disposition, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("CONDDISP=%s", disposition))
+	return err
+}
+
+// Truncate sets whether stored records longer than LRECL are truncated rather
+// than failing the transfer (SITE TRUNCATE / NOTRUNCATE).
+func (s *StatusSetter) Truncate(option bool) error {
+	return s.toggle("TRUNCATE", option)
+}
+
+// WrapRecord sets whether stored records longer than LRECL are wrapped onto the
+// next record (SITE WRAPRECORD / NOWRAPRECORD).
+func (s *StatusSetter) WrapRecord(option bool) error {
+	return s.toggle("WRAPRECORD", option)
+}
+
+// TrailingBlanks sets whether trailing blanks of fixed-length records are kept
+// on retrieval (SITE TRAILINGBLANKS / NOTRAILINGBLANKS).
+func (s *StatusSetter) TrailingBlanks(option bool) error {
+	return s.toggle("TRAILINGBLANKS", option)
+}
+
+// ISPFStats sets whether ISPF statistics are kept for stored PDS members (SITE
+// ISPFSTATS / NOISPFSTATS).
+func (s *StatusSetter) ISPFStats(option bool) error {
+	return s.toggle("ISPFSTATS", option)
+}
+
+// AutoRecall sets whether migrated datasets are recalled when accessed (SITE
+// AUTORECALL / NOAUTORECALL).
+func (s *StatusSetter) AutoRecall(option bool) error {
+	return s.toggle("AUTORECALL", option)
+}
+
+// AutoMount sets whether volumes that are not mounted are mounted when a
+// dataset on them is accessed (SITE AUTOMOUNT / NOAUTOMOUNT).
+func (s *StatusSetter) AutoMount(option bool) error {
+	return s.toggle("AUTOMOUNT", option)
+}
+
+// QuotesOverride sets whether a quoted name overrides the working directory
+// prefix (SITE QUOTESOVERRIDE / NOQUOTESOVERRIDE).
+func (s *StatusSetter) QuotesOverride(option bool) error {
+	return s.toggle("QUOTESOVERRIDE", option)
+}
+
+// ASATrans sets whether ASA carriage control characters are translated to
+// their C0 equivalents on ASCII transfers (SITE ASATRANS / NOASATRANS).
+func (s *StatusSetter) ASATrans(option bool) error {
+	return s.toggle("ASATRANS", option)
+}
+
+// ListSubDir sets whether NLST lists the files of z/OS UNIX subdirectories
+// (SITE LISTSUBDIR / NOLISTSUBDIR).
+func (s *StatusSetter) ListSubDir(option bool) error {
+	return s.toggle("LISTSUBDIR", option)
+}
+
+// MBRequireLastEol sets whether the last record of a multibyte store must end
+// with a line end (SITE MBREQUIRELASTEOL / NOMBREQUIRELASTEOL).
+func (s *StatusSetter) MBRequireLastEol(option bool) error {
+	return s.toggle("MBREQUIRELASTEOL", option)
+}
+
+// SBSub sets whether untranslatable single-byte characters are replaced by
+// SBSUBCHAR instead of failing the transfer (SITE SBSUB / NOSBSUB).
+func (s *StatusSetter) SBSub(option bool) error {
+	return s.toggle("SBSUB", option)
+}
+
+// SBSubChar sets the character that replaces untranslatable single-byte data
+// (SITE SBSUBCHAR): SPACE, or a two-digit hexadecimal code such as 3F.
+func (s *StatusSetter) SBSubChar(char string) error {
+	if char != "SPACE" && !hexByte.MatchString(char) {
+		return fmt.Errorf("error : '%s', %s", char, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("SBSUBCHAR=%s", char))
+	return err
+}
+
+var hexByte = regexp.MustCompile(`^[0-9A-Fa-f]{2}$`)
+
+// SPRead sets whether spool files are retrieved with their carriage control
+// (SITE SPREAD / NOSPREAD).
+func (s *StatusSetter) SPRead(option bool) error {
+	return s.toggle("SPREAD", option)
+}
+
+// UMask sets the mask of permission bits turned off in new z/OS UNIX files
+// (SITE UMASK). The valid range is 0 to 0777, sent in octal.
+func (s *StatusSetter) UMask(mask int) error {
+	if mask < 0 || mask > 0o777 {
+		return fmt.Errorf("UMask must be between 0 and 0777")
+	}
+	_, err := s.site(fmt.Sprintf("UMASK=%03o", mask))
+	return err
+}
+
+// UnixFileType sets whether z/OS UNIX transfers use regular files or named
+// pipes (SITE UNIXFILETYPE). Valid values are FILE and FIFO.
+func (s *StatusSetter) UnixFileType(Type string) error {
+	switch Type {
+	case "FILE", "FIFO":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", Type, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("UNIXFILETYPE=%s", Type))
+	return err
+}
+
+// FifoIoTime sets how long, in seconds, a named pipe read or write may wait
+// (SITE FIFOIOTIME). The valid range is 1 to 86400.
+func (s *StatusSetter) FifoIoTime(seconds int) error {
+	if seconds < 1 || seconds > 86400 {
+		return fmt.Errorf("FifoIoTime must be between 1 and 86400")
+	}
+	_, err := s.site(fmt.Sprintf("FIFOIOTIME=%d", seconds))
+	return err
+}
+
+// FifoOpenTime sets how long, in seconds, opening a named pipe may wait (SITE
+// FIFOOPENTIME). The valid range is 1 to 86400.
+func (s *StatusSetter) FifoOpenTime(seconds int) error {
+	if seconds < 1 || seconds > 86400 {
+		return fmt.Errorf("FifoOpenTime must be between 1 and 86400")
+	}
+	_, err := s.site(fmt.Sprintf("FIFOOPENTIME=%d", seconds))
+	return err
+}
+
+// DataKeepAlive sets the data connection keep-alive interval, in seconds (SITE
+// DATAKEEPALIVE). The valid range is 5 to 14400; 0 turns it off.
+func (s *StatusSetter) DataKeepAlive(seconds int) error {
+	if seconds != 0 && (seconds < 5 || seconds > 14400) {
+		return fmt.Errorf("DataKeepAlive must be 0 or between 5 and 14400")
+	}
+	_, err := s.site(fmt.Sprintf("DATAKEEPALIVE=%d", seconds))
+	return err
+}
+
+// DSWaitTime sets how long, in minutes, FTP waits for a dataset held by
+// another job (SITE DSWAITTIME). The valid range is 0 to 14400.
+func (s *StatusSetter) DSWaitTime(minutes int) error {
+	if minutes < 0 || minutes > 14400 {
+		return fmt.Errorf("DSWaitTime must be between 0 and 14400")
+	}
+	_, err := s.site(fmt.Sprintf("DSWAITTIME=%d", minutes))
+	return err
+}
+
+// SQLCol sets the column headings of FILETYPE=SQL results (SITE SQLCOL). Valid
+// values are NAMES, LABELS and ANY.
+func (s *StatusSetter) SQLCol(headings string) error {
+	switch headings {
+	case "NAMES", "LABELS", "ANY":
+		break
+	default:
+		return fmt.Errorf("error : '%s', %s", headings, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("SQLCOL=%s", headings))
+	return err
+}
+
+// Destination sets the NJE node (and user) stored datasets are routed to
+// instead of being written locally (SITE DEST); "" clears it.
+func (s *StatusSetter) Destination(dest string) error {
+	if dest == "" {
+		_, err := s.site("DEST")
+		return err
+	}
+	_, err := s.site(fmt.Sprintf("DEST=%s", dest))
+	return err
+}
+
+// XLate sets the translate table dataset for single-byte conversion (SITE
+// XLATE).
+func (s *StatusSetter) XLate(table string) error {
+	if !hostName.MatchString(table) {
+		return fmt.Errorf("error : '%s', %s", table, "Unrecognized parameter")
+	}
+	_, err := s.site(fmt.Sprintf("XLATE=%s", table))
+	return err
+}
@@ -171,3 +171,101 @@ func TestTransferType_Concrete(t *testing.T) {
 		t.Errorf("TypeAscii.Name() = %q, want ASCII", got)
 	}
 }
+
+func TestStatusSetter_Multibyte(t *testing.T) {
+	s, srv := dialMock(t)
+	set := s.SetStatusOf()
+	for name, err := range map[string]error{
+		"Encoding":             set.Encoding("MBCS"),
+		"MBDataConn":           set.MBDataConn("IBM-1047", "UTF-8"),
+		"SBDataConn":           set.SBDataConn("FTP_STANDARD_TABLE", ""),
+		"UCSSub":               set.UCSSub(false),
+		"UCSTrunc":             set.UCSTrunc(true),
+		"UnicodeFileSystemBOM": set.UnicodeFileSystemBOM("REMOVE"),
+	} {
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, want := range []string{"SITE ENCODING=MBCS", "SITE MBDATACONN=(IBM-1047,UTF-8)",
+		"SITE SBDATACONN=FTP_STANDARD_TABLE", "SITE NOUCSSUB", "SITE UCSTRUNC", "SITE UNICODEFILESYSTEMBOM=REMOVE"} {
+		if !hasCmd(srv.Commands(), want) {
+			t.Errorf("%s not sent: %v", want, srv.Commands())
+		}
+	}
+	// Invalid values are rejected client-side before any SITE command.
+	if set.Encoding("UTF8") == nil || set.MBDataConn("IBM-1047", "") == nil ||
+		set.SBDataConn("", "ISO8859-1") == nil || set.UnicodeFileSystemBOM("KEEP") == nil {
+		t.Error("invalid multibyte values were accepted")
+	}
+}
+
+func TestStatusSetter_Allocation(t *testing.T) {
+	s, srv := dialMock(t)
+	set := s.SetStatusOf()
+	valid := []struct {
+		call func() error
+		want string
+	}{
+		{func() error { return set.Primary(10) }, "SITE PRIMARY=10"},
+		{func() error { return set.Secondary(0) }, "SITE SECONDARY=0"},
+		{set.Cylinders, "SITE CYLINDERS"},
+		{func() error { return set.Directory(20) }, "SITE DIRECTORY=20"},
+		{func() error { return set.Recfm("VBA") }, "SITE RECFM=VBA"},
+		{func() error { return set.Lrecl(133) }, "SITE LRECL=133"},
+		{func() error { return set.Volume("VOL001", "VOL002") }, "SITE VOLUME=(VOL001,VOL002)"},
+		{func() error { return set.Volume() }, "SITE VOLUME"},
+		{func() error { return set.Unit("3390") }, "SITE UNIT=3390"},
+		{func() error { return set.UCount(0) }, "SITE UCOUNT=P"},
+		{func() error { return set.StorageClass("SCPROD") }, "SITE STORCLASS=SCPROD"},
+		{func() error { return set.MgmtClass("") }, "SITE MGMTCLASS"},
+		{func() error { return set.RetPD(30) }, "SITE RETPD=30"},
+		{func() error { return set.DSNType("LIBRARY") }, "SITE DSNTYPE=LIBRARY"},
+		{func() error { return set.ConditionDisposition("DELETE") }, "SITE CONDDISP=DELETE"},
+		{func() error { return set.Truncate(false) }, "SITE NOTRUNCATE"},
+		{func() error { return set.WrapRecord(true) }, "SITE WRAPRECORD"},
+		{func() error { return set.TrailingBlanks(true) }, "SITE TRAILINGBLANKS"},
+		{func() error { return set.ISPFStats(false) }, "SITE NOISPFSTATS"},
+		{func() error { return set.AutoRecall(true) }, "SITE AUTORECALL"},
+		{func() error { return set.QuotesOverride(false) }, "SITE NOQUOTESOVERRIDE"},
+		{func() error { return set.UMask(0o022) }, "SITE UMASK=022"},
+		{func() error { return set.SBSubChar("3F") }, "SITE SBSUBCHAR=3F"},
+	}
+	for _, tc := range valid {
+		if err := tc.call(); err != nil {
+			t.Errorf("%s: %v", tc.want, err)
+		} else if !hasCmd(srv.Commands(), tc.want) {
+			t.Errorf("%s not sent", tc.want)
+		}
+	}
+
+	sent := len(srv.Commands())
+	// Invalid values are rejected client-side before any SITE command.
+	for name, err := range map[string]error{
+		"Primary(0)":                 set.Primary(0),
+		"Secondary(-1)":              set.Secondary(-1),
+		"Directory(0)":               set.Directory(0),
+		"Recfm(FX)":                  set.Recfm("FX"),
+		"Lrecl(32761)":               set.Lrecl(32761),
+		"BlockSize(-1)":              set.BlockSize(-1),
+		"Volume(TOOLONG)":            set.Volume("TOOLONG"),
+		"Unit(bad name)":             set.Unit("SYS DA"),
+		"UCount(60)":                 set.UCount(60),
+		"DataClass(TOOLONGNAME)":     set.DataClass("TOOLONGNAME"),
+		"RetPD(93001)":               set.RetPD(93001),
+		"DSNType(PDSE)":              set.DSNType("PDSE"),
+		"ConditionDisposition(KEEP)": set.ConditionDisposition("KEEP"),
+		"UMask(01000)":               set.UMask(0o1000),
+		"DataKeepAlive(4)":           set.DataKeepAlive(4),
+		"SBSubChar(X)":               set.SBSubChar("X"),
+		"UnixFileType(PIPE)":         set.UnixFileType("PIPE"),
+		"EATTR(YES)":                 set.EATTR("YES"),
+	} {
+		if err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+	if n := len(srv.Commands()); n != sent {
+		t.Errorf("invalid values sent %d commands", n-sent)
+	}
+}
@@ -6,66 +6,128 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"gopkg.in/ro-ag/zftp.v2/carriage"
+	"gopkg.in/ro-ag/zftp.v2/ebcdic"
 	"gopkg.in/ro-ag/zftp.v2/eol"
 	"gopkg.in/ro-ag/zftp.v2/internal/transfer"
+	"gopkg.in/ro-ag/zftp.v2/internal/utils"
 	"io"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 // ErrAsciiResumeUnsupported is returned by the *At transfer methods when a
-// byte-offset resume (REST) is requested for an ASCII transfer. A REST argument
-// is a byte position, which only has a stable correspondence to a remote position
-// in image/binary mode. In TYPE A the server performs end-of-line and codepage
-// translation, so resuming by byte offset slices mid-record and silently corrupts
-// the data. Resume requires image/binary mode (TypeImage/TypeBinary).
+// byte-offset resume (REST) is requested for a transfer the server translates:
+// ASCII in any form, EBCDIC, DBCS or Unicode. A REST argument is a byte
+// position, which only has a stable correspondence to a remote position in
+// image/binary mode. In the other types the server performs end-of-line and
+// codepage translation, so resuming by byte offset slices mid-record and silently
+// corrupts the data. Resume requires image/binary mode (TypeImage/TypeBinary);
+// an ASCII download is resumed from a block-mode restart marker instead
+// (GetRestart).
 var ErrAsciiResumeUnsupported = errors.New("zftp: byte-offset resume (REST) requires image/binary mode; ASCII transfers cannot be resumed by byte offset")
 
 // guardResume rejects a byte-offset resume that cannot be honored. It returns
-// ErrAsciiResumeUnsupported when a positive offset is paired with an ASCII
+// ErrAsciiResumeUnsupported when a positive offset is paired with a translated
 // transfer type and nil otherwise. The check lives here so every *At entry point
 // (RetrieveIOAt, StoreIOAt, GetAt, PutAt) enforces the same rule before any
 // local-file or network I/O. Image/binary resume (offset > 0) is unaffected.
 func guardResume(t TransferType, offset int64) error {
-	if offset > 0 && t.IsAscii() {
+	if offset > 0 && t.Translated() {
 		return ErrAsciiResumeUnsupported
 	}
 	return nil
 }
 
-// TransferType is the FTP representation type for a transfer. It is a concrete
-// enum (not an interface): callers pass one of the exported values rather than
-// implementing it.
+// TransferType is the FTP representation type for a transfer (TYPE). It is a
+// concrete enum (not an interface): callers pass one of the exported values
+// rather than implementing it. Each value is one byte, the type code letter, or
+// for the types sent with a format control or byte size a letter of its own.
 type TransferType uint8
 
 const (
 	// TypeAscii selects ASCII mode (TYPE A), with end-of-line conversion.
 	TypeAscii TransferType = 'A'
+	// TypeAsciiASA selects ASCII with ASA carriage control (TYPE A C): column 1
+	// of each line holds the control of a print dataset.
+	TypeAsciiASA TransferType = 'C'
+	// TypeAsciiTelnet selects ASCII with Telnet format controls (TYPE A T).
+	TypeAsciiTelnet TransferType = 'T'
+	// TypeEbcdic selects EBCDIC (TYPE E): the data keeps its EBCDIC code page,
+	// with records ending in the EBCDIC NL (0x15).
+	TypeEbcdic TransferType = 'E'
 	// TypeImage selects binary/image mode (TYPE I), byte-for-byte.
 	TypeImage TransferType = 'I'
 	// TypeBinary is an alias for TypeImage.
 	TypeBinary = TypeImage
+	// TypeDBCS selects double-byte character set data (TYPE B), converted with
+	// the server's default DBCS table.
+	TypeDBCS TransferType = 'B'
+	// TypeUnicode selects UCS-2 (TYPE U 2): two bytes per character, big-endian.
+	TypeUnicode TransferType = 'U'
 )
 
+// code returns the type code letter; param returns the format control or byte
+// size, or 0.
+func (t TransferType) code() byte {
+	switch t {
+	case TypeAsciiASA, TypeAsciiTelnet:
+		return 'A'
+	}
+	return byte(t)
+}
+
+func (t TransferType) param() byte {
+	switch t {
+	case TypeAsciiASA, TypeAsciiTelnet:
+		return byte(t)
+	case TypeUnicode:
+		return '2'
+	}
+	return 0
+}
+
+// ascii reports whether t is one of the ASCII types, whatever its format
+// control.
+func (t TransferType) ascii() bool { return t.code() == 'A' }
+
 // strCommand returns the FTP command string for the transfer type.
 func (t TransferType) strCommand() string {
-	return fmt.Sprintf("TYPE %c", t)
+	if p := t.param(); p != 0 {
+		return fmt.Sprintf("TYPE %c %c", t.code(), p)
+	}
+	return fmt.Sprintf("TYPE %c", t.code())
 }
 
 // Name returns the human-readable name of the transfer type, or "UNKNOWN" for a
-// value that is neither ASCII nor image/binary (e.g. the zero value).
+// value that is not one of the exported types (e.g. the zero value).
 func (t TransferType) Name() string {
 	switch t {
 	case TypeAscii:
 		return "ASCII"
+	case TypeAsciiASA:
+		return "ASCII ASA"
+	case TypeAsciiTelnet:
+		return "ASCII TELNET"
+	case TypeEbcdic:
+		return "EBCDIC"
 	case TypeImage:
 		return "BINARY"
+	case TypeDBCS:
+		return "DBCS"
+	case TypeUnicode:
+		return "UNICODE"
 	default:
 		return "UNKNOWN"
 	}
 }
 
-// IsAscii reports whether the transfer type is ASCII.
+// IsAscii reports whether the transfer type is ASCII, with or without a format
+// control. ASCII data travels as lines the client ends with CRLF on upload and
+// the server ends with SBSENDEOL on download.
 func (t TransferType) IsAscii() bool {
-	return t == TypeAscii
+	return t.code() == 'A'
 }
 
 // IsBinary reports whether the transfer type is binary/image.
@@ -73,7 +135,31 @@ func (t TransferType) IsBinary() bool {
 	return t == TypeBinary
 }
 
-// SetType sets the transfer type and stores it in the FTPSession.
+// Translated reports whether the server converts the data or adds record ends
+// to it (every type but image; EBCDIC keeps its code page but gains NLs), so
+// that local and remote byte positions differ and a transfer cannot be resumed
+// by byte offset.
+func (t TransferType) Translated() bool {
+	return !t.IsBinary()
+}
+
+// recordEnd is what ends a record of a block or compressed mode download: the
+// local line end for ASCII and DBCS, NL for EBCDIC, a UCS-2 line feed for
+// Unicode, and nothing for binary.
+func (t TransferType) recordEnd() []byte {
+	switch t.code() {
+	case 'A', 'B':
+		return []byte(eol.System.NewLine())
+	case 'E':
+		return []byte{0x15}
+	case 'U':
+		return []byte{0, '\n'}
+	}
+	return nil
+}
+
+// SetType sets the transfer type and stores it in the FTPSession. A type the
+// server is known to be in already is not sent again.
 func (s *FTPSession) SetType(t TransferType) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -83,13 +169,96 @@ func (s *FTPSession) SetType(t TransferType) error {
 // setTypeLocked issues the TYPE command and records the new transfer type on
 // success. The caller must hold s.mu.
 func (s *FTPSession) setTypeLocked(t TransferType) error {
+	if s.known.isType(t) {
+		return nil
+	}
 	_, err := s.sendLocked(context.Background(), CodeCmdOK, t.strCommand())
-	if err == nil {
-		s.currType.Store(uint32(t))
+	if err != nil {
+		s.known.setType(0)
+		return err
 	}
+	s.currType.Store(uint32(t))
+	s.known.setType(t)
+	return nil
+}
+
+// TransferMode is the FTP transmission mode of the data connection (MODE).
+type TransferMode uint8
+
+const (
+	// ModeStream sends the data as a plain byte stream (MODE S), the default.
+	ModeStream TransferMode = 'S'
+	// ModeBlock frames the data in blocks that mark records and carry restart
+	// markers (MODE B); see WithBlockMode.
+	ModeBlock TransferMode = 'B'
+	// ModeCompressed run-length encodes the data (MODE C): runs of a byte, and
+	// above all of blanks, travel as two bytes or one; see WithCompressedMode.
+	ModeCompressed TransferMode = 'C'
+)
+
+// Name returns "STREAM", "BLOCK", "COMPRESSED", or "UNKNOWN".
+func (m TransferMode) Name() string {
+	switch m {
+	case ModeStream:
+		return "STREAM"
+	case ModeBlock:
+		return "BLOCK"
+	case ModeCompressed:
+		return "COMPRESSED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// filler is the byte a MODE C filler string stands for: a space in ASCII or
+// EBCDIC, zero otherwise.
+func (t TransferType) filler() byte {
+	switch t.code() {
+	case 'A':
+		return ' '
+	case 'E':
+		return 0x40
+	}
+	return 0
+}
+
+// setMode issues the MODE command.
+func (s *FTPSession) setMode(m TransferMode) error {
+	_, err := s.send(CodeCmdOK, "MODE", string(rune(m)))
 	return err
 }
 
+// enterMode switches the data connection to m for one transfer and returns the
+// mode in effect. A server that rejects MODE C gets the transfer in stream mode
+// instead, and a session defaulting to MODE C (WithTransferMode) stops asking.
+func (s *FTPSession) enterMode(m TransferMode) (TransferMode, error) {
+	if m == 0 || m == ModeStream {
+		return ModeStream, nil
+	}
+	err := s.setMode(m)
+	var rerr *ReturnError
+	if m == ModeCompressed && errors.As(err, &rerr) {
+		s.log.Warningf("MODE C rejected (%d), transferring in stream mode", rerr.ReturnCode())
+		s.defMode.CompareAndSwap(uint32(ModeCompressed), uint32(ModeStream))
+		return ModeStream, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return m, nil
+}
+
+// restoreStreamMode puts the session back in MODE S after a transfer in another
+// mode, following the rules of restoreType.
+func (s *FTPSession) restoreStreamMode(errp *error) {
+	if s.IsClosed() {
+		return
+	}
+	if rerr := s.setMode(ModeStream); rerr != nil && *errp == nil {
+		*errp = fmt.Errorf("error while setting back the transfer mode: %w", rerr)
+	}
+}
+
 // currentType returns the session's current transfer type. It is safe to call
 // without holding s.mu.
 func (s *FTPSession) currentType() TransferType {
@@ -100,6 +269,16 @@ func (s *FTPSession) currentType() TransferType {
 // If offset is greater than zero, a REST command is issued before
 // starting the transfer to resume at the given byte position.
 func (s *FTPSession) transfer(t transfer.DataTransfer, remote string, offset int64) (int64, string, error) {
+	rest := ""
+	if offset > 0 {
+		rest = strconv.FormatInt(offset, 10)
+	}
+	return s.transferRest(t, remote, rest)
+}
+
+// transferRest performs a data transfer, first issuing REST with rest (a byte
+// offset or a block-mode restart marker) when it is not empty.
+func (s *FTPSession) transferRest(t transfer.DataTransfer, remote string, rest string) (int64, string, error) {
 
 	port, err := s.SetPassiveMode()
 	if err != nil {
@@ -118,13 +297,13 @@ func (s *FTPSession) transfer(t transfer.DataTransfer, remote string, offset int
 		}
 	}(child)
 
-	if offset > 0 {
-		if _, err := s.SendCommand(CodeNeedInfo, "REST", fmt.Sprintf("%d", offset)); err != nil {
+	if rest != "" {
+		if _, err := s.send(CodeNeedInfo, "REST", rest); err != nil {
 			return 0, "", err
 		}
 	}
 
-	msg1, err := s.SendCommand(CodeListOK, t.Command(), remote)
+	msg1, err := s.send(CodeListOK, t.Command(), remote)
 	if err != nil {
 		return 0, msg1, err
 	}
@@ -177,21 +356,274 @@ func (s *FTPSession) restoreType(prev TransferType, errp *error) {
 	}
 }
 
+// TransferOption adjusts a single RetrieveIO or StoreIO call.
+type TransferOption func(*transferOptions)
+
+type transferOptions struct {
+	codePage   *ebcdic.CodePage
+	newline    ebcdic.Newline
+	control    carriage.Control
+	mode       TransferMode // 0 is the session's default
+	checkpoint *Checkpoint
+	stats      *TransferStats
+	mbcs       [2]string // file system and network code pages of WithMBCS
+	lines      *linePolicy
+	spill      io.Writer
+}
+
+func applyTransferOptions(opts []TransferOption) transferOptions {
+	var o transferOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// TransferStats describes a completed RetrieveIO or StoreIO; see
+// WithTransferStats.
+type TransferStats struct {
+	// Mode is the transmission mode the data went in: ModeStream when MODE C was
+	// asked for but the server rejected it.
+	Mode TransferMode
+	// Bytes is the size of the data, as written to the destination or read from
+	// the source.
+	Bytes int64
+	// WireBytes is the number of bytes on the data connection, compressed or
+	// framed.
+	WireBytes int64
+	// LongLines are the 1-based numbers of the lines WithLinePolicy truncated,
+	// wrapped or spilled.
+	LongLines []int
+}
+
+// Ratio returns WireBytes divided by Bytes: below 1 when compression saved
+// bandwidth, 1 for an empty transfer.
+func (st TransferStats) Ratio() float64 {
+	if st.Bytes == 0 {
+		return 1
+	}
+	return float64(st.WireBytes) / float64(st.Bytes)
+}
+
+// record fills o.stats, when set, from a finished transfer in mode m.
+func (o transferOptions) record(m TransferMode, t transfer.DataTransfer, sz int64) {
+	if o.stats == nil {
+		return
+	}
+	wire := sz
+	if w, ok := t.(interface{ WireBytes() int64 }); ok {
+		wire = w.WireBytes()
+	}
+	*o.stats = TransferStats{Mode: m, Bytes: sz, WireBytes: wire}
+}
+
+// WithLocalConversion runs the transfer in binary (TYPE I), whatever the
+// TransferType passed, and converts between the EBCDIC code page cp and UTF-8 on
+// the client, so the server's translate table never touches the data. With
+// ebcdic.NLToLF the EBCDIC NL (0x15) becomes '\n' on download and '\n' becomes
+// NL on upload, as z/OS UNIX text expects; with ebcdic.KeepNL records of a
+// dataset arrive without line ends, exactly as a binary transfer sends them.
+// The byte count reported is the EBCDIC size on the wire.
+//
+//	_, err := s.RetrieveIO("/u/me/app.conf", &buf, zftp.TypeImage,
+//		zftp.WithLocalConversion(ebcdic.IBM1047, ebcdic.NLToLF))
+func WithLocalConversion(cp *ebcdic.CodePage, nl ebcdic.Newline) TransferOption {
+	return func(o *transferOptions) {
+		o.codePage = cp
+		o.newline = nl
+	}
+}
+
+// WithMBCS has the server convert the transfer's text between the multibyte
+// code pages fileSystem and network (SITE MBDATACONN=(fileSystem,network) and
+// ENCODING=MBCS), such as UTF-8 source kept in IBM-1047 datasets, and puts the
+// session's previous MBDATACONN and ENCODING back afterwards. It only affects
+// ASCII transfers.
+//
+//	_, err := s.StoreIO("'HLQ.SRC(MAIN)'", f, zftp.TypeAscii,
+//		zftp.WithMBCS("IBM-1047", "UTF-8"))
+func WithMBCS(fileSystem, network string) TransferOption {
+	return func(o *transferOptions) {
+		o.mbcs = [2]string{fileSystem, network}
+	}
+}
+
+// codepagePair matches the "(file system,network)" pair of an MBDATACONN status.
+var codepagePair = regexp.MustCompile(`\(\s*([^,()\s]+)\s*,\s*([^,()\s]+)\s*\)`)
+
+// mbDataConn returns the session's MBDATACONN as "fileSystem,network", or ""
+// when the server reports no pair.
+func (s *FTPSession) mbDataConn() (string, error) {
+	resp, err := s.XStat("MBDATACONN")
+	if err != nil {
+		return "", err
+	}
+	if m := codepagePair.FindStringSubmatch(resp); m != nil {
+		return m[1] + "," + m[2], nil
+	}
+	return "", nil
+}
+
+// setMBDataConn sets MBDATACONN from a mbDataConn value; "" leaves it alone.
+func (s *FTPSession) setMBDataConn(v string) error {
+	if v == "" {
+		return nil
+	}
+	fs, network, _ := strings.Cut(v, ",")
+	return s.SetStatusOf().MBDataConn(fs, network)
+}
+
+// useMBCS applies the WithMBCS code pages, MBDATACONN before ENCODING as z/OS
+// requires, and returns the function that restores both.
+func (s *FTPSession) useMBCS(o transferOptions) (restore func(), err error) {
+	if o.mbcs[0] == "" && o.mbcs[1] == "" {
+		return func() {}, nil
+	}
+	conn, err := utils.SetValueAndGetCurrent(s.log, o.mbcs[0]+","+o.mbcs[1], s.setMBDataConn, s.mbDataConn)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := utils.SetValueAndGetCurrent(s.log, "MBCS", s.SetStatusOf().Encoding, s.knownOr("ENCODING", s.StatusOf().Encoding))
+	if err != nil {
+		conn.Restore()
+		return nil, err
+	}
+	return func() {
+		enc.Restore()
+		conn.Restore()
+	}, nil
+}
+
+// WithCarriageControl interprets the column-1 carriage control of a print
+// dataset (RECFM=FBA, VBA, FBM, VBM) or JES SYSOUT on retrieval, so the
+// destination receives plain text with blank lines and form feeds instead of
+// the raw control characters (see the carriage package). It needs the data as
+// lines: an ASCII transfer, or WithLocalConversion of newline-delimited text.
+// StoreIO ignores it.
+//
+//	_, err := s.RetrieveIO("'HLQ.REPORT.LIST'", os.Stdout, zftp.TypeAscii,
+//		zftp.WithCarriageControl(carriage.ASA))
+func WithCarriageControl(c carriage.Control) TransferOption {
+	return func(o *transferOptions) {
+		o.control = c
+	}
+}
+
+// Checkpoint is the last restart marker of a block-mode download and how much
+// of the data had been written when it arrived. WithBlockMode keeps it current
+// during the transfer; after an interruption it is where GetRestart resumes.
+type Checkpoint struct {
+	// Marker is the server's restart marker, sent back with REST to resume; ""
+	// until the first marker arrives.
+	Marker string
+	// Offset is the number of bytes written to the destination up to Marker,
+	// counted as the data arrives (before WithLocalConversion or carriage
+	// control processing).
+	Offset int64
+}
+
+// WithBlockMode runs the transfer in block mode (MODE B) and puts the session
+// back in stream mode afterwards. Records travel as blocks: an ASCII download
+// ends each record with the local line end, and an ASCII upload sends each line
+// as a record. The server inserts a restart marker every CHKPTINT records (see
+// StatusSetter.CheckpointInterval).
+//
+// On RetrieveIO, cp (which may be nil) receives each marker with the offset it
+// applies to. A cp that already holds a marker resumes the download there with
+// REST <marker>, and the offsets it receives continue from cp.Offset; the
+// destination must already hold the first cp.Offset bytes. StoreIO ignores cp.
+//
+//	var cp zftp.Checkpoint
+//	_, err := s.RetrieveIO("'HLQ.BIG.TEXT'", f, zftp.TypeAscii, zftp.WithBlockMode(&cp))
+func WithBlockMode(cp *Checkpoint) TransferOption {
+	return func(o *transferOptions) {
+		o.mode = ModeBlock
+		o.checkpoint = cp
+	}
+}
+
+// WithCompressedMode runs the transfer in compressed mode (MODE C), which pays
+// off for data with long runs of blanks or of any byte, such as fixed-length
+// records. Records are marked as in block mode. If the server rejects MODE C
+// the transfer runs in stream mode; WithTransferStats tells which was used and
+// the compression ratio. WithTransferMode makes it the session's default.
+//
+//	var st zftp.TransferStats
+//	_, err := s.RetrieveIO("'HLQ.REPORT'", w, zftp.TypeAscii,
+//		zftp.WithCompressedMode(), zftp.WithTransferStats(&st))
+//	fmt.Printf("%.0f%% of the size on the wire\n", 100*st.Ratio())
+func WithCompressedMode() TransferOption {
+	return func(o *transferOptions) {
+		o.mode = ModeCompressed
+	}
+}
+
+// WithTransferStats stores the transmission mode, data size and bytes on the
+// wire of the transfer in st, including when it fails part-way.
+func WithTransferStats(st *TransferStats) TransferOption {
+	return func(o *transferOptions) {
+		o.stats = st
+	}
+}
+
+// defaultMode fills in the session's default transmission mode when no option
+// chose one.
+func (s *FTPSession) defaultMode(o *transferOptions) {
+	if o.mode == 0 {
+		o.mode = TransferMode(s.defMode.Load())
+	}
+}
+
 // StoreIO stores the contents of the reader to the remote file in the specified
 // mode and returns the number of bytes transferred.
 //
 // The original transfer type is restored to the previous value after the transfer
 // (including when the transfer fails at the control level and the session stays
-// open); supports ASCII and binary/Image transfers.
-func (s *FTPSession) StoreIO(remote string, src io.Reader, t TransferType) (int64, error) {
-	sz, _, err := s.storeIO(remote, src, t)
+// open); supports ASCII and binary/Image transfers, local codepage conversion
+// with WithLocalConversion, and block and compressed modes with WithBlockMode and
+// WithCompressedMode. Long text lines are handled on the client with
+// WithLinePolicy.
+func (s *FTPSession) StoreIO(remote string, src io.Reader, t TransferType, opts ...TransferOption) (int64, error) {
+	o := applyTransferOptions(opts)
+	s.defaultMode(&o)
+	var limiter *lineLimiter
+	switch {
+	case t.ascii() || o.codePage != nil:
+		var err error
+		if src, limiter, err = s.enforceLines(remote, src, o); err != nil {
+			return 0, err
+		}
+	case o.lines != nil:
+		return 0, fmt.Errorf("zftp: WithLinePolicy needs an ASCII transfer or WithLocalConversion, not %s", t.Name())
+	}
+	if o.codePage != nil {
+		t = TypeImage
+		src = ebcdic.NewEncodingReader(src, o.codePage, o.newline)
+	}
+	restore, err := s.useMBCS(o)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+	sz, _, err := s.storeIO(remote, src, t, o)
+	if limiter != nil && o.stats != nil {
+		o.stats.LongLines = limiter.long
+	}
 	return sz, err
 }
 
 // storeIO is the implementation behind StoreIO that also returns the concatenated
 // server reply text. The public StoreIO drops that text; internal callers (e.g.
 // SubmitIO in jes.go) keep it to parse the JES job id from the submit reply.
-func (s *FTPSession) storeIO(remote string, src io.Reader, t TransferType) (sz int64, msg string, err error) {
+func (s *FTPSession) storeIO(remote string, src io.Reader, t TransferType, o transferOptions) (sz int64, msg string, err error) {
+
+	mode, err := s.enterMode(o.mode)
+	if err != nil {
+		return 0, "", err
+	}
+	if mode != ModeStream {
+		defer s.restoreStreamMode(&err)
+	}
 
 	current := s.currentType()
 	if err = s.SetType(t); err != nil {
@@ -201,22 +633,52 @@ func (s *FTPSession) storeIO(remote string, src io.Reader, t TransferType) (sz i
 
 	var format transfer.DataTransfer
 
-	if t.IsAscii() {
+	switch {
+	case mode == ModeBlock:
+		format = transfer.NewStoreBlock(src, t.IsAscii())
+	case mode == ModeCompressed:
+		format = transfer.NewStoreCompressed(src, t.IsAscii(), t.filler())
+	case t.IsAscii():
 		format = transfer.NewStoreAscii(src)
-	} else {
+	default:
 		format = transfer.NewStore(src)
 	}
 
 	sz, msg, err = s.transfer(format, remote, 0)
+	o.record(mode, format, sz)
 	return sz, msg, err
 }
 
 // RetrieveIO retrieves the contents of the remote file and writes it to the writer.
 // The transfer type is restored to the previous value after the transfer (including
 // when the transfer fails at the control level and the session stays open);
-// supports ASCII and binary/Image transfers.
-func (s *FTPSession) RetrieveIO(remote string, dest io.Writer, t TransferType) (int64, error) {
-	sz, _, err := s.retrieveIO(remote, dest, t)
+// supports ASCII and binary/Image transfers, local codepage conversion with
+// WithLocalConversion, carriage control with WithCarriageControl, block mode
+// with restart markers with WithBlockMode, and compressed mode with
+// WithCompressedMode.
+func (s *FTPSession) RetrieveIO(remote string, dest io.Writer, t TransferType, opts ...TransferOption) (int64, error) {
+	o := applyTransferOptions(opts)
+	s.defaultMode(&o)
+	var cc *carriage.Writer
+	if o.control != 0 {
+		cc = carriage.NewWriter(dest, o.control)
+		dest = cc
+	}
+	if o.codePage != nil {
+		t = TypeImage
+		dest = ebcdic.NewDecodingWriter(dest, o.codePage, o.newline)
+	}
+	restore, err := s.useMBCS(o)
+	if err != nil {
+		return 0, err
+	}
+	defer restore()
+	sz, _, err := s.retrieveIO(remote, dest, t, o)
+	if cc != nil {
+		if cerr := cc.Close(); err == nil {
+			err = cerr
+		}
+	}
 	return sz, err
 }
 
@@ -224,9 +686,17 @@ func (s *FTPSession) RetrieveIO(remote string, dest io.Writer, t TransferType) (
 // concatenated server reply text. The public RetrieveIO drops that text; internal
 // callers (e.g. SubmitJesGetByDSN in jes.go) keep it to parse the JES job id from
 // the retrieve reply.
-func (s *FTPSession) retrieveIO(remote string, dest io.Writer, t TransferType) (sz int64, msg string, err error) {
+func (s *FTPSession) retrieveIO(remote string, dest io.Writer, t TransferType, o transferOptions) (sz int64, msg string, err error) {
+	mode, err := s.enterMode(o.mode)
+	if err != nil {
+		return 0, "", err
+	}
+	if mode != ModeStream {
+		defer s.restoreStreamMode(&err)
+	}
+
 	current := s.currentType()
-	if t.IsAscii() {
+	if t.IsAscii() && mode == ModeStream {
 		if err = s.SetStatusOf().SBSendEol(eol.System); err != nil {
 			return 0, "", err
 		}
@@ -236,7 +706,34 @@ func (s *FTPSession) retrieveIO(remote string, dest io.Writer, t TransferType) (
 	}
 	defer s.restoreType(current, &err)
 
-	sz, msg, err = s.transfer(transfer.NewRetrieve(dest), remote, 0)
+	// In block and compressed modes records arrive without line ends; text gets
+	// the one of its representation.
+	eor := t.recordEnd()
+	var onMarker func(string, int64)
+	rest := ""
+	if cp := o.checkpoint; cp != nil && mode != ModeStream {
+		rest = cp.Marker
+		base := cp.Offset
+		if rest == "" {
+			base = 0
+		}
+		onMarker = func(marker string, written int64) {
+			cp.Marker = marker
+			cp.Offset = base + written
+		}
+	}
+
+	var format transfer.DataTransfer
+	switch mode {
+	case ModeBlock:
+		format = transfer.NewRetrieveBlock(dest, eor, onMarker)
+	case ModeCompressed:
+		format = transfer.NewRetrieveCompressed(dest, eor, t.filler(), onMarker)
+	default:
+		format = transfer.NewRetrieve(dest)
+	}
+	sz, msg, err = s.transferRest(format, remote, rest)
+	o.record(mode, format, sz)
 	return sz, msg, err
 }
 
@@ -5,6 +5,8 @@ package zftp_test
 import (
 	"bytes"
 	"errors"
+	"io"
+	"slices"
 	"strings"
 	"testing"
 
@@ -232,3 +234,71 @@ func TestStoreIOAt_BinaryOffset(t *testing.T) {
 		t.Errorf("n = %d, want %d", n, len(payload))
 	}
 }
+
+// TestTransfer_RepresentationTypes issues each type's TYPE command: ASCII with a
+// format control still frames uploads as CRLF lines, with the SBSENDEOL set at
+// login known and not sent again, while
+// EBCDIC passes through untouched, and neither may be resumed by byte offset.
+func TestTransfer_RepresentationTypes(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.DataFor("RETR", "REPORT", "1TITLE\r\n PAGE\r\n")
+
+	var b bytes.Buffer
+	before := len(srv.Commands())
+	if _, err := s.RetrieveIO("REPORT", &b, zftp.TypeAsciiASA); err != nil {
+		t.Fatalf("RetrieveIO TYPE A C: %v", err)
+	}
+	cmds := srv.Commands()[before:]
+	if i, j := cmdIndex(cmds, "TYPE A C"), cmdIndex(cmds, "TYPE I"); i < 0 || j < i || strings.HasPrefix(cmds[0], "SITE SBSENDEOL=") {
+		t.Errorf("TYPE A C commands = %v", cmds)
+	}
+
+	ebcdic := "\xC1\x15\x40\xC2\x15\r\n"
+	before = len(srv.Commands())
+	if _, err := s.StoreIO("EBC.DATA", strings.NewReader(ebcdic), zftp.TypeEbcdic); err != nil {
+		t.Fatalf("StoreIO TYPE E: %v", err)
+	}
+	if got, _ := srv.Stored("EBC.DATA"); string(got) != ebcdic {
+		t.Errorf("TYPE E stored %q, want %q", got, ebcdic)
+	}
+	if cmds := srv.Commands()[before:]; !hasCmd(cmds, "TYPE E") {
+		t.Errorf("TYPE E commands = %v", cmds)
+	}
+
+	for _, typ := range []zftp.TransferType{zftp.TypeEbcdic, zftp.TypeUnicode, zftp.TypeAsciiTelnet} {
+		if _, err := s.RetrieveIOAt("REPORT", &b, typ, 10); !errors.Is(err, zftp.ErrAsciiResumeUnsupported) {
+			t.Errorf("%s resume: err = %v, want ErrAsciiResumeUnsupported", typ.Name(), err)
+		}
+	}
+}
+
+// TestTransfer_WithMBCS checks WithMBCS sets MBDATACONN before ENCODING=MBCS
+// for the transfer and puts the session's previous values back.
+func TestTransfer_WithMBCS(t *testing.T) {
+	s, srv := dialMock(t)
+	srv.EnableState()
+	if err := s.SetStatusOf().MBDataConn("IBM-037", "UTF-8"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.StoreIO("'ME.SRC'", strings.NewReader("héllo\n"), zftp.TypeAscii, zftp.WithMBCS("IBM-1047", "UTF-8")); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	cmds := srv.Commands()
+	conn, enc := cmdIndex(cmds, "SITE MBDATACONN=(IBM-1047,UTF-8)"), cmdIndex(cmds, "SITE ENCODING=MBCS")
+	stor := slices.IndexFunc(cmds, func(c string) bool { return strings.HasPrefix(c, "STOR ") })
+	if conn < 0 || enc < conn || stor < enc {
+		t.Errorf("commands = %v", cmds)
+	}
+	if got, err := s.StatusOf().Encoding(); err != nil || got != "SBCS" {
+		t.Errorf("Encoding after transfer = %q, %v; want SBCS", got, err)
+	}
+	if !hasCmd(srv.Commands()[stor:], "SITE MBDATACONN=(IBM-037,UTF-8)") {
+		t.Errorf("MBDATACONN not restored: %v", srv.Commands()[stor:])
+	}
+
+	// An invalid code page pair fails before anything is sent.
+	if _, err := s.RetrieveIO("'ME.SRC'", io.Discard, zftp.TypeAscii, zftp.WithMBCS("IBM-1047", "")); err == nil {
+		t.Error("WithMBCS without a network code page: want error")
+	}
+}
@@ -36,3 +36,34 @@ func TestTransferType_NameUnknownForZeroValue(t *testing.T) {
 		t.Fatalf("TypeImage.Name() = %q, want BINARY", got)
 	}
 }
+
+// Every representation type renders its TYPE command, including the format
+// control or byte size, and only image data may be resumed by byte offset.
+func TestTransferType_Representations(t *testing.T) {
+	for _, tc := range []struct {
+		t          TransferType
+		cmd, name  string
+		translated bool
+	}{
+		{TypeAscii, "TYPE A", "ASCII", true},
+		{TypeAsciiASA, "TYPE A C", "ASCII ASA", true},
+		{TypeAsciiTelnet, "TYPE A T", "ASCII TELNET", true},
+		{TypeEbcdic, "TYPE E", "EBCDIC", true},
+		{TypeImage, "TYPE I", "BINARY", false},
+		{TypeDBCS, "TYPE B", "DBCS", true},
+		{TypeUnicode, "TYPE U 2", "UNICODE", true},
+	} {
+		if got := tc.t.strCommand(); got != tc.cmd {
+			t.Errorf("%s: command %q, want %q", tc.name, got, tc.cmd)
+		}
+		if got := tc.t.Name(); got != tc.name {
+			t.Errorf("%s: Name() = %q", tc.cmd, got)
+		}
+		if tc.t.Translated() != tc.translated || (guardResume(tc.t, 1) != nil) != tc.translated {
+			t.Errorf("%s: translated/resume classification wrong", tc.cmd)
+		}
+	}
+	if !TypeAsciiASA.IsAscii() || !TypeAsciiTelnet.IsAscii() || TypeEbcdic.IsAscii() || TypeUnicode.IsBinary() {
+		t.Error("IsAscii/IsBinary classification wrong")
+	}
+}
@@ -3,19 +3,24 @@
 package zftp
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
 // Stat returns the server status string.
 func (s *FTPSession) Stat(a ...string) (string, error) {
-	return s.SendCommand(CodeSysStatus, "STAT", a...)
+	return s.send(CodeSysStatus, "STAT", a...)
 }
 
 // XStat issues an XSTA command to retrieve an individual status variable or
 // property from the server's current status.
 func (s *FTPSession) XStat(feature string) (string, error) {
-	out, err := s.SendCommand(CodeSysStatus, "XSTA", fmt.Sprintf("(%s", feature))
+	return xstatReply(s.send(CodeSysStatus, "XSTA", fmt.Sprintf("(%s", feature)))
+}
+
+// xstatReply strips the end-of-status trailer from an XSTA reply.
+func xstatReply(out string, err error) (string, error) {
 	if err != nil {
 		return "", err
 	}
@@ -32,3 +37,15 @@ func (s *FTPSession) XStat(feature string) (string, error) {
 func (s *FTPSession) StatusOf() *ServerStatus {
 	return &ServerStatus{xstat: s.XStat, stat: s.Stat}
 }
+
+// statusOfContext is StatusOf with every query bounded by ctx.
+func (s *FTPSession) statusOfContext(ctx context.Context) *ServerStatus {
+	return &ServerStatus{
+		xstat: func(feature string) (string, error) {
+			return xstatReply(s.sendContext(ctx, CodeSysStatus, "XSTA", fmt.Sprintf("(%s", feature)))
+		},
+		stat: func(a ...string) (string, error) {
+			return s.sendContext(ctx, CodeSysStatus, "STAT", a...)
+		},
+	}
+}
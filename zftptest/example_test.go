@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftptest_test
+
+import (
+	"testing"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/zftptest"
+)
+
+// ExampleNewServer shows an application test seeding a dataset and exercising
+// its own download code against the server. It is compiled but not executed,
+// since it needs the calling test's *testing.T.
+func ExampleNewServer() {
+	var t *testing.T // the *testing.T of the enclosing test
+
+	srv := zftptest.NewServer(t)
+	srv.AddDataset("IBMUSER.CONFIG", zftptest.DCB{Recfm: "FB", Lrecl: 80}, "MODE=TEST")
+
+	s := srv.Dial(t)
+	if err := s.Get("'IBMUSER.CONFIG'", "config.txt", zftp.TypeAscii); err != nil {
+		t.Fatal(err)
+	}
+	srv.ExpectCommands(t, "TYPE A", "RETR 'IBMUSER.CONFIG'")
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftptest
+
+import (
+	"time"
+
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// Fault is one network misbehavior of a fault profile installed with
+// Server.InjectFaults. Faults compose: a profile applies all of them at once.
+type Fault struct {
+	f mockzos.Fault
+}
+
+// Latency delays the reply to a command by a random duration in [min, max]. The
+// key is a full command line or a verb, or "*" for every command.
+func Latency(command string, min, max time.Duration) Fault {
+	return Fault{mockzos.Latency(command, min, max)}
+}
+
+// Bandwidth caps every data connection, in both directions, at bytesPerSecond.
+func Bandwidth(bytesPerSecond int) Fault { return Fault{mockzos.Bandwidth(bytesPerSecond)} }
+
+// ResetData makes a transfer of verb (RETR, LIST, NLST, STOR, STOU or APPE)
+// abort its data connection with a TCP reset, at a random byte offset, with the
+// given probability. The control connection then reports 426.
+func ResetData(verb string, probability float64) Fault {
+	return Fault{mockzos.ResetData(verb, probability)}
+}
+
+// DelayCompletion holds back the closing reply of a transfer of verb for d after
+// its data connection is closed.
+func DelayCompletion(verb string, d time.Duration) Fault {
+	return Fault{mockzos.DelayCompletion(verb, d)}
+}
+
+// SplitReplies delivers every control reply in several TCP segments cut at
+// random points.
+func SplitReplies() Fault { return Fault{mockzos.SplitReplies()} }
+
+// DisconnectAfter makes each control connection answer its (n+1)th command with
+// a 421 reply and hang up.
+func DisconnectAfter(n int) Fault { return Fault{mockzos.DisconnectAfter(n)} }
+
+// InjectFaults replaces the server's fault profile. Every random choice the
+// faults make comes from a generator seeded with seed and the connection's
+// sequence number, so a failure seen with one seed reproduces with it. Calling
+// InjectFaults with no faults clears the profile.
+//
+//	srv.InjectFaults(42, zftptest.Latency("*", 0, 20*time.Millisecond), zftptest.ResetData("RETR", 0.1))
+func (s *Server) InjectFaults(seed int64, faults ...Fault) {
+	fs := make([]mockzos.Fault, len(faults))
+	for i, f := range faults {
+		fs[i] = f.f
+	}
+	s.m.InjectFaults(seed, fs...)
+}
@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package zftptest provides an in-process z/OS FTP server for testing code that
+// uses zftp, in the spirit of net/http/httptest. A Server listens on a loopback
+// port and speaks the z/OS FTP dialect the client relies on: MVS SYST, SITE and
+// XSTA, passive data connections, dataset and PDS listings in the z/OS column
+// layout, and JES spool access under SITE FILETYPE=JES.
+//
+// A Server starts as a small virtual z/OS system. Datasets, PDS members, z/OS
+// UNIX files and jobs are seeded with the Add… and SubmitJob methods, and the
+// client's uploads, deletes and renames change them:
+//
+//	srv := zftptest.NewServer(t)
+//	srv.AddDataset("IBMUSER.INPUT", zftptest.DCB{Recfm: "FB", Lrecl: 80}, "RECORD 1")
+//	s := srv.Dial(t)
+//	err := s.Get("'IBMUSER.INPUT'", "input.txt", zftp.TypeAscii)
+//
+// Individual replies can be scripted instead (Script, DataFor, CompletionReply),
+// one-shot faults injected (Withhold, Hangup, TruncateData, …) or a seeded
+// profile of network faults installed (InjectFaults), and the commands the
+// server received asserted on (Commands, Received, ExpectCommands).
+package zftptest
+
+import (
+	"crypto/tls"
+	"strings"
+	"testing"
+	"time"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/internal/mockzos"
+)
+
+// DefaultUser is the user Dial logs in as. Relative dataset names resolve under
+// it ("INPUT" is IBMUSER.INPUT), and the default JES filters list its jobs.
+const DefaultUser = "IBMUSER"
+
+// Server is an in-process z/OS FTP server bound to a loopback ephemeral port.
+type Server struct {
+	m *mockzos.Server
+}
+
+// NewServer starts a Server on 127.0.0.1 with an empty catalog and spool. It is
+// closed when the test and its subtests complete.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s := &Server{m: mockzos.New(tb)}
+	s.m.EnableState()
+	return s
+}
+
+// Listen starts a Server on addr (e.g. "127.0.0.1:2121") for use outside a
+// test, such as a fake mainframe for other tools to connect to. Seeding mistakes
+// and connection trouble are reported through logf, which may be nil, rather
+// than failing a test. The caller must Close the server.
+func Listen(addr string, logf func(format string, args ...any)) (*Server, error) {
+	m, err := mockzos.Listen(addr, logf)
+	if err != nil {
+		return nil, err
+	}
+	m.EnableState()
+	return &Server{m: m}, nil
+}
+
+// Addr returns the host:port the server is listening on, suitable for zftp.Open.
+func (s *Server) Addr() string { return s.m.Addr() }
+
+// Close stops the server and waits for its connections to drain. It is safe to
+// call more than once.
+func (s *Server) Close() { s.m.Close() }
+
+// Dial opens a session to the server and logs in as DefaultUser. The session is
+// closed when the test completes; a dial or login failure fails the test.
+func (s *Server) Dial(tb testing.TB, opts ...zftp.Option) *zftp.FTPSession {
+	tb.Helper()
+	return s.DialUser(tb, DefaultUser, opts...)
+}
+
+// DialUser is Dial for a specific user. The server accepts any password.
+func (s *Server) DialUser(tb testing.TB, user string, opts ...zftp.Option) *zftp.FTPSession {
+	tb.Helper()
+	sess, err := zftp.Open(s.Addr(), opts...)
+	if err != nil {
+		tb.Fatalf("zftptest: open %s: %v", s.Addr(), err)
+	}
+	tb.Cleanup(func() { _ = sess.Close() })
+	if err := sess.Login(user, "PASSWORD"); err != nil {
+		tb.Fatalf("zftptest: login %s: %v", user, err)
+	}
+	return sess
+}
+
+// EnableTLS makes the server accept AUTH TLS and upgrade the control connection
+// using cfg, which must carry a certificate; after PROT P the data connections
+// are protected too. Call it before the client dials.
+func (s *Server) EnableTLS(cfg *tls.Config) { s.m.EnableTLS(cfg) }
+
+// DCB holds the data control block attributes of a seeded dataset. A zero field
+// takes the z/OS FTP allocation default (RECFM=VB, LRECL=256, BLKSIZE=6233), and
+// an empty Volume lists as MOCK01.
+type DCB struct {
+	// Recfm is the record format, e.g. "FB", "VB" or "U".
+	Recfm string
+	// Lrecl is the logical record length.
+	Lrecl int
+	// BlkSize is the block size.
+	BlkSize int
+	// Volume is the volume serial shown in dataset listings.
+	Volume string
+}
+
+func (d DCB) attrs() mockzos.Attrs {
+	return mockzos.Attrs{Recfm: d.Recfm, Lrecl: d.Lrecl, BlkSize: d.BlkSize, Volume: d.Volume}
+}
+
+// AddDataset catalogs a sequential dataset holding records, one per line. The
+// name is fully qualified whether or not it is quoted.
+func (s *Server) AddDataset(dsn string, dcb DCB, records ...string) {
+	s.m.AddDataset(dsn, dcb.attrs(), records...)
+}
+
+// AddPDS catalogs an empty partitioned dataset.
+func (s *Server) AddPDS(dsn string, dcb DCB) { s.m.AddPDS(dsn, dcb.attrs()) }
+
+// AddMember adds a member to a PDS created with AddPDS, with ISPF statistics. It
+// fails the test when the PDS does not exist (a Listen server logs it instead).
+func (s *Server) AddMember(pds, member string, records ...string) {
+	s.m.AddMember(pds, member, records...)
+}
+
+// AddFile creates a z/OS UNIX file at the absolute path p, along with any
+// missing parent directories.
+func (s *Server) AddFile(p string, data []byte) { s.m.AddFile(p, data) }
+
+// SubmitJob puts jcl on the JES spool as if owner had submitted it and returns
+// the job id. It fails the test when the JCL has no JOB statement (a Listen
+// server logs it and returns "").
+func (s *Server) SubmitJob(owner, jcl string) string { return s.m.SubmitJob(owner, jcl) }
+
+// JobCompletion sets how jobs named jobName end from now on: "RC=nnnn" (the
+// default is "RC=0000"), "ABEND=Scde" or "ABEND=Unnnn", or "JCL error".
+func (s *Server) JobCompletion(jobName, completion string) {
+	s.m.JobCompletion(jobName, completion)
+}
+
+// JESTiming sets how long jobs submitted from now on wait for an initiator and
+// then run before their output is available. Both default to zero.
+func (s *Server) JESTiming(input, active time.Duration) { s.m.JESTiming(input, active) }
+
+// SetClock replaces the server clock (time.Now by default), which stamps
+// catalog entries and moves jobs through INPUT, ACTIVE and OUTPUT. A nil now
+// restores the wall clock.
+func (s *Server) SetClock(now func() time.Time) { s.m.SetClock(now) }
+
+// Records returns the records of a sequential dataset or a PDS member
+// ("HLQ.PDS(MEMBER)").
+func (s *Server) Records(dsn string) ([]string, bool) { return s.m.Records(dsn) }
+
+// File returns the content of a z/OS UNIX file.
+func (s *Server) File(p string) ([]byte, bool) { return s.m.File(p) }
+
+// Exists reports whether a dataset, PDS member or z/OS UNIX path exists.
+func (s *Server) Exists(name string) bool { return s.m.Exists(name) }
+
+// JobStatus reports the phase of a job on the spool: INPUT, ACTIVE or OUTPUT.
+func (s *Server) JobStatus(jobID string) (string, bool) { return s.m.JobStatus(jobID) }
+
+// Script registers the raw reply lines for a command, overriding the server's
+// own answer. The key is a full command line ("XSTA (BLOCKSIze") or a verb
+// ("STAT"); a full-line script wins over a verb script.
+//
+//	srv.Script("STAT", "211-begin", "211 end")
+func (s *Server) Script(command string, replies ...string) { s.m.Script(command, replies...) }
+
+// DataFor sets the payload a download (LIST, NLST or RETR) sends, overriding the
+// catalog. An empty arg matches any argument of the verb.
+func (s *Server) DataFor(verb, arg, payload string) { s.m.DataFor(verb, arg, payload) }
+
+// CompletionReply replaces the closing reply of scripted transfers for a verb
+// (RETR, LIST, NLST, STOR, STOU or APPE).
+func (s *Server) CompletionReply(verb string, replies ...string) {
+	s.m.CompletionReply(verb, replies...)
+}
+
+// Withhold makes the server swallow a command without ever replying, as a hung
+// control connection would. The key is a full command line or a verb.
+func (s *Server) Withhold(command string) { s.m.Withhold(command) }
+
+// Hangup makes the server close the control connection, without replying, when
+// it receives a command. The key is a full command line or a verb.
+func (s *Server) Hangup(command string) { s.m.Hangup(command) }
+
+// TruncateData makes a download reset its data connection after sending the
+// payload while the control connection still reports success.
+func (s *Server) TruncateData(verb string) { s.m.TruncateData(verb) }
+
+// HangData makes a download hold its data connection open after sending the
+// payload, until the client closes it.
+func (s *Server) HangData(verb string) { s.m.HangData(verb) }
+
+// DropControlAfterData makes a download close the control connection after the
+// data is delivered, instead of sending the closing reply.
+func (s *Server) DropControlAfterData(verb string) { s.m.DropControlAfterData(verb) }
+
+// WithholdReplyAfterData makes a download deliver its data but never send the
+// closing reply.
+func (s *Server) WithholdReplyAfterData(verb string) { s.m.WithholdReplyAfterData(verb) }
+
+// Commands returns every command line the server has received, in order.
+func (s *Server) Commands() []string { return s.m.Commands() }
+
+// Stored returns the bytes of the last upload to the given remote name, as sent.
+func (s *Server) Stored(name string) ([]byte, bool) { return s.m.Stored(name) }
+
+// Received reports whether the server received command, compared
+// case-insensitively against full command lines, or against verbs when command
+// is a single word.
+func (s *Server) Received(command string) bool {
+	for _, line := range s.Commands() {
+		if matchCommand(command, line) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectCommands fails the test unless the server received want in that order,
+// possibly with other commands in between. Each entry matches like Received.
+//
+//	srv.ExpectCommands(t, "SITE FILETYPE=JES", "STOR", "SITE FILETYPE=SEQ")
+func (s *Server) ExpectCommands(tb testing.TB, want ...string) {
+	tb.Helper()
+	got := s.Commands()
+	i := 0
+	for _, line := range got {
+		if i < len(want) && matchCommand(want[i], line) {
+			i++
+		}
+	}
+	if i < len(want) {
+		tb.Errorf("zftptest: command %q not received in order; received:\n\t%s", want[i], strings.Join(got, "\n\t"))
+	}
+}
+
+func matchCommand(want, line string) bool {
+	want = strings.TrimSpace(want)
+	if strings.EqualFold(want, strings.TrimSpace(line)) {
+		return true
+	}
+	verb, _, _ := strings.Cut(strings.TrimSpace(line), " ")
+	return !strings.Contains(want, " ") && strings.EqualFold(want, verb)
+}
@@ -0,0 +1,105 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package zftptest_test
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	zftp "gopkg.in/ro-ag/zftp.v2"
+	"gopkg.in/ro-ag/zftp.v2/zftptest"
+)
+
+func TestServer_SeededCatalog(t *testing.T) {
+	srv := zftptest.NewServer(t)
+	srv.AddDataset("IBMUSER.INPUT", zftptest.DCB{Recfm: "FB", Lrecl: 80}, "RECORD 1", "RECORD 2")
+	srv.AddPDS("IBMUSER.SRC", zftptest.DCB{Recfm: "FB", Lrecl: 80, BlkSize: 3120})
+	srv.AddMember("IBMUSER.SRC", "PROG1", "       IDENTIFICATION DIVISION.")
+	s := srv.Dial(t)
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("INPUT", &out, zftp.TypeAscii); err != nil {
+		t.Fatalf("RetrieveIO: %v", err)
+	}
+	if out.String() != "RECORD 1\nRECORD 2\n" {
+		t.Errorf("retrieved %q", out.String())
+	}
+
+	members, err := s.ListPds("'IBMUSER.SRC'")
+	if err != nil || len(members) != 1 || members[0].Name.String() != "PROG1" {
+		t.Fatalf("ListPds = %v, %v", members, err)
+	}
+
+	if _, err := s.StoreIO("'IBMUSER.SRC(PROG2)'", strings.NewReader("A\nB\n"), zftp.TypeAscii); err != nil {
+		t.Fatalf("StoreIO: %v", err)
+	}
+	if recs, ok := srv.Records("IBMUSER.SRC(PROG2)"); !ok || !slices.Equal(recs, []string{"A", "B"}) {
+		t.Errorf("PROG2 = %q (ok=%v)", recs, ok)
+	}
+	srv.ExpectCommands(t, "USER IBMUSER", "RETR", "STOR 'IBMUSER.SRC(PROG2)'")
+	if srv.Received("DELE") {
+		t.Error("Received(DELE) = true")
+	}
+}
+
+func TestServer_Jobs(t *testing.T) {
+	srv := zftptest.NewServer(t)
+	now := time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC)
+	srv.SetClock(func() time.Time { return now })
+	srv.JESTiming(0, time.Minute)
+	srv.JobCompletion("IBMUSERX", "RC=0008")
+	id := srv.SubmitJob("IBMUSER", "//IBMUSERX JOB CLASS=A\n//S1 EXEC PGM=IEFBR14\n")
+	s := srv.Dial(t)
+
+	if st, _ := srv.JobStatus(id); st != "ACTIVE" {
+		t.Fatalf("JobStatus = %q, want ACTIVE", st)
+	}
+	now = now.Add(time.Hour)
+	jd, err := s.GetJobStatus(id)
+	if err != nil {
+		t.Fatalf("GetJobStatus: %v", err)
+	}
+	if rc, _ := jd.ReturnCode(); rc != 8 {
+		t.Errorf("ReturnCode = %d, want 8", rc)
+	}
+}
+
+func TestServer_Faults(t *testing.T) {
+	srv := zftptest.NewServer(t)
+	srv.DataFor("RETR", "", "DATA")
+	srv.TruncateData("RETR")
+	s := srv.Dial(t)
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("'ANY.DATA'", &out, zftp.TypeBinary); err == nil {
+		t.Fatal("RetrieveIO over a reset data connection succeeded")
+	}
+
+	srv.Hangup("NOOP")
+	s = srv.Dial(t)
+	if _, err := s.SendCommand(zftp.CodeCmdOK, "NOOP"); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("NOOP after hangup err = %v", err)
+	}
+}
+
+func TestServer_FaultProfile(t *testing.T) {
+	srv := zftptest.NewServer(t)
+	srv.AddDataset("IBMUSER.BIG", zftptest.DCB{}, strings.Repeat("Y", 200))
+	srv.InjectFaults(9, zftptest.ResetData("RETR", 1), zftptest.SplitReplies())
+	s := srv.Dial(t)
+
+	var out strings.Builder
+	if _, err := s.RetrieveIO("BIG", &out, zftp.TypeBinary); err == nil {
+		t.Fatal("RetrieveIO over a reset data connection succeeded")
+	}
+
+	srv.InjectFaults(0)
+	s = srv.Dial(t)
+	if _, err := s.RetrieveIO("BIG", &out, zftp.TypeBinary); err != nil {
+		t.Fatalf("RetrieveIO after clearing the profile: %v", err)
+	}
+}